@@ -0,0 +1,316 @@
+package gorouter
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrForbidden = errors.New("the principal is not allowed to access the resource")
+)
+
+// Policy decides whether a principal is allowed to access a resource.
+type Policy interface {
+	Evaluate(ctx Context, principal *Principal) bool
+
+	// String returns the human readable form of the policy.
+	String() string
+}
+
+// Authorizer authorizes the principal of a request against a policy.
+// It returns nil, if the access is allowed, ErrForbidden if it is denied,
+// while any other error results in 500.
+type Authorizer interface {
+	Authorize(ctx Context, principal *Principal, policy Policy) error
+}
+
+// RouteInfo describes a registered route.
+type RouteInfo struct {
+	Method string
+	Url    string
+	Name   string
+
+	// The policy of the route – if there is any.
+	Policy Policy
+
+	// The permissions referenced by the policy of the route.
+	Permissions []string
+}
+
+type (
+	permissionPolicy string
+	rolePolicy       string
+	allOfPolicy      []Policy
+	anyOfPolicy      []Policy
+
+	authenticatedPolicy struct{}
+
+	funcPolicy struct {
+		name string
+		fn   func(Context, *Principal) bool
+	}
+
+	defaultAuthorizer struct{}
+)
+
+// Permission returns a policy, which requires the given permission.
+func Permission(permission string) Policy {
+	return permissionPolicy(permission)
+}
+
+// Role returns a policy, which requires the given role.
+func Role(role string) Policy {
+	return rolePolicy(role)
+}
+
+// Authenticated returns a policy, which requires only an authenticated principal.
+func Authenticated() Policy {
+	return authenticatedPolicy{}
+}
+
+// AllOf returns a policy, which requires all the given policies.
+func AllOf(policies ...Policy) Policy {
+	return allOfPolicy(policies)
+}
+
+// AnyOf returns a policy, which requires at least one of the given policies.
+func AnyOf(policies ...Policy) Policy {
+	return anyOfPolicy(policies)
+}
+
+// NewPolicy returns a policy evaluated by the given function.
+// The name is used as the human readable form of the policy.
+func NewPolicy(name string, fn func(ctx Context, principal *Principal) bool) Policy {
+	return &funcPolicy{name: name, fn: fn}
+}
+
+func (p permissionPolicy) Evaluate(_ Context, principal *Principal) bool {
+	return principal.HasPermission(string(p))
+}
+
+func (p permissionPolicy) String() string {
+	return string(p)
+}
+
+func (p rolePolicy) Evaluate(_ Context, principal *Principal) bool {
+	return principal.HasRole(string(p))
+}
+
+func (p rolePolicy) String() string {
+	return "role:" + string(p)
+}
+
+func (authenticatedPolicy) Evaluate(_ Context, principal *Principal) bool {
+	return principal != nil
+}
+
+func (authenticatedPolicy) String() string {
+	return "authenticated"
+}
+
+func (p allOfPolicy) Evaluate(ctx Context, principal *Principal) bool {
+	for _, e := range p {
+		if !e.Evaluate(ctx, principal) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p allOfPolicy) String() string {
+	return joinPolicies(p, " AND ")
+}
+
+func (p anyOfPolicy) Evaluate(ctx Context, principal *Principal) bool {
+	for _, e := range p {
+		if e.Evaluate(ctx, principal) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p anyOfPolicy) String() string {
+	return joinPolicies(p, " OR ")
+}
+
+func (p *funcPolicy) Evaluate(ctx Context, principal *Principal) bool {
+	return p.fn(ctx, principal)
+}
+
+func (p *funcPolicy) String() string {
+	return p.name
+}
+
+func joinPolicies(policies []Policy, sep string) string {
+	parts := make([]string, 0, len(policies))
+
+	for _, p := range policies {
+		s := p.String()
+
+		// The composite policies are parenthesized.
+		switch v := p.(type) {
+		case allOfPolicy:
+			if len(v) > 1 {
+				s = "(" + s + ")"
+			}
+		case anyOfPolicy:
+			if len(v) > 1 {
+				s = "(" + s + ")"
+			}
+		}
+
+		parts = append(parts, s)
+	}
+
+	return strings.Join(parts, sep)
+}
+
+// Authorize evaluates the policy against the principal.
+func (defaultAuthorizer) Authorize(ctx Context, principal *Principal, policy Policy) error {
+	if policy.Evaluate(ctx, principal) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// getPermissions returns all the permissions referenced by the given policy.
+func getPermissions(policy Policy) []string {
+	var (
+		permissions []string
+
+		walk func(p Policy)
+	)
+
+	walk = func(p Policy) {
+		switch v := p.(type) {
+		case permissionPolicy:
+			if !slices.Contains(permissions, string(v)) {
+				permissions = append(permissions, string(v))
+			}
+		case allOfPolicy:
+			for _, e := range v {
+				walk(e)
+			}
+		case anyOfPolicy:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+
+	if policy != nil {
+		walk(policy)
+	}
+
+	return permissions
+}
+
+// AuthorizeMiddleware creates and returns a middleware, which authorizes the
+// requests against the given policy, eg. for all the routes under a prefix
+// by MiddlewareWithMatchers. The principal must be bound before it
+// is executed, so the authentication must be registered before it.
+func AuthorizeMiddleware(policy Policy, opts ...MiddlewareOptionFunc) Middleware {
+	return NewMiddleware(func(ctx Context) {
+		var r *router
+		if c, ok := ctx.(*context); ok {
+			r = c.router
+		}
+
+		if !r.authorize(ctx, policy) {
+			return
+		}
+
+		ctx.Next()
+	}, opts...)
+}
+
+// authorize authorizes the request against the given policy. If it is denied,
+// then the proper response is rendered, and false is returned.
+// The router could be <nil>, in which case the defaults are used.
+func (r *router) authorize(ctx Context, policy Policy) bool {
+	if policy == nil {
+		return true
+	}
+
+	var (
+		authorizer       Authorizer  = defaultAuthorizer{}
+		forbiddenHandler HandlerFunc = defaultForbiddenHandler
+	)
+
+	if r != nil {
+		authorizer = r.authorizer
+		forbiddenHandler = r.forbiddenHandler
+	}
+
+	principal, _ := GetPrincipal(ctx)
+
+	err := authorizer.Authorize(ctx, principal, policy)
+	if err == nil {
+		return true
+	}
+
+	switch {
+	case !errors.Is(err, ErrForbidden):
+		ctx.StatusText(http.StatusInternalServerError)
+
+	// Without authentication the request is unauthorized, rather than forbidden.
+	case principal == nil:
+		ctx.StatusText(http.StatusUnauthorized)
+
+	default:
+		forbiddenHandler(ctx)
+	}
+
+	return false
+}
+
+func defaultForbiddenHandler(ctx Context) {
+	ctx.StatusText(http.StatusForbidden)
+}
+
+// Routes returns the information about all the registered
+// routes, ordered by their url then their method.
+func (r *router) Routes() []RouteInfo {
+	var (
+		nodes  = []*node{r.endpointTree}
+		routes = make([]RouteInfo, 0)
+	)
+
+	for i := 0; i < len(nodes); i++ {
+		currNode := nodes[i]
+		if currNode == nil {
+			continue
+		}
+
+		for method, v := range currNode.values {
+			if v == nil || v.route == nil {
+				continue
+			}
+
+			info := RouteInfo{
+				Method: method,
+				Url:    v.route.GetUrl(),
+				Name:   v.route.GetName(),
+				Policy: v.route.GetPolicy(),
+			}
+			info.Permissions = getPermissions(info.Policy)
+
+			routes = append(routes, info)
+		}
+
+		nodes = append(nodes, currNode.children...)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Url != routes[j].Url {
+			return routes[i].Url < routes[j].Url
+		}
+		return slices.Index(allowedMethodsOrder, routes[i].Method) < slices.Index(allowedMethodsOrder, routes[j].Method)
+	})
+
+	return routes
+}
@@ -0,0 +1,275 @@
+package gorouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicies(t *testing.T) {
+	type testCase struct {
+		name      string
+		policy    Policy
+		principal *Principal
+
+		expectedAllowed bool
+		expectedString  string
+	}
+
+	var (
+		admin = &Principal{
+			Subject:     "admin",
+			Roles:       []string{"admin"},
+			Permissions: []string{"orders:read", "orders:write"},
+		}
+
+		reader = &Principal{
+			Subject:     "reader",
+			Permissions: []string{"orders:read"},
+		}
+	)
+
+	tt := []testCase{
+		{
+			name:            "permission is allowed",
+			policy:          Permission("orders:read"),
+			principal:       reader,
+			expectedAllowed: true,
+			expectedString:  "orders:read",
+		},
+		{
+			name:            "permission is denied",
+			policy:          Permission("orders:write"),
+			principal:       reader,
+			expectedAllowed: false,
+			expectedString:  "orders:write",
+		},
+		{
+			name:            "permission is denied without principal",
+			policy:          Permission("orders:read"),
+			principal:       nil,
+			expectedAllowed: false,
+			expectedString:  "orders:read",
+		},
+		{
+			name:            "role is allowed",
+			policy:          Role("admin"),
+			principal:       admin,
+			expectedAllowed: true,
+			expectedString:  "role:admin",
+		},
+		{
+			name:            "authenticated is denied without principal",
+			policy:          Authenticated(),
+			principal:       nil,
+			expectedAllowed: false,
+			expectedString:  "authenticated",
+		},
+		{
+			name:            "all of requires every policy",
+			policy:          AllOf(Permission("orders:read"), Permission("orders:write")),
+			principal:       reader,
+			expectedAllowed: false,
+			expectedString:  "orders:read AND orders:write",
+		},
+		{
+			name:            "any of requires one policy",
+			policy:          AnyOf(Role("admin"), Permission("orders:read")),
+			principal:       reader,
+			expectedAllowed: true,
+			expectedString:  "role:admin OR orders:read",
+		},
+		{
+			name: "nested policies are parenthesized",
+			policy: AllOf(
+				Authenticated(),
+				AnyOf(Role("admin"), Permission("orders:write")),
+			),
+			principal:       admin,
+			expectedAllowed: true,
+			expectedString:  "authenticated AND (role:admin OR orders:write)",
+		},
+		{
+			name: "custom policy",
+			policy: NewPolicy("owner", func(_ Context, p *Principal) bool {
+				return p != nil && p.Subject == "admin"
+			}),
+			principal:       reader,
+			expectedAllowed: false,
+			expectedString:  "owner",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := NewContext(ContextConfig{})
+
+			if got := tc.policy.Evaluate(ctx, tc.principal); got != tc.expectedAllowed {
+				t.Errorf("expected allowed: %v; got: %v\n", tc.expectedAllowed, got)
+			}
+
+			if got := tc.policy.String(); got != tc.expectedString {
+				t.Errorf("expected string: %q; got: %q\n", tc.expectedString, got)
+			}
+		})
+	}
+}
+
+type errorAuthorizer struct{}
+
+func (errorAuthorizer) Authorize(_ Context, _ *Principal, _ Policy) error {
+	return errors.New("the policy store is unavailable")
+}
+
+func TestAuthorization(t *testing.T) {
+	type testCase struct {
+		name      string
+		opts      []routerOptionFunc
+		url       string
+		principal *Principal
+
+		expectedStatusCode    int
+		expectedHandlerCalled bool
+	}
+
+	var (
+		reader = &Principal{Subject: "reader", Permissions: []string{"orders:read"}}
+		writer = &Principal{Subject: "writer", Permissions: []string{"orders:read", "orders:write"}}
+		admin  = &Principal{Subject: "admin", Roles: []string{"admin"}}
+	)
+
+	tt := []testCase{
+		{
+			name:                  "route without policy is allowed",
+			url:                   "/api/public",
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+		},
+		{
+			name:                  "responds with 401 without principal",
+			url:                   "/api/orders",
+			expectedStatusCode:    http.StatusUnauthorized,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "responds with 403 without the permission",
+			url:                   "/api/orders",
+			principal:             reader,
+			expectedStatusCode:    http.StatusForbidden,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "allows the principal with the permission",
+			url:                   "/api/orders",
+			principal:             writer,
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+		},
+		{
+			name:                  "custom forbidden handler",
+			opts:                  []routerOptionFunc{WithForbiddenHandler(func(ctx Context) { ctx.Status(http.StatusNotFound) })},
+			url:                   "/api/orders",
+			principal:             reader,
+			expectedStatusCode:    http.StatusNotFound,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "responds with 500 if the authorizer fails",
+			opts:                  []routerOptionFunc{WithAuthorizer(errorAuthorizer{})},
+			url:                   "/api/orders",
+			principal:             writer,
+			expectedStatusCode:    http.StatusInternalServerError,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "the middleware policy denies the non admin",
+			url:                   "/admin/stats",
+			principal:             writer,
+			expectedStatusCode:    http.StatusForbidden,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "the middleware policy allows the admin",
+			url:                   "/admin/stats",
+			principal:             admin,
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				handlerCalled bool
+
+				r = New(tc.opts...)
+
+				handler = func(ctx Context) {
+					handlerCalled = true
+					ctx.Status(http.StatusOK)
+				}
+			)
+
+			r.RegisterMiddlewares(NewMiddleware(func(ctx Context) {
+				if tc.principal != nil {
+					ctx.BindValue(PrincipalKey, tc.principal)
+				}
+				ctx.Next()
+			}))
+
+			r.RegisterMiddlewares(AuthorizeMiddleware(Role("admin"), MiddlewareWithMatchers(func(ctx Context) bool {
+				return strings.HasPrefix(ctx.GetUrl(), "/admin")
+			})))
+
+			r.Get("/api/public", handler)
+			r.Get("/api/orders", handler).Require("orders:read", "orders:write")
+			r.Get("/admin/stats", handler)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if handlerCalled != tc.expectedHandlerCalled {
+				t.Errorf("expected handler called: %v; got: %v\n", tc.expectedHandlerCalled, handlerCalled)
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+
+	handler := func(ctx Context) {}
+
+	r.Post("/api/orders", handler).Require("orders:write").RequirePolicy(AnyOf(Role("admin"), Permission("orders:read")))
+	r.Get("/api/orders", handler).Require("orders:read").Name("orders")
+	r.Get("/api/health", handler)
+
+	expected := []RouteInfo{
+		{Method: http.MethodGet, Url: "/api/health"},
+		{Method: http.MethodGet, Url: "/api/orders", Name: "orders", Permissions: []string{"orders:read"}},
+		{Method: http.MethodPost, Url: "/api/orders", Permissions: []string{"orders:write", "orders:read"}},
+	}
+
+	got := r.Routes()
+	if len(got) != len(expected) {
+		t.Fatalf("expected routes: %d; got: %d\n", len(expected), len(got))
+	}
+
+	for i, e := range expected {
+		g := got[i]
+		if g.Method != e.Method || g.Url != e.Url || g.Name != e.Name || !reflect.DeepEqual(g.Permissions, e.Permissions) {
+			t.Errorf("expected route: %v; got: %v\n", e, g)
+		}
+	}
+
+	if expected, got := "orders:write AND (role:admin OR orders:read)", got[2].Policy.String(); got != expected {
+		t.Errorf("expected policy: %q; got: %q\n", expected, got)
+	}
+}
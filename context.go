@@ -6,12 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"weak"
 )
@@ -19,7 +22,8 @@ import (
 const (
 	contentTypeHeaderKey string = "Content-Type"
 
-	MultiPartFormContentType string = "multipart/form-data"
+	MultiPartFormContentType  string = "multipart/form-data"
+	FormUrlEncodedContentType string = "application/x-www-form-urlencoded"
 
 	// If there statusCode written to the context,
 	// this default will be written to the response.
@@ -33,10 +37,14 @@ const (
 	routeParamsKey   ContextKey = "__routeParams__"
 	queryParamsKey   ContextKey = "__queryParams__"
 	reqisteredUrlKey ContextKey = "__registeredUrl__"
+
+	// RequestIDKey is the key of the id of the request bound to the Context.
+	RequestIDKey ContextKey = "__requestId__"
 )
 
 var (
 	ErrCtNotMultipart             = errors.New("the content-type is not multipart/form-data")
+	ErrCtNotForm                  = errors.New("the content-type is not multipart/form-data or application/x-www-form-urlencoded")
 	ErrNoUnderlyingRequestPointer = errors.New("no underlying request pointer")
 )
 
@@ -52,6 +60,10 @@ type (
 )
 
 type context struct {
+	// The router which the context belongs to. It could be <nil>
+	// in case of the context was created outside of a router.
+	router *router
+
 	ctx     ctxpkg.Context
 	writer  *responseWriter
 	request weak.Pointer[http.Request]
@@ -60,9 +72,23 @@ type context struct {
 	contextIdChan contextIdChan
 	startTime     time.Time
 	maxBodySize   int64
+	keyRing       *KeyRing
 
 	isFormParsed bool
 
+	// The functions to call after the response is written.
+	deferred []func()
+
+	// The request with the context replaced by SetRequestContext. The context
+	// holds only a weak pointer to the request, so it must be kept alive.
+	derivedRequest *http.Request
+
+	// The state of the timeout set by SetTimeout.
+	deadline      time.Time
+	onTimeout     HandlerFunc
+	timeoutWriter *timeoutWriter
+	timedOut      atomic.Bool
+
 	index uint8
 }
 
@@ -84,20 +110,37 @@ type Context interface {
 	GetStartTime() time.Time
 	Next()
 	GetInfo() ContextInfo
+	Defer(fn func())
+	BeforeWrite(fn func())
+	DiscardResponse()
+	SetTimeout(d time.Duration, onTimeout HandlerFunc)
+	GetLogger() *slog.Logger
 
 	// ---- Request
 	GetRequest() *http.Request
+	GetRequestContext() ctxpkg.Context
+	SetRequestContext(c ctxpkg.Context)
 	GetRequestMethod() string
 	GetUrl() string
 	GetCleanedUrl() string
 	GetRegisteredUrl() string
 	GetQueryParams() url.Values
 	GetQueryParam(key string) string
+	QueryInt(key string, def ...int) (int, error)
+	QueryInt64(key string, def ...int64) (int64, error)
+	QueryFloat64(key string, def ...float64) (float64, error)
+	QueryBool(key string, def ...bool) (bool, error)
+	QueryTime(key string, layout string, def ...time.Time) (time.Time, error)
+	QueryDuration(key string, def ...time.Duration) (time.Duration, error)
+	QuerySlice(key string, style QuerySliceStyle) []string
 	BindValue(key ContextKey, value any)
 	GetBindedValue(key ContextKey) any
 	GetRequestHeader(key string) string
 	GetContentType() string
 	GetRequestHeaders() http.Header
+	GetCookie(name string) (*http.Cookie, error)
+	GetSignedCookie(name string) (*http.Cookie, error)
+	GetEncryptedCookie(name string) (*http.Cookie, error)
 	GetBody() io.ReadCloser
 	ParseForm() error
 	GetFormFile(string) (File, error)
@@ -117,10 +160,23 @@ type Context interface {
 	Status(statusCode int)
 	StatusText(statusCode int)
 	AppendHttpHeader(key string, value string)
+	GetResponseHeaders() http.Header
+	GetResponseWriter() http.ResponseWriter
+	SetResponseWriter(w http.ResponseWriter)
+	SetCookie(cookie *http.Cookie) error
+	SetSignedCookie(cookie *http.Cookie) error
+	SetEncryptedCookie(cookie *http.Cookie) error
+	DeleteCookie(cookie *http.Cookie) error
+	Redirect(statusCode int, url string) error
+	RedirectToRoute(name string, params map[string]string) error
 	Flush()
 	Copy(io.Reader)
 	Render(statusCode int, r Response)
 	SendJson(statusCode int, data any)
+	ServeContent(name string, modTime time.Time, content io.ReadSeeker)
+	File(path string)
+	FileFS(fsys fs.FS, name string)
+	Attachment(path string, name string)
 }
 
 var _ Context = (*context)(nil)
@@ -142,6 +198,7 @@ type ContextConfig struct {
 	ContextIdChannel          contextIdChan
 	DefaultResponseStatusCode int
 	MaxIncomingBodySize       int64
+	KeyRing                   *KeyRing
 }
 
 // NewContext creates and returns a new context.
@@ -150,6 +207,7 @@ func NewContext(conf ContextConfig) *context {
 		contextIdChan: conf.ContextIdChannel,
 		writer:        newResponseWriter(conf.DefaultResponseStatusCode),
 		maxBodySize:   conf.MaxIncomingBodySize,
+		keyRing:       conf.KeyRing,
 		index:         1,
 	}
 }
@@ -179,9 +237,50 @@ func (ctx *context) Reset(w http.ResponseWriter, r *http.Request) {
 func (ctx *context) Empty() {
 	ctx.discard()
 	ctx.writer.Empty()
+	ctx.deferred = ctx.deferred[:0]
+	ctx.deadline = time.Time{}
+	ctx.onTimeout = nil
+	ctx.timeoutWriter = nil
+	ctx.derivedRequest = nil
+	ctx.timedOut.Store(false)
+	ctx.isFormParsed = false
 	ctx.index = 1
 }
 
+// Defer registers the given function to be called after the response
+// is written – even if a panic happened during the execution.
+// The functions are called in reverse order of their registration.
+func (ctx *context) Defer(fn func()) {
+	ctx.deferred = append(ctx.deferred, fn)
+}
+
+// BeforeWrite registers the given function to be called right before the
+// status code and the headers are written – either at the end of the
+// execution or at the first Flush –, so it could still modify them,
+// eg. to set a cookie. The functions are called in order of their registration.
+func (ctx *context) BeforeWrite(fn func()) {
+	ctx.writer.beforeWrite = append(ctx.writer.beforeWrite, fn)
+}
+
+// DiscardResponse drops the status code, the buffered body and the headers
+// describing the content of the response, so a different response could be
+// written instead – eg. by a function registered by BeforeWrite, if it fails.
+// It has no effect on the response, which has been already streamed.
+func (ctx *context) DiscardResponse() {
+	if ctx.writer.isStreaming {
+		return
+	}
+
+	ctx.writer.reset()
+}
+
+// runDeferred calls the deferred functions of the context.
+func (ctx *context) runDeferred() {
+	for i := len(ctx.deferred) - 1; i >= 0; i-- {
+		ctx.deferred[i]()
+	}
+}
+
 // GetContextId returns the id of the context entity.
 func (ctx *context) GetContextId() uint64 {
 	return ctx.contextId
@@ -207,14 +306,22 @@ func (ctx *context) GetInfo() ContextInfo {
 		method = r.Method
 	}
 
-	return ContextInfo{
+	info := ContextInfo{
 		Id:           ctx.contextId,
 		WrittenBytes: int64(ctx.writer.writtenBytes),
 		StartTime:    ctx.startTime,
 		Url:          ctx.GetUrl(),
-		StatusCode:   ctx.writer.statusCode,
+		StatusCode:   ctx.writer.getStatusCode(),
 		Method:       method,
 	}
+
+	// The response of the timed out handler has been replaced.
+	if tw := ctx.timeoutWriter; ctx.isTimedOut() && tw != nil && tw.statusCode > 0 {
+		info.StatusCode = tw.statusCode
+		info.WrittenBytes = int64(tw.writtenBytes)
+	}
+
+	return info
 }
 
 // GetRequest returns the attached http.Request pointer.
@@ -222,6 +329,18 @@ func (ctx *context) GetRequest() *http.Request {
 	return ctx.request.Value()
 }
 
+// SetRequestContext replaces the context of the request, so the values
+// of it – eg. the span of the tracing – are available for the handlers.
+func (ctx *context) SetRequestContext(c ctxpkg.Context) {
+	r := ctx.GetRequest()
+	if r == nil || c == nil {
+		return
+	}
+
+	ctx.derivedRequest = r.WithContext(c)
+	ctx.request = weak.Make(ctx.derivedRequest)
+}
+
 // GetRequestMethod returns the method of incoming request.
 func (ctx *context) GetRequestMethod() string {
 	if ctx == nil {
@@ -257,6 +376,13 @@ func (ctx *context) GetBindedValue(key ContextKey) any {
 	return bindedValues[key]
 }
 
+// GetRequestID returns the id of the request bound to the
+// given Context by the RequestID middleware – if there is any.
+func GetRequestID(ctx Context) string {
+	id, _ := ctx.GetBindedValue(RequestIDKey).(string)
+	return id
+}
+
 // GetlUrl returns the full URL with all queryParams included.
 func (ctx *context) GetUrl() string {
 	r := ctx.GetRequest()
@@ -339,58 +465,54 @@ func (ctx *context) GetParam(key string) string {
 
 // GetIntParam returns the parsed integer value of the param identified by the given key.
 func (ctx *context) GetIntParam(key string) (int, error) {
-	v := ctx.GetParams()[key]
-	return strconv.Atoi(v)
+	i, err := ctx.parseIntParam(key, "int", strconv.IntSize)
+	return int(i), err
 }
 
 // GetInt8Param returns the parsed int8 value of the param identified by the given key.
 func (ctx *context) GetInt8Param(key string) (int8, error) {
-	i, err := ctx.GetIntParam(key)
-	if err != nil {
-		return 0, nil
-	}
-	return int8(i), nil
+	i, err := ctx.parseIntParam(key, "int8", 8)
+	return int8(i), err
 }
 
 // GetInt16Param returns the parsed int16 value of the param identified by the given key.
 func (ctx *context) GetInt16Param(key string) (int16, error) {
-	i, err := ctx.GetIntParam(key)
-	if err != nil {
-		return 0, nil
-	}
-	return int16(i), nil
+	i, err := ctx.parseIntParam(key, "int16", 16)
+	return int16(i), err
 }
 
 // GetInt32Param returns the parsed int32 value of the param identified by the given key.
 func (ctx *context) GetInt32Param(key string) (int32, error) {
-	i, err := ctx.GetIntParam(key)
-	if err != nil {
-		return 0, nil
-	}
-	return int32(i), nil
+	i, err := ctx.parseIntParam(key, "int32", 32)
+	return int32(i), err
 }
 
 // GetInt64Param returns the parsed int64 value of the param identified by the given key.
 func (ctx *context) GetInt64Param(key string) (int64, error) {
-	i, err := ctx.GetIntParam(key)
-	if err != nil {
-		return 0, nil
-	}
-	return int64(i), nil
+	return ctx.parseIntParam(key, "int64", 64)
 }
 
 // GetFloat32Param returns the parsed float32 value of the param identified by the given key.
 func (ctx *context) GetFloat32Param(key string) (float32, error) {
-	f, err := ctx.GetFloat64Param(key)
-	if err != nil {
-		return 0.0, err
-	}
-	return float32(f), nil
+	f, err := ctx.parseFloatParam(key, "float32", 32)
+	return float32(f), err
 }
 
 // GetFloat64Param returns the parsed float64 value of the param identified by the given key.
 func (ctx *context) GetFloat64Param(key string) (float64, error) {
-	return strconv.ParseFloat(ctx.GetParam(key), 64)
+	return ctx.parseFloatParam(key, "float64", 64)
+}
+
+func (ctx *context) parseIntParam(key string, typeName string, bitSize int) (int64, error) {
+	return parsePathParam(ctx, key, typeName, func(v string) (int64, error) {
+		return strconv.ParseInt(v, 10, bitSize)
+	})
+}
+
+func (ctx *context) parseFloatParam(key string, typeName string, bitSize int) (float64, error) {
+	return parsePathParam(ctx, key, typeName, func(v string) (float64, error) {
+		return strconv.ParseFloat(v, bitSize)
+	})
 }
 
 // GetParams returns all the path params associated with thre context.
@@ -407,20 +529,38 @@ func (ctx *context) GetParams() pathParams {
 	return params
 }
 
-// ParseForm tries to parse the incoming request as a formdata. Returns error
-// if the content-type is not valid, or the native parse returns error.
+// ParseForm tries to parse the incoming request as a multipart/form-data or
+// application/x-www-form-urlencoded form. Returns error if the content-type
+// is not valid, or the native parse returns error.
 func (ctx *context) ParseForm() error {
 	if ctx.isFormParsed {
 		return nil
 	}
-	if !strings.Contains(ctx.GetContentType(), MultiPartFormContentType) {
-		return ErrCtNotMultipart
+
+	r := ctx.GetRequest()
+	if r == nil {
+		return ErrNoUnderlyingRequestPointer
 	}
-	ctx.isFormParsed = true
-	if r := ctx.GetRequest(); r != nil {
+
+	ct := ctx.GetContentType()
+
+	switch {
+	case strings.Contains(ct, MultiPartFormContentType):
+		ctx.isFormParsed = true
+
 		return r.ParseMultipartForm(ctx.maxBodySize)
+
+	case strings.Contains(ct, FormUrlEncodedContentType):
+		ctx.isFormParsed = true
+
+		if ctx.maxBodySize > 0 {
+			r.Body = http.MaxBytesReader(ctx.writer.w, r.Body, ctx.maxBodySize)
+		}
+
+		return r.ParseForm()
 	}
-	return errors.New("no underlying request pointer")
+
+	return ErrCtNotForm
 }
 
 // GetFormValue returns the value in the form associated with
@@ -440,6 +580,9 @@ func (ctx *context) GetFormValue(key string) (string, error) {
 // GetFormFile returns the File and and error associated with
 // the given key. It calls ParseForm, if has to.
 func (ctx *context) GetFormFile(key string) (File, error) {
+	if !strings.Contains(ctx.GetContentType(), MultiPartFormContentType) {
+		return nil, ErrCtNotMultipart
+	}
 	if !ctx.isFormParsed {
 		if err := ctx.ParseForm(); err != nil {
 			return nil, err
@@ -507,7 +650,31 @@ func (ctx *context) AppendHttpHeader(key string, value string) {
 	ctx.writer.addHeader(key, value)
 }
 
-// WriteToResponseNow writes the actual response of context to the underlying connection.
+// GetResponseHeaders returns the headers of the response,
+// which could be modified until the response is written.
+func (ctx *context) GetResponseHeaders() http.Header {
+	if ctx.writer.w == nil {
+		return http.Header{}
+	}
+	return ctx.writer.w.Header()
+}
+
+// GetResponseWriter returns the underlying http.ResponseWriter,
+// where the response is written to by the context.
+func (ctx *context) GetResponseWriter() http.ResponseWriter {
+	return ctx.writer.w
+}
+
+// SetResponseWriter replaces the underlying http.ResponseWriter, so the
+// response could be transformed – eg. compressed – by wrapping it.
+// It must be called before the response is streamed.
+func (ctx *context) SetResponseWriter(w http.ResponseWriter) {
+	ctx.writer.w = w
+}
+
+// Flush writes the status code, the headers and the buffered response
+// to the underlying connection right away. From that point the response
+// is streamed, meaning every subsequent write goes directly to the connection.
 func (ctx *context) Flush() {
 	ctx.writer.flush()
 }
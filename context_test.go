@@ -2,12 +2,15 @@ package gorouter
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 type contextFactory func(*testing.T) Context
@@ -222,3 +225,317 @@ func TestResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTypedParams(t *testing.T) {
+	type testCase struct {
+		name   string
+		value  string
+		get    func(Context) (any, error)
+		result any
+		err    error
+	}
+
+	tt := []testCase{
+		{
+			name:   "parses int8",
+			value:  "-128",
+			get:    func(ctx Context) (any, error) { return ctx.GetInt8Param("p") },
+			result: int8(-128),
+		},
+		{
+			name:   "returns error for overflowing int8",
+			value:  "128",
+			get:    func(ctx Context) (any, error) { return ctx.GetInt8Param("p") },
+			result: int8(127),
+			err:    strconv.ErrRange,
+		},
+		{
+			name:   "returns error for overflowing int16",
+			value:  "40000",
+			get:    func(ctx Context) (any, error) { return ctx.GetInt16Param("p") },
+			result: int16(32767),
+			err:    strconv.ErrRange,
+		},
+		{
+			name:   "returns error for invalid int32",
+			value:  "abc",
+			get:    func(ctx Context) (any, error) { return ctx.GetInt32Param("p") },
+			result: int32(0),
+			err:    strconv.ErrSyntax,
+		},
+		{
+			name:   "parses int64",
+			value:  "9223372036854775807",
+			get:    func(ctx Context) (any, error) { return ctx.GetInt64Param("p") },
+			result: int64(9223372036854775807),
+		},
+		{
+			name:   "returns error for missing param",
+			value:  "1",
+			get:    func(ctx Context) (any, error) { return ctx.GetIntParam("missing") },
+			result: 0,
+			err:    ErrParamNotFound,
+		},
+		{
+			name:   "parses float32",
+			value:  "1.5",
+			get:    func(ctx Context) (any, error) { return ctx.GetFloat32Param("p") },
+			result: float32(1.5),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := NewContext(ContextConfig{})
+			ctx.Reset(nil, httptest.NewRequest(http.MethodGet, "/", nil))
+			ctx.BindValue(routeParamsKey, pathParams{"p": tc.value})
+
+			result, err := tc.get(ctx)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+
+			var paramErr *ParamError
+			if tc.err != nil && (!errors.As(err, &paramErr) || paramErr.Source != "path") {
+				t.Errorf("expected path *ParamError; got: %T\n", err)
+			}
+
+			if tc.err == nil && !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("expected result: %v; got result: %v\n", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	type testCase struct {
+		name   string
+		query  string
+		get    func(Context) (any, error)
+		result any
+		err    error
+	}
+
+	var day = time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tt := []testCase{
+		{
+			name:   "parses int",
+			query:  "page=3",
+			get:    func(ctx Context) (any, error) { return ctx.QueryInt("page") },
+			result: 3,
+		},
+		{
+			name:   "returns the default for missing int",
+			query:  "",
+			get:    func(ctx Context) (any, error) { return ctx.QueryInt("page", 1) },
+			result: 1,
+		},
+		{
+			name:   "returns the default for empty int",
+			query:  "page=",
+			get:    func(ctx Context) (any, error) { return ctx.QueryInt("page", 1) },
+			result: 1,
+		},
+		{
+			name:   "returns error for missing int without default",
+			query:  "",
+			get:    func(ctx Context) (any, error) { return ctx.QueryInt("page") },
+			result: 0,
+			err:    ErrParamNotFound,
+		},
+		{
+			name:   "returns error for invalid int even with default",
+			query:  "page=first",
+			get:    func(ctx Context) (any, error) { return ctx.QueryInt("page", 1) },
+			result: 0,
+			err:    strconv.ErrSyntax,
+		},
+		{
+			name:   "returns error for overflowing int64",
+			query:  "id=9223372036854775808",
+			get:    func(ctx Context) (any, error) { return ctx.QueryInt64("id") },
+			result: int64(0),
+			err:    strconv.ErrRange,
+		},
+		{
+			name:   "parses float64",
+			query:  "lat=47.5",
+			get:    func(ctx Context) (any, error) { return ctx.QueryFloat64("lat") },
+			result: 47.5,
+		},
+		{
+			name:   "parses bool",
+			query:  "active=true",
+			get:    func(ctx Context) (any, error) { return ctx.QueryBool("active") },
+			result: true,
+		},
+		{
+			name:   "parses checkbox bool",
+			query:  "active=on",
+			get:    func(ctx Context) (any, error) { return ctx.QueryBool("active", false) },
+			result: true,
+		},
+		{
+			name:   "parses time with layout",
+			query:  "from=2025-03-01",
+			get:    func(ctx Context) (any, error) { return ctx.QueryTime("from", time.DateOnly) },
+			result: day,
+		},
+		{
+			name:   "returns error for invalid time",
+			query:  "from=yesterday",
+			get:    func(ctx Context) (any, error) { return ctx.QueryTime("from", time.DateOnly, day) },
+			result: time.Time{},
+			err:    &time.ParseError{},
+		},
+		{
+			name:   "parses duration",
+			query:  "timeout=1m30s",
+			get:    func(ctx Context) (any, error) { return ctx.QueryDuration("timeout") },
+			result: 90 * time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := NewContext(ContextConfig{})
+			ctx.Reset(nil, httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil))
+
+			result, err := tc.get(ctx)
+
+			if tc.err == nil && err != nil {
+				t.Fatalf("expected no error; got error: %v\n", err)
+			}
+
+			if tc.err != nil {
+				var paramErr *ParamError
+				if !errors.As(err, &paramErr) || paramErr.Source != "query" {
+					t.Fatalf("expected query *ParamError; got: %v\n", err)
+				}
+
+				if target := tc.err; !errors.Is(err, target) && reflect.TypeOf(paramErr.Err) != reflect.TypeOf(target) {
+					t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+				}
+			}
+
+			if !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("expected result: %v; got result: %v\n", tc.result, result)
+			}
+		})
+	}
+}
+
+func TestQuerySlice(t *testing.T) {
+	type testCase struct {
+		name     string
+		query    string
+		style    QuerySliceStyle
+		expected []string
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns empty slice for missing key",
+			query:    "",
+			style:    QuerySliceRepeated,
+			expected: []string{},
+		},
+		{
+			name:     "returns repeated values",
+			query:    "id=1&id=2&id=&id=3,4",
+			style:    QuerySliceRepeated,
+			expected: []string{"1", "2", "3,4"},
+		},
+		{
+			name:     "returns comma separated values",
+			query:    "id=1,%202,,3&id=4",
+			style:    QuerySliceComma,
+			expected: []string{"1", "2", "3", "4"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := NewContext(ContextConfig{})
+			ctx.Reset(nil, httptest.NewRequest(http.MethodGet, "/?"+tc.query, nil))
+
+			if got := ctx.QuerySlice("id", tc.style); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected values: %v; got values: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	type testCase struct {
+		name        string
+		contentType string
+		body        string
+		maxBodySize int64
+
+		expectedValue string
+		expectedError error
+		isError       bool
+	}
+
+	tt := []testCase{
+		{
+			name:          "parses the urlencoded form",
+			contentType:   FormUrlEncodedContentType,
+			body:          "name=John+Doe&age=30",
+			expectedValue: "John Doe",
+		},
+		{
+			name:          "parses the multipart form",
+			contentType:   MultiPartFormContentType + "; boundary=xxx",
+			body:          "--xxx\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nJohn Doe\r\n--xxx--\r\n",
+			maxBodySize:   defaultMaxFormBodySize,
+			expectedValue: "John Doe",
+		},
+		{
+			name:          "returns error for json",
+			contentType:   "application/json",
+			body:          `{"name":"John Doe"}`,
+			expectedError: ErrCtNotForm,
+			isError:       true,
+		},
+		{
+			name:        "returns error for too large urlencoded form",
+			contentType: FormUrlEncodedContentType,
+			body:        "name=" + strings.Repeat("a", 100),
+			maxBodySize: 10,
+			isError:     true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				ctx = NewContext(ContextConfig{MaxIncomingBodySize: tc.maxBodySize})
+				req = httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(tc.body))
+			)
+
+			req.Header.Set(contentTypeHeaderKey, tc.contentType)
+			ctx.Reset(httptest.NewRecorder(), req)
+
+			value, err := ctx.GetFormValue("name")
+
+			if tc.isError {
+				if err == nil || (tc.expectedError != nil && !errors.Is(err, tc.expectedError)) {
+					t.Errorf("expected error: %v; got: %v\n", tc.expectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error; got: %v\n", err)
+			}
+
+			if value != tc.expectedValue {
+				t.Errorf("expected value: %q; got: %q\n", tc.expectedValue, value)
+			}
+		})
+	}
+}
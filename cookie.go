@@ -0,0 +1,385 @@
+package gorouter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	setCookieHeaderKey string = "Set-Cookie"
+
+	// Browsers are not obliged to store cookies larger than this.
+	maxCookieSize int = 4096
+
+	// The minimum length of a key in the key ring.
+	minKeyLength int = 32
+
+	signedCookieSeparator string = "."
+)
+
+var (
+	// The labels of the derived keys, so the same
+	// key is never used for signing and encryption.
+	signingKeyLabel    = []byte("gorouter cookie signing key")
+	encryptionKeyLabel = []byte("gorouter cookie encryption key")
+
+	cookieEncoding = base64.RawURLEncoding
+)
+
+var (
+	ErrNoKeyRing        = errors.New("there is no key ring configured")
+	ErrEmptyKeyRing     = errors.New("the key ring must contain at least one key")
+	ErrKeyTooShort      = errors.New("the key must be at least 32 bytes long")
+	ErrCookieTooLarge   = errors.New("the cookie exceeds the maximum size of 4096 bytes")
+	ErrInvalidSignature = errors.New("the signature of the cookie is invalid")
+	ErrCookieDecryption = errors.New("the cookie could not be decrypted")
+	ErrMalformedCookie  = errors.New("the value of the cookie is malformed")
+)
+
+type ringKey struct {
+	signingKey []byte
+	aead       cipher.AEAD
+}
+
+// KeyRing holds the keys for signing and encrypting cookies.
+// The first – primary – key is used for signing and encryption,
+// while every key is tried for verification and decryption,
+// so the keys could be rotated without invalidating the
+// already issued cookies.
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys []*ringKey
+}
+
+// NewKeyRing creates and returns a new KeyRing with the given keys,
+// where the first one is the primary. Every key must be at least 32 bytes.
+func NewKeyRing(keys ...[]byte) (*KeyRing, error) {
+	kr := &KeyRing{}
+
+	if err := kr.SetKeys(keys...); err != nil {
+		return nil, err
+	}
+
+	return kr, nil
+}
+
+// SetKeys replaces all the keys of the ring. The first key is the primary.
+func (kr *KeyRing) SetKeys(keys ...[]byte) error {
+	if len(keys) == 0 {
+		return ErrEmptyKeyRing
+	}
+
+	ringKeys := make([]*ringKey, 0, len(keys))
+
+	for _, k := range keys {
+		rk, err := newRingKey(k)
+		if err != nil {
+			return err
+		}
+		ringKeys = append(ringKeys, rk)
+	}
+
+	kr.mu.Lock()
+	kr.keys = ringKeys
+	kr.mu.Unlock()
+
+	return nil
+}
+
+// Rotate makes the given key the primary one, while
+// the previous keys are kept only for verification.
+func (kr *KeyRing) Rotate(key []byte) error {
+	rk, err := newRingKey(key)
+	if err != nil {
+		return err
+	}
+
+	kr.mu.Lock()
+	kr.keys = append([]*ringKey{rk}, kr.keys...)
+	kr.mu.Unlock()
+
+	return nil
+}
+
+// Retire removes all, but the given count of the most recent keys.
+func (kr *KeyRing) Retire(keep int) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if keep > 0 && keep < len(kr.keys) {
+		kr.keys = kr.keys[:keep]
+	}
+}
+
+// primary returns the primary key of the ring, or <nil> if the ring is empty.
+func (kr *KeyRing) primary() *ringKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	if len(kr.keys) == 0 {
+		return nil
+	}
+
+	return kr.keys[0]
+}
+
+// Sign returns the value alongside its HMAC-SHA256 signature
+// created by the primary key. The name of the cookie is also
+// signed, so the value can not be used for an other cookie.
+// If the ring is empty, then an empty string is returned,
+// which is never verified.
+func (kr *KeyRing) Sign(name string, value string) string {
+	primary := kr.primary()
+	if primary == nil {
+		return ""
+	}
+
+	var (
+		encoded   = cookieEncoding.EncodeToString([]byte(value))
+		signature = primary.sign(name, encoded)
+	)
+
+	return encoded + signedCookieSeparator + cookieEncoding.EncodeToString(signature)
+}
+
+// Verify returns the original value of the signed value,
+// if it was signed by any of the keys of the ring.
+func (kr *KeyRing) Verify(name string, signed string) (string, error) {
+	encoded, encodedSignature, ok := strings.Cut(signed, signedCookieSeparator)
+	if !ok {
+		return "", ErrMalformedCookie
+	}
+
+	signature, err := cookieEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", ErrMalformedCookie
+	}
+
+	kr.mu.RLock()
+	keys := kr.keys
+	kr.mu.RUnlock()
+
+	for _, k := range keys {
+		if !hmac.Equal(k.sign(name, encoded), signature) {
+			continue
+		}
+
+		value, err := cookieEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", ErrMalformedCookie
+		}
+
+		return string(value), nil
+	}
+
+	return "", ErrInvalidSignature
+}
+
+// Encrypt encrypts the given plaintext with AES-GCM by the
+// primary key. The name is used as additional authenticated data.
+func (kr *KeyRing) Encrypt(name string, plaintext []byte) (string, error) {
+	primary := kr.primary()
+	if primary == nil {
+		return "", ErrEmptyKeyRing
+	}
+
+	nonce := make([]byte, primary.aead.NonceSize(), primary.aead.NonceSize()+len(plaintext)+primary.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := primary.aead.Seal(nonce, nonce, plaintext, []byte(name))
+
+	return cookieEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of the given value, if it was
+// encrypted by any of the keys of the ring with the same name.
+func (kr *KeyRing) Decrypt(name string, value string) ([]byte, error) {
+	sealed, err := cookieEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrMalformedCookie
+	}
+
+	kr.mu.RLock()
+	keys := kr.keys
+	kr.mu.RUnlock()
+
+	for _, k := range keys {
+		nonceSize := k.aead.NonceSize()
+		if len(sealed) < nonceSize {
+			return nil, ErrMalformedCookie
+		}
+
+		plaintext, err := k.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+		if err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, ErrCookieDecryption
+}
+
+func newRingKey(key []byte) (*ringKey, error) {
+	if len(key) < minKeyLength {
+		return nil, ErrKeyTooShort
+	}
+
+	block, err := aes.NewCipher(deriveKey(key, encryptionKeyLabel))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ringKey{
+		signingKey: deriveKey(key, signingKeyLabel),
+		aead:       aead,
+	}, nil
+}
+
+func (rk *ringKey) sign(name string, encoded string) []byte {
+	mac := hmac.New(sha256.New, rk.signingKey)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(encoded))
+
+	return mac.Sum(nil)
+}
+
+// deriveKey derives a 32 bytes long key for the given purpose.
+func deriveKey(key []byte, label []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(label)
+
+	return mac.Sum(nil)
+}
+
+// GetCookie returns the cookie of the request with the given name.
+func (ctx *context) GetCookie(name string) (*http.Cookie, error) {
+	r := ctx.GetRequest()
+	if r == nil {
+		return nil, ErrNoUnderlyingRequestPointer
+	}
+	return r.Cookie(name)
+}
+
+// SetCookie appends the given cookie to the response,
+// if it is valid and does not exceed the maximum size.
+func (ctx *context) SetCookie(cookie *http.Cookie) error {
+	if err := cookie.Valid(); err != nil {
+		return err
+	}
+
+	v := cookie.String()
+	if len(v) > maxCookieSize {
+		return ErrCookieTooLarge
+	}
+
+	ctx.AppendHttpHeader(setCookieHeaderKey, v)
+
+	return nil
+}
+
+// DeleteCookie instructs the client to delete the given cookie.
+// The Path, Domain, Secure, SameSite and Partitioned attributes
+// must be the same, as they were when the cookie was set.
+func (ctx *context) DeleteCookie(cookie *http.Cookie) error {
+	c := *cookie
+	c.Value = ""
+	c.MaxAge = -1
+	c.Expires = time.Unix(0, 0)
+
+	return ctx.SetCookie(&c)
+}
+
+// SetSignedCookie appends the given cookie to the response
+// with its value signed by the primary key of the key ring.
+func (ctx *context) SetSignedCookie(cookie *http.Cookie) error {
+	if ctx.keyRing == nil {
+		return ErrNoKeyRing
+	}
+
+	if ctx.keyRing.primary() == nil {
+		return ErrEmptyKeyRing
+	}
+
+	c := *cookie
+	c.Value = ctx.keyRing.Sign(c.Name, c.Value)
+
+	return ctx.SetCookie(&c)
+}
+
+// GetSignedCookie returns the cookie of the request with the
+// given name, with its verified – original – value.
+func (ctx *context) GetSignedCookie(name string) (*http.Cookie, error) {
+	if ctx.keyRing == nil {
+		return nil, ErrNoKeyRing
+	}
+
+	cookie, err := ctx.GetCookie(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ctx.keyRing.Verify(name, cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie.Value = value
+
+	return cookie, nil
+}
+
+// SetEncryptedCookie appends the given cookie to the response
+// with its value encrypted by the primary key of the key ring.
+func (ctx *context) SetEncryptedCookie(cookie *http.Cookie) error {
+	if ctx.keyRing == nil {
+		return ErrNoKeyRing
+	}
+
+	value, err := ctx.keyRing.Encrypt(cookie.Name, []byte(cookie.Value))
+	if err != nil {
+		return err
+	}
+
+	c := *cookie
+	c.Value = value
+
+	return ctx.SetCookie(&c)
+}
+
+// GetEncryptedCookie returns the cookie of the request with
+// the given name, with its decrypted value.
+func (ctx *context) GetEncryptedCookie(name string) (*http.Cookie, error) {
+	if ctx.keyRing == nil {
+		return nil, ErrNoKeyRing
+	}
+
+	cookie, err := ctx.GetCookie(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ctx.keyRing.Decrypt(name, cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie.Value = string(value)
+
+	return cookie, nil
+}
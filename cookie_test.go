@@ -0,0 +1,349 @@
+package gorouter
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var (
+	mockKey1 = bytes.Repeat([]byte{1}, 32)
+	mockKey2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestNewKeyRing(t *testing.T) {
+	type testCase struct {
+		name string
+		keys [][]byte
+		err  error
+	}
+
+	tt := []testCase{
+		{
+			name: "returns error if there is no key",
+			keys: nil,
+			err:  ErrEmptyKeyRing,
+		},
+		{
+			name: "returns error if a key is too short",
+			keys: [][]byte{mockKey1, []byte("short")},
+			err:  ErrKeyTooShort,
+		},
+		{
+			name: "returns the key ring",
+			keys: [][]byte{mockKey1, mockKey2},
+			err:  nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewKeyRing(tc.keys...); !errors.Is(err, tc.err) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestKeyRingSign(t *testing.T) {
+	kr, _ := NewKeyRing(mockKey1)
+
+	signed := kr.Sign("session", "user:1")
+
+	type testCase struct {
+		name     string
+		getRing  func(*testing.T) *KeyRing
+		cookie   string
+		value    string
+		expected string
+		err      error
+	}
+
+	tt := []testCase{
+		{
+			name:     "verifies the signed value",
+			getRing:  func(*testing.T) *KeyRing { return kr },
+			cookie:   "session",
+			value:    signed,
+			expected: "user:1",
+		},
+		{
+			name:    "rejects the value signed for an other cookie",
+			getRing: func(*testing.T) *KeyRing { return kr },
+			cookie:  "other",
+			value:   signed,
+			err:     ErrInvalidSignature,
+		},
+		{
+			name:    "rejects the tampered value",
+			getRing: func(*testing.T) *KeyRing { return kr },
+			cookie:  "session",
+			value:   "dXNlcjoy" + signed[strings.Index(signed, "."):],
+			err:     ErrInvalidSignature,
+		},
+		{
+			name:    "rejects the value without signature",
+			getRing: func(*testing.T) *KeyRing { return kr },
+			cookie:  "session",
+			value:   "dXNlcjoy",
+			err:     ErrMalformedCookie,
+		},
+		{
+			name: "verifies the value signed by a rotated key",
+			getRing: func(t *testing.T) *KeyRing {
+				rotated, _ := NewKeyRing(mockKey1)
+				if err := rotated.Rotate(mockKey2); err != nil {
+					t.Fatal(err)
+				}
+				return rotated
+			},
+			cookie:   "session",
+			value:    signed,
+			expected: "user:1",
+		},
+		{
+			name: "rejects the value signed by a retired key",
+			getRing: func(t *testing.T) *KeyRing {
+				rotated, _ := NewKeyRing(mockKey1)
+				if err := rotated.Rotate(mockKey2); err != nil {
+					t.Fatal(err)
+				}
+				rotated.Retire(1)
+				return rotated
+			},
+			cookie: "session",
+			value:  signed,
+			err:    ErrInvalidSignature,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := tc.getRing(t).Verify(tc.cookie, tc.value)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+
+			if value != tc.expected {
+				t.Errorf("expected value: %s; got value: %s\n", tc.expected, value)
+			}
+		})
+	}
+}
+
+func TestKeyRingEncrypt(t *testing.T) {
+	kr, _ := NewKeyRing(mockKey1)
+
+	encrypted, err := kr.Encrypt("session", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(encrypted, "secret") {
+		t.Fatal("the encrypted value contains the plaintext")
+	}
+
+	rotated, _ := NewKeyRing(mockKey2, mockKey1)
+
+	type testCase struct {
+		name     string
+		ring     *KeyRing
+		cookie   string
+		value    string
+		expected string
+		err      error
+	}
+
+	tt := []testCase{
+		{
+			name:     "decrypts the value",
+			ring:     kr,
+			cookie:   "session",
+			value:    encrypted,
+			expected: "secret",
+		},
+		{
+			name:     "decrypts the value encrypted by a previous key",
+			ring:     rotated,
+			cookie:   "session",
+			value:    encrypted,
+			expected: "secret",
+		},
+		{
+			name:   "rejects the value encrypted for an other cookie",
+			ring:   kr,
+			cookie: "other",
+			value:  encrypted,
+			err:    ErrCookieDecryption,
+		},
+		{
+			name:   "rejects the malformed value",
+			ring:   kr,
+			cookie: "session",
+			value:  "!!!",
+			err:    ErrMalformedCookie,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := tc.ring.Decrypt(tc.cookie, tc.value)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+
+			if string(value) != tc.expected {
+				t.Errorf("expected value: %s; got value: %s\n", tc.expected, value)
+			}
+		})
+	}
+}
+
+func TestEmptyKeyRing(t *testing.T) {
+	var kr KeyRing
+
+	if got := kr.Sign("session", "value"); got != "" {
+		t.Errorf("expected signed value: %q; got: %q\n", "", got)
+	}
+
+	if _, err := kr.Encrypt("session", []byte("value")); !errors.Is(err, ErrEmptyKeyRing) {
+		t.Errorf("expected error: %v; got: %v\n", ErrEmptyKeyRing, err)
+	}
+
+	ctx := NewContext(ContextConfig{KeyRing: &kr})
+	ctx.Reset(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	for _, set := range []func(*http.Cookie) error{ctx.SetSignedCookie, ctx.SetEncryptedCookie} {
+		if err := set(&http.Cookie{Name: "session", Value: "value"}); !errors.Is(err, ErrEmptyKeyRing) {
+			t.Errorf("expected error: %v; got: %v\n", ErrEmptyKeyRing, err)
+		}
+	}
+}
+
+func TestSetCookie(t *testing.T) {
+	type testCase struct {
+		name     string
+		set      func(Context) error
+		expected string
+		hasError bool
+		err      error
+	}
+
+	tt := []testCase{
+		{
+			name: "writes all the attributes",
+			set: func(ctx Context) error {
+				return ctx.SetCookie(&http.Cookie{
+					Name:        "__Host-id",
+					Value:       "1",
+					Path:        "/",
+					MaxAge:      60,
+					Secure:      true,
+					HttpOnly:    true,
+					SameSite:    http.SameSiteStrictMode,
+					Partitioned: true,
+				})
+			},
+			expected: "__Host-id=1; Path=/; Max-Age=60; HttpOnly; Secure; SameSite=Strict; Partitioned",
+		},
+		{
+			name: "returns error for invalid cookie",
+			set: func(ctx Context) error {
+				return ctx.SetCookie(&http.Cookie{Name: "invalid name", Value: "1"})
+			},
+			hasError: true,
+		},
+		{
+			name: "returns error for too large cookie",
+			set: func(ctx Context) error {
+				return ctx.SetCookie(&http.Cookie{Name: "big", Value: strings.Repeat("a", maxCookieSize)})
+			},
+			hasError: true,
+			err:      ErrCookieTooLarge,
+		},
+		{
+			name: "deletes the cookie",
+			set: func(ctx Context) error {
+				return ctx.DeleteCookie(&http.Cookie{Name: "id", Path: "/"})
+			},
+			expected: "id=; Path=/; Expires=Thu, 01 Jan 1970 00:00:00 GMT; Max-Age=0",
+		},
+		{
+			name: "returns error for signed cookie without key ring",
+			set: func(ctx Context) error {
+				return ctx.SetSignedCookie(&http.Cookie{Name: "id", Value: "1"})
+			},
+			hasError: true,
+			err:      ErrNoKeyRing,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				ctx = NewContext(ContextConfig{})
+				rec = httptest.NewRecorder()
+			)
+
+			ctx.Reset(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			err := tc.set(ctx)
+			if hasError := err != nil; hasError != tc.hasError {
+				t.Fatalf("expected error: %t; got error: %v\n", tc.hasError, err)
+			}
+
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+
+			if got := rec.Header().Get("Set-Cookie"); got != tc.expected {
+				t.Errorf("expected cookie: %s; got cookie: %s\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSignedAndEncryptedCookieRoundTrip(t *testing.T) {
+	kr, _ := NewKeyRing(mockKey1)
+
+	var (
+		ctx = NewContext(ContextConfig{KeyRing: kr})
+		rec = httptest.NewRecorder()
+	)
+
+	ctx.Reset(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if err := ctx.SetSignedCookie(&http.Cookie{Name: "signed", Value: "hello world"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ctx.SetEncryptedCookie(&http.Cookie{Name: "encrypted", Value: "top secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	ctx.Reset(httptest.NewRecorder(), req)
+
+	signed, err := ctx.GetSignedCookie("signed")
+	if err != nil || signed.Value != "hello world" {
+		t.Errorf("expected signed value: %q; got: %v (%v)\n", "hello world", signed, err)
+	}
+
+	encrypted, err := ctx.GetEncryptedCookie("encrypted")
+	if err != nil || encrypted.Value != "top secret" {
+		t.Errorf("expected encrypted value: %q; got: %v (%v)\n", "top secret", encrypted, err)
+	}
+
+	if _, err := ctx.GetSignedCookie("missing"); !errors.Is(err, http.ErrNoCookie) {
+		t.Errorf("expected error: %v; got error: %v\n", http.ErrNoCookie, err)
+	}
+}
@@ -0,0 +1,239 @@
+package gorouter
+
+import (
+	"bytes"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rtpprof "runtime/pprof"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDebugPrefix string = "/debug"
+
+	debugProfileParamKey string = "profile"
+)
+
+// ErrDebugAuthRequired is the panic of Debug, if the endpoints would be
+// served by the main listener without any middleware guarding them.
+var ErrDebugAuthRequired = errors.New("the debug endpoints of the main listener require an auth middleware")
+
+// GCStats is the response of the GC stats debug endpoint.
+type GCStats struct {
+	NumGC        int64           `json:"numGc"`
+	LastGC       time.Time       `json:"lastGc"`
+	PauseTotal   time.Duration   `json:"pauseTotalNs"`
+	RecentPauses []time.Duration `json:"recentPausesNs"`
+	CPUFraction  float64         `json:"gcCpuFraction"`
+	HeapAlloc    uint64          `json:"heapAlloc"`
+	HeapSys      uint64          `json:"heapSys"`
+	HeapObjects  uint64          `json:"heapObjects"`
+	NextGC       uint64          `json:"nextGc"`
+	TotalAlloc   uint64          `json:"totalAlloc"`
+	Sys          uint64          `json:"sys"`
+	MemoryLimit  int64           `json:"memoryLimit"`
+	NumGoroutine int             `json:"numGoroutine"`
+	GOMAXPROCS   int             `json:"gomaxprocs"`
+}
+
+// The count of the recent GC pauses in the GC stats.
+const recentGCPausesCount int = 16
+
+// Debug registers the runtime debug endpoints under the given prefix – by
+// default /debug –, each guarded by the given middleware:
+//
+//   - /pprof/ – the profiles of net/http/pprof, eg. /pprof/heap or /pprof/profile,
+//   - /vars – the variables published by expvar,
+//   - /goroutines – the stack traces of all the goroutines,
+//   - /gc – the GC and the memory statistics,
+//   - /buildinfo – the build information of the binary.
+//
+// If the router is configured by WithAdminAddress, then the endpoints are
+// served only by the separate admin listener, where the <nil> middleware
+// allows only the requests from the loopback addresses. Otherwise the
+// middleware is required – it panics with ErrDebugAuthRequired –, since
+// the remote address of the requests coming through a proxy is loopback
+// as well. The LoopbackOnly middleware could be still given explicitly.
+func (r *router) Debug(prefix string, auth Middleware) {
+	if prefix == "" {
+		prefix = defaultDebugPrefix
+	}
+	prefix = strings.TrimSuffix(prefix, slash)
+
+	if auth == nil {
+		if r.adminAddress == 0 {
+			panic(ErrDebugAuthRequired)
+		}
+
+		auth = LoopbackOnly()
+	}
+
+	var (
+		target = r.getAdminRouter()
+
+		register = func(method, url string, handler HandlerFunc) {
+			if route := target.addRoute(method, prefix+url, handler); route != nil {
+				route.RegisterMiddlewares(auth)
+			}
+		}
+	)
+
+	// The relative links of the index only work with the trailing slash.
+	register(http.MethodGet, "/pprof", func(ctx Context) {
+		ctx.Redirect(http.StatusMovedPermanently, prefix+"/pprof/")
+	})
+	register(http.MethodGet, "/pprof/{"+debugProfileParamKey+catchAllSuffix+"}", serveProfile)
+	register(http.MethodGet, "/pprof/cmdline", wrapHandler(http.HandlerFunc(pprof.Cmdline)))
+	register(http.MethodGet, "/pprof/profile", wrapHandler(http.HandlerFunc(pprof.Profile)))
+	register(http.MethodGet, "/pprof/symbol", wrapHandler(http.HandlerFunc(pprof.Symbol)))
+	register(http.MethodPost, "/pprof/symbol", wrapHandler(http.HandlerFunc(pprof.Symbol)))
+	register(http.MethodGet, "/pprof/trace", wrapHandler(http.HandlerFunc(pprof.Trace)))
+
+	register(http.MethodGet, "/vars", wrapHandler(expvar.Handler()))
+	register(http.MethodGet, "/goroutines", serveGoroutines)
+	register(http.MethodGet, "/gc", serveGCStats)
+	register(http.MethodGet, "/buildinfo", serveBuildInfo)
+}
+
+// getAdminRouter returns the router of the admin listener – if there is
+// any –, otherwise the router itself.
+func (r *router) getAdminRouter() *router {
+	if r.adminAddress == 0 {
+		return r
+	}
+
+	if r.adminRouter == nil {
+		r.adminRouter = New(
+			WithServerName(r.serverName+" admin"),
+			WithAddress(r.adminAddress),
+		).(*router)
+
+		// The admin listener logs the same way as the main one.
+		r.adminRouter.logger = r.logger
+		r.adminRouter.logLevel = r.logLevel
+	}
+
+	return r.adminRouter
+}
+
+// serveProfile serves the index of the profiles, or the profile of the given name.
+func serveProfile(ctx Context) {
+	name := ctx.GetParam(debugProfileParamKey)
+	if name == "" {
+		wrapHandler(http.HandlerFunc(pprof.Index))(ctx)
+
+		return
+	}
+
+	wrapHandler(pprof.Handler(name))(ctx)
+}
+
+func serveGCStats(ctx Context) {
+	var (
+		gc  = debug.GCStats{Pause: make([]time.Duration, recentGCPausesCount)}
+		mem runtime.MemStats
+	)
+
+	debug.ReadGCStats(&gc)
+	runtime.ReadMemStats(&mem)
+
+	ctx.SendJson(http.StatusOK, &GCStats{
+		NumGC:        gc.NumGC,
+		LastGC:       gc.LastGC,
+		PauseTotal:   gc.PauseTotal,
+		RecentPauses: gc.Pause,
+		CPUFraction:  mem.GCCPUFraction,
+		HeapAlloc:    mem.HeapAlloc,
+		HeapSys:      mem.HeapSys,
+		HeapObjects:  mem.HeapObjects,
+		NextGC:       mem.NextGC,
+		TotalAlloc:   mem.TotalAlloc,
+		Sys:          mem.Sys,
+		// A negative value only reads the current limit.
+		MemoryLimit:  debug.SetMemoryLimit(-1),
+		NumGoroutine: runtime.NumGoroutine(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+	})
+}
+
+// serveGoroutines serves the stack traces of all the goroutines
+// in the same format as an unrecovered panic.
+func serveGoroutines(ctx Context) {
+	var b bytes.Buffer
+	rtpprof.Lookup("goroutine").WriteTo(&b, 2)
+
+	ctx.GetResponseHeaders().Set(contentTypeHeaderKey, "text/plain; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	ctx.Copy(&b)
+}
+
+func serveBuildInfo(ctx Context) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		ctx.StatusText(http.StatusNotFound)
+
+		return
+	}
+
+	ctx.GetResponseHeaders().Set(contentTypeHeaderKey, "text/plain; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	ctx.Copy(strings.NewReader(info.String()))
+}
+
+// LoopbackOnly creates and returns a middleware, which allows only the requests
+// from the loopback addresses – based on the remote address of the connection,
+// so it must not be used behind a proxy running on the same host. The others
+// get 404, so the existence of the endpoints is not revealed.
+func LoopbackOnly() Middleware {
+	return NewMiddleware(loopbackOnly)
+}
+
+func loopbackOnly(ctx Context) {
+	r := ctx.GetRequest()
+	if r == nil {
+		ctx.StatusText(http.StatusInternalServerError)
+
+		return
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		ctx.Status(http.StatusNotFound)
+
+		return
+	}
+
+	ctx.Next()
+}
+
+// wrapHandler adapts the given http.Handler to a HandlerFunc. The response
+// is streamed right after the status code is written by the handler.
+func wrapHandler(h http.Handler) HandlerFunc {
+	return func(ctx Context) {
+		r := ctx.GetRequest()
+		if r == nil {
+			ctx.StatusText(http.StatusInternalServerError)
+
+			return
+		}
+
+		c, ok := ctx.(*context)
+		if !ok {
+			h.ServeHTTP(ctx.GetResponseWriter(), r)
+
+			return
+		}
+
+		h.ServeHTTP(&contentWriter{rw: c.writer, isStreaming: true}, r)
+	}
+}
@@ -0,0 +1,219 @@
+package gorouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	type testCase struct {
+		name  string
+		url   string
+		token string
+
+		expectedStatusCode int
+		expectedBody       string
+	}
+
+	tt := []testCase{
+		{
+			name:               "serves the index of the profiles",
+			url:                "/admin/pprof/",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "goroutine",
+		},
+		{
+			name:               "redirects to the index of the profiles",
+			url:                "/admin/pprof",
+			token:              "secret",
+			expectedStatusCode: http.StatusMovedPermanently,
+		},
+		{
+			name:               "serves the named profile",
+			url:                "/admin/pprof/heap?debug=1",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "heap profile",
+		},
+		{
+			name:               "serves the command line",
+			url:                "/admin/pprof/cmdline",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "serves the expvar variables",
+			url:                "/admin/vars",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"memstats"`,
+		},
+		{
+			name:               "serves the goroutine dump",
+			url:                "/admin/goroutines",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "goroutine ",
+		},
+		{
+			name:               "serves the gc stats",
+			url:                "/admin/gc",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       `"numGoroutine"`,
+		},
+		{
+			name:               "serves the build info",
+			url:                "/admin/buildinfo",
+			token:              "secret",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "go\t",
+		},
+		{
+			name:               "the middleware guards the endpoints",
+			url:                "/admin/gc",
+			token:              "wrong",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New()
+
+			r.Debug("/admin/", NewMiddleware(func(ctx Context) {
+				if ctx.GetRequestHeader("X-Debug-Token") != "secret" {
+					ctx.Status(http.StatusUnauthorized)
+
+					return
+				}
+				ctx.Next()
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			req.Header.Set("X-Debug-Token", tc.token)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if !strings.Contains(rec.Body.String(), tc.expectedBody) {
+				t.Errorf("expected body to contain: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestDebugLoopbackOnly(t *testing.T) {
+	type testCase struct {
+		name       string
+		remoteAddr string
+
+		expectedStatusCode int
+	}
+
+	tt := []testCase{
+		{
+			name:               "allows the ipv4 loopback address",
+			remoteAddr:         "127.0.0.1:1234",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "allows the ipv6 loopback address",
+			remoteAddr:         "[::1]:1234",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "hides the endpoints from the remote address",
+			remoteAddr:         "192.0.2.1:1234",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New()
+			r.Debug("", LoopbackOnly())
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/gc", nil)
+			req.RemoteAddr = tc.remoteAddr
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestDebugRequiresAuth(t *testing.T) {
+	type testCase struct {
+		name string
+		opts []routerOptionFunc
+
+		expectedPanic any
+	}
+
+	tt := []testCase{
+		{
+			name:          "panics without middleware on the main listener",
+			expectedPanic: ErrDebugAuthRequired,
+		},
+		{
+			name:          "allows the loopback addresses of the admin listener by default",
+			opts:          []routerOptionFunc{WithAdminAddress(9090)},
+			expectedPanic: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var val any
+
+			func() {
+				defer func() { val = recover() }()
+
+				New(tc.opts...).Debug("", nil)
+			}()
+
+			if val != tc.expectedPanic {
+				t.Errorf("expected panic: %v; got: %v\n", tc.expectedPanic, val)
+			}
+		})
+	}
+}
+
+func TestDebugAdminAddress(t *testing.T) {
+	r := New(WithAdminAddress(9090)).(*router)
+	r.Debug("", nil)
+
+	var serve = func(h http.Handler) int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/gc", nil)
+		req.RemoteAddr = "127.0.0.1:1234"
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	if got := serve(r); got != http.StatusNotFound {
+		t.Errorf("expected status code of the main router: %d; got: %d\n", http.StatusNotFound, got)
+	}
+
+	if r.adminRouter == nil {
+		t.Fatalf("expected admin router; got: <nil>\n")
+	}
+
+	if got := serve(r.adminRouter); got != http.StatusOK {
+		t.Errorf("expected status code of the admin router: %d; got: %d\n", http.StatusOK, got)
+	}
+}
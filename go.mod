@@ -1,3 +1,3 @@
 module github.com/balazskvancz/gorouter
 
-go 1.23
+go 1.24
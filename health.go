@@ -0,0 +1,283 @@
+package gorouter
+
+import (
+	ctxpkg "context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthPath    string = "/healthz"
+	defaultReadinessPath string = "/readyz"
+	defaultLivenessPath  string = "/livez"
+
+	defaultHealthCheckTimeout time.Duration = time.Second
+)
+
+type HealthStatus string
+
+const (
+	HealthStatusOk HealthStatus = "ok"
+	// Some of the non-critical checks are failing.
+	HealthStatusDegraded HealthStatus = "degraded"
+	// Some of the critical checks are failing.
+	HealthStatusFailing HealthStatus = "failing"
+	// The router is shutting down, so it is not ready anymore.
+	HealthStatusShuttingDown HealthStatus = "shutting_down"
+)
+
+// HealthCheckFunc checks a dependency of the service. The context
+// is cancelled once the timeout of the check is exceeded.
+type HealthCheckFunc func(ctx ctxpkg.Context) error
+
+// HealthCheck is a named check of the health endpoints.
+type HealthCheck struct {
+	Name  string
+	Check HealthCheckFunc
+
+	// The maximum duration of the check. By default it is 1 second.
+	Timeout time.Duration
+
+	// Whether the service is not ready, if the check is failing.
+	// The non-critical failing checks only degrade the health.
+	Critical bool
+
+	// Whether the check is part of the liveness as well. Only the checks,
+	// which could be fixed by a restart – eg. a deadlock – should be.
+	Liveness bool
+
+	// If it is set, then the result is cached for the given duration,
+	// so the frequent probes do not overload the dependency.
+	CacheTTL time.Duration
+}
+
+// HealthConfig is the configuration of the health endpoints.
+type HealthConfig struct {
+	// The paths of the endpoints. By default /healthz, /readyz and /livez.
+	HealthPath    string
+	ReadinessPath string
+	LivenessPath  string
+
+	Checks []HealthCheck
+}
+
+// HealthCheckResult is the result of a single check.
+type HealthCheckResult struct {
+	Name     string       `json:"name"`
+	Status   HealthStatus `json:"status"`
+	Critical bool         `json:"critical"`
+	Error    string       `json:"error,omitempty"`
+	Duration string       `json:"duration"`
+}
+
+// HealthReport is the JSON report of the health endpoints.
+type HealthReport struct {
+	Status HealthStatus        `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+type healthCheck struct {
+	HealthCheck
+
+	mu        sync.Mutex
+	result    HealthCheckResult
+	checkedAt time.Time
+}
+
+// Health serves the health (/healthz), the readiness (/readyz) and
+// the liveness (/livez) endpoints of the router.
+type Health struct {
+	router *router
+
+	mu     sync.RWMutex
+	checks []*healthCheck
+}
+
+// Health registers the health, the readiness and the liveness endpoints,
+// then returns the Health, where further checks could be added.
+//
+// The health endpoint reports all the checks, and it responds with 503
+// only if a critical check is failing. The readiness endpoint responds
+// with 503 if a critical check is failing or the router is shutting down,
+// while the liveness endpoint responds with 503 if a liveness check is failing.
+func (r *router) Health(conf HealthConfig) *Health {
+	h := &Health{router: r}
+
+	for _, c := range conf.Checks {
+		h.AddCheck(c)
+	}
+
+	if conf.HealthPath == "" {
+		conf.HealthPath = defaultHealthPath
+	}
+
+	if conf.ReadinessPath == "" {
+		conf.ReadinessPath = defaultReadinessPath
+	}
+
+	if conf.LivenessPath == "" {
+		conf.LivenessPath = defaultLivenessPath
+	}
+
+	r.Get(conf.HealthPath, h.handle(false, false))
+	r.Get(conf.ReadinessPath, h.handle(true, false))
+	r.Get(conf.LivenessPath, h.handle(false, true))
+
+	return h
+}
+
+// AddCheck adds the given check to the endpoints.
+func (h *Health) AddCheck(check HealthCheck) {
+	if check.Check == nil {
+		return
+	}
+
+	if check.Timeout <= 0 {
+		check.Timeout = defaultHealthCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, &healthCheck{HealthCheck: check})
+}
+
+// Report runs the checks, then returns the report of the health endpoint.
+func (h *Health) Report(ctx ctxpkg.Context) HealthReport {
+	return h.report(ctx, false, false)
+}
+
+func (h *Health) handle(isReadiness, isLiveness bool) HandlerFunc {
+	return func(ctx Context) {
+		report := h.report(ctx.GetRequestContext(), isReadiness, isLiveness)
+
+		statusCode := http.StatusOK
+		if report.Status == HealthStatusFailing || report.Status == HealthStatusShuttingDown {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		// The probes must not be served from a cache.
+		ctx.GetResponseHeaders().Set("Cache-Control", "no-store")
+		ctx.SendJson(statusCode, report)
+	}
+}
+
+func (h *Health) report(ctx ctxpkg.Context, isReadiness, isLiveness bool) HealthReport {
+	// Once the shutdown begins, the readiness is failing,
+	// so the load balancers stop sending new requests.
+	if isReadiness && h.router.isShuttingDown.Load() {
+		return HealthReport{Status: HealthStatusShuttingDown, Checks: []HealthCheckResult{}}
+	}
+
+	h.mu.RLock()
+	checks := make([]*healthCheck, 0, len(h.checks))
+	for _, c := range h.checks {
+		if !isLiveness || c.Liveness {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	var (
+		wg sync.WaitGroup
+
+		report = HealthReport{
+			Status: HealthStatusOk,
+			Checks: make([]HealthCheckResult, len(checks)),
+		}
+	)
+
+	for i, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			report.Checks[i] = c.run(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status == HealthStatusOk {
+			continue
+		}
+
+		if res.Critical || isLiveness {
+			report.Status = HealthStatusFailing
+
+			break
+		}
+
+		report.Status = HealthStatusDegraded
+	}
+
+	return report
+}
+
+// run runs the check – unless there is a cached result –
+// and returns the result of it.
+func (hc *healthCheck) run(ctx ctxpkg.Context) HealthCheckResult {
+	if hc.CacheTTL > 0 {
+		hc.mu.Lock()
+		if !hc.checkedAt.IsZero() && time.Since(hc.checkedAt) < hc.CacheTTL {
+			defer hc.mu.Unlock()
+
+			return hc.result
+		}
+		hc.mu.Unlock()
+	}
+
+	// The result must not depend on the cancellation of the request,
+	// since it could be cached and served for the other probes.
+	c, cancel := ctxpkg.WithTimeout(ctxpkg.WithoutCancel(ctx), hc.Timeout)
+	defer cancel()
+
+	var (
+		start = time.Now()
+		done  = make(chan error, 1)
+	)
+
+	go func() {
+		defer func() {
+			if val := recover(); val != nil {
+				done <- fmt.Errorf("panic: %v", val)
+			}
+		}()
+
+		done <- hc.Check(c)
+	}()
+
+	var err error
+
+	// The check could ignore the cancellation of the context.
+	select {
+	case err = <-done:
+	case <-c.Done():
+		err = c.Err()
+	}
+
+	res := HealthCheckResult{
+		Name:     hc.Name,
+		Status:   HealthStatusOk,
+		Critical: hc.Critical,
+		Duration: time.Since(start).String(),
+	}
+
+	if err != nil {
+		res.Status = HealthStatusFailing
+		res.Error = err.Error()
+	}
+
+	if hc.CacheTTL > 0 {
+		hc.mu.Lock()
+		hc.result = res
+		hc.checkedAt = time.Now()
+		hc.mu.Unlock()
+	}
+
+	return res
+}
@@ -0,0 +1,197 @@
+package gorouter
+
+import (
+	ctxpkg "context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	var (
+		okCheck = HealthCheck{
+			Name:     "database",
+			Critical: true,
+			Check:    func(ctxpkg.Context) error { return nil },
+		}
+
+		failingCheck = HealthCheck{
+			Name:  "cache",
+			Check: func(ctxpkg.Context) error { return errors.New("connection refused") },
+		}
+
+		failingCriticalCheck = HealthCheck{
+			Name:     "queue",
+			Critical: true,
+			Check:    func(ctxpkg.Context) error { return errors.New("connection refused") },
+		}
+
+		slowCheck = HealthCheck{
+			Name:     "search",
+			Critical: true,
+			Timeout:  10 * time.Millisecond,
+			Check: func(ctx ctxpkg.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}
+
+		failingLivenessCheck = HealthCheck{
+			Name:     "worker",
+			Liveness: true,
+			Check:    func(ctxpkg.Context) error { return errors.New("deadlock") },
+		}
+	)
+
+	type testCase struct {
+		name           string
+		checks         []HealthCheck
+		isShuttingDown bool
+		url            string
+
+		expectedStatusCode int
+		expectedStatus     HealthStatus
+		expectedChecks     int
+	}
+
+	tt := []testCase{
+		{
+			name:               "healthy without checks",
+			url:                "/healthz",
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     HealthStatusOk,
+			expectedChecks:     0,
+		},
+		{
+			name:               "healthy with passing checks",
+			checks:             []HealthCheck{okCheck},
+			url:                "/healthz",
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     HealthStatusOk,
+			expectedChecks:     1,
+		},
+		{
+			name:               "degraded with failing non-critical check",
+			checks:             []HealthCheck{okCheck, failingCheck},
+			url:                "/healthz",
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     HealthStatusDegraded,
+			expectedChecks:     2,
+		},
+		{
+			name:               "ready with failing non-critical check",
+			checks:             []HealthCheck{okCheck, failingCheck},
+			url:                "/readyz",
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     HealthStatusDegraded,
+			expectedChecks:     2,
+		},
+		{
+			name:               "not ready with failing critical check",
+			checks:             []HealthCheck{failingCheck, failingCriticalCheck},
+			url:                "/readyz",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedStatus:     HealthStatusFailing,
+			expectedChecks:     2,
+		},
+		{
+			name:               "not ready with timed out critical check",
+			checks:             []HealthCheck{slowCheck},
+			url:                "/readyz",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedStatus:     HealthStatusFailing,
+			expectedChecks:     1,
+		},
+		{
+			name:               "not ready while shutting down",
+			checks:             []HealthCheck{okCheck},
+			isShuttingDown:     true,
+			url:                "/readyz",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedStatus:     HealthStatusShuttingDown,
+			expectedChecks:     0,
+		},
+		{
+			name:               "alive while shutting down",
+			checks:             []HealthCheck{okCheck},
+			isShuttingDown:     true,
+			url:                "/livez",
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     HealthStatusOk,
+			expectedChecks:     0,
+		},
+		{
+			name:               "alive runs only the liveness checks",
+			checks:             []HealthCheck{failingCriticalCheck, failingLivenessCheck},
+			url:                "/livez",
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedStatus:     HealthStatusFailing,
+			expectedChecks:     1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New().(*router)
+			r.Health(HealthConfig{Checks: tc.checks})
+			r.isShuttingDown.Store(tc.isShuttingDown)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			var report HealthReport
+			if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+				t.Fatalf("unexpected error: %v\n", err)
+			}
+
+			if report.Status != tc.expectedStatus {
+				t.Errorf("expected status: %s; got: %s\n", tc.expectedStatus, report.Status)
+			}
+
+			if len(report.Checks) != tc.expectedChecks {
+				t.Errorf("expected checks: %d; got: %d\n", tc.expectedChecks, len(report.Checks))
+			}
+		})
+	}
+}
+
+func TestHealthCheckCache(t *testing.T) {
+	var (
+		calls atomic.Int32
+
+		r = New()
+		h = r.Health(HealthConfig{
+			HealthPath: "/health",
+		})
+	)
+
+	h.AddCheck(HealthCheck{
+		Name:     "database",
+		CacheTTL: time.Minute,
+		Check: func(ctxpkg.Context) error {
+			calls.Add(1)
+			return nil
+		},
+	})
+
+	for range 3 {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status code: %d; got: %d\n", http.StatusOK, rec.Code)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected calls: %d; got: %d\n", 1, got)
+	}
+}
@@ -1,86 +1,252 @@
 package gorouter
 
 import (
+	ctxpkg "context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strings"
 )
 
-type (
-	logTypeName  string
-	logTypeValue uint8
-)
+// Logger is the interface of the loggers accepted by WithLogger. The
+// arguments following the message are key-value pairs – the same as
+// the ones of the slog package –, so *slog.Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
 
-const (
-	tInfo    logTypeName = "info"
-	tWarning logTypeName = "warning"
-	tError   logTypeName = "error"
-)
+var _ Logger = (*slog.Logger)(nil)
+
+const defaultLogLevel = slog.LevelInfo
 
+// The log levels of the earlier versions, which are the levels of slog now.
 const (
-	InfoLogLevel logTypeValue = 1 << iota
-	WarningLogLevel
-	ErrorLogLevel
+	// Deprecated: use slog.LevelInfo instead.
+	InfoLogLevel = slog.LevelInfo
+	// Deprecated: use slog.LevelWarn instead.
+	WarningLogLevel = slog.LevelWarn
+	// Deprecated: use slog.LevelError instead.
+	ErrorLogLevel = slog.LevelError
 )
 
-const defaultLogLevel = InfoLogLevel + WarningLogLevel + ErrorLogLevel
+// LegacyLogger is the interface of the loggers of the earlier versions,
+// whose methods accept a format string with its arguments – the same as
+// the ones of the fmt package – instead of key-value pairs.
+//
+// Deprecated: implement the Logger interface instead. The existing
+// implementations could be passed to WithLogger by AdaptLegacyLogger.
+type LegacyLogger interface {
+	Info(format string, v ...any)
+	Error(format string, v ...any)
+	Warning(format string, v ...any)
+}
 
-var logLevelValues = map[logTypeName]logTypeValue{
-	tInfo:    InfoLogLevel,
-	tWarning: WarningLogLevel,
-	tError:   ErrorLogLevel,
+// AdaptLegacyLogger adapts a LegacyLogger to the Logger interface. The
+// key-value pairs are appended to the message, eg. "user loaded id=1",
+// and the debug logs are written as info, since the earlier versions
+// had no debug level.
+//
+// Deprecated: implement the Logger interface instead.
+func AdaptLegacyLogger(l LegacyLogger) Logger {
+	return &legacyLogger{l: l}
 }
 
-type logger struct {
-	logLevel logTypeValue
-	*log.Logger
+type legacyLogger struct {
+	l LegacyLogger
 }
 
-type Logger interface {
-	Info(string, ...any)
-	Error(string, ...any)
-	Warning(string, ...any)
-	// disable(logTypeValue)
+func (ll *legacyLogger) Debug(msg string, args ...any) { ll.l.Info("%s", formatLegacy(msg, args)) }
+func (ll *legacyLogger) Info(msg string, args ...any)  { ll.l.Info("%s", formatLegacy(msg, args)) }
+func (ll *legacyLogger) Warn(msg string, args ...any)  { ll.l.Warning("%s", formatLegacy(msg, args)) }
+func (ll *legacyLogger) Error(msg string, args ...any) { ll.l.Error("%s", formatLegacy(msg, args)) }
+
+// formatLegacy appends the key-value pairs to the message.
+func formatLegacy(msg string, args []any) string {
+	var b strings.Builder
+	b.WriteString(msg)
+
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			fmt.Fprintf(&b, " %v", args[i])
+
+			break
+		}
+
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+
+	return b.String()
 }
 
-const (
-	defaultLogFlag int = log.LstdFlags
-)
+// WithLogger allows to configure the logger of the router – either
+// a *slog.Logger or any other implementation of the Logger interface.
+// The level of the router is applied on top of the level of the logger.
+func WithLogger(l Logger) routerOptionFunc {
+	return func(r *router) {
+		if l != nil {
+			r.customLogger = l
+		}
+	}
+}
+
+// WithLogLevel allows to configure the minimum level of the logs
+// written by the router. It could be changed later by SetLogLevel.
+func WithLogLevel(level slog.Level) routerOptionFunc {
+	return func(r *router) {
+		r.logLevel.Set(level)
+	}
+}
+
+// SetLogLevel changes the minimum level of the logs written by
+// the router. It is safe to call while the router is serving.
+func (r *router) SetLogLevel(level slog.Level) {
+	r.logLevel.Set(level)
+}
+
+// GetLogger returns the logger of the router.
+func (r *router) GetLogger() *slog.Logger {
+	return r.logger
+}
+
+// newLogger creates the logger of the router. By default the logs are
+// written to the standard output in the text format of the slog package.
+func newLogger(serverName string, l Logger, level slog.Leveler) *slog.Logger {
+	var handler slog.Handler
+
+	switch v := l.(type) {
+	case nil:
+		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level}).
+			WithAttrs([]slog.Attr{
+				slog.String("server", serverName),
+				slog.String("version", version),
+			})
+	case *slog.Logger:
+		handler = v.Handler()
+	default:
+		handler = &loggerHandler{logger: v}
+	}
+
+	return slog.New(&levelHandler{Handler: handler, level: level})
+}
 
-var _ Logger = (*logger)(nil)
+// GetLogger returns the logger of the request, which includes the id
+// of the request – if there is any –, the method and the registered
+// url of the route in every record. If the context does not belong to
+// a router, then the default logger of the slog package is used.
+func (ctx *context) GetLogger() *slog.Logger {
+	l := slog.Default()
+	if ctx.router != nil {
+		l = ctx.router.logger
+	}
 
-func newLogger(serverName string) Logger {
-	logPrefix := fmt.Sprintf("[%s %s] ", serverName, version)
+	args := make([]any, 0, 6)
 
-	return &logger{
-		Logger:   log.New(os.Stdout, logPrefix, defaultLogFlag),
-		logLevel: defaultLogLevel,
+	if id := GetRequestID(ctx); id != "" {
+		args = append(args, "request_id", id)
 	}
+
+	args = append(args, "method", ctx.GetRequestMethod(), "route", ctx.GetRegisteredUrl())
+
+	return l.With(args...)
+}
+
+// levelHandler filters the records of the wrapped handler by a level,
+// which could be changed at runtime.
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(c ctxpkg.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(c, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// loggerHandler adapts a Logger to a slog.Handler. The attributes
+// of the groups are flattened, eg. the attribute id of the group
+// user is passed as user.id.
+type loggerHandler struct {
+	logger Logger
+	attrs  []any
+	group  string
 }
 
-func (l *logger) Info(format string, v ...any) {
-	l.write(tInfo, fmt.Sprintf("[INFO] – %s\n", format), v...)
+func (h *loggerHandler) Enabled(ctxpkg.Context, slog.Level) bool {
+	return true
 }
 
-func (l *logger) Error(format string, v ...any) {
-	l.write(tError, fmt.Sprintf("[ERROR] – %s\n", format), v...)
+func (h *loggerHandler) Handle(_ ctxpkg.Context, rec slog.Record) error {
+	args := make([]any, 0, len(h.attrs)+2*rec.NumAttrs())
+	args = append(args, h.attrs...)
+
+	rec.Attrs(func(a slog.Attr) bool {
+		args = appendAttr(args, h.group, a)
+		return true
+	})
+
+	switch {
+	case rec.Level < slog.LevelInfo:
+		h.logger.Debug(rec.Message, args...)
+	case rec.Level < slog.LevelWarn:
+		h.logger.Info(rec.Message, args...)
+	case rec.Level < slog.LevelError:
+		h.logger.Warn(rec.Message, args...)
+	default:
+		h.logger.Error(rec.Message, args...)
+	}
+
+	return nil
 }
 
-func (l *logger) Warning(format string, v ...any) {
-	l.write(tWarning, fmt.Sprintf("[WARNING] – %s\n", format), v...)
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	args := make([]any, 0, len(h.attrs)+2*len(attrs))
+	args = append(args, h.attrs...)
+
+	for _, a := range attrs {
+		args = appendAttr(args, h.group, a)
+	}
+
+	return &loggerHandler{logger: h.logger, attrs: args, group: h.group}
 }
 
-func (l *logger) write(logType logTypeName, format string, v ...any) {
-	if (logLevelValues[logType] & l.logLevel) == 0 {
-		return
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
 	}
-	l.Printf(format, v...)
+
+	return &loggerHandler{logger: h.logger, attrs: h.attrs, group: h.group + name + "."}
 }
 
-// Maybe to it later.
-// func (l *logger) disable(d logTypeValue) {
-// if l.logLevel&d == 0 {
-// return
-// }
-// l.logLevel -= d
-// }
+// appendAttr appends the given attribute as a key-value pair to the args.
+func appendAttr(args []any, group string, a slog.Attr) []any {
+	v := a.Value.Resolve()
+
+	if v.Kind() == slog.KindGroup {
+		prefix := group
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+
+		for _, ga := range v.Group() {
+			args = appendAttr(args, prefix, ga)
+		}
+
+		return args
+	}
+
+	if a.Equal(slog.Attr{}) {
+		return args
+	}
+
+	return append(args, group+a.Key, v.Any())
+}
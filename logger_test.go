@@ -0,0 +1,165 @@
+package gorouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingLogger is an implementation of the Logger interface,
+// which keeps the formatted lines in the memory.
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) write(level string, msg string, args ...any) {
+	l.lines = append(l.lines, strings.TrimSpace(fmt.Sprintln(append([]any{level, msg}, args...)...)))
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.write("DEBUG", msg, args...) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.write("INFO", msg, args...) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.write("WARN", msg, args...) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.write("ERROR", msg, args...) }
+
+func TestContextGetLogger(t *testing.T) {
+	var (
+		b bytes.Buffer
+
+		r = New(WithLogger(slog.New(slog.NewJSONHandler(&b, nil))))
+	)
+
+	r.RegisterMiddlewares(NewMiddleware(func(ctx Context) {
+		ctx.BindValue(RequestIDKey, "req-1")
+		ctx.Next()
+	}))
+
+	r.Get("/users/{id}", func(ctx Context) {
+		ctx.GetLogger().Info("user loaded", "id", ctx.GetParam("id"))
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	var record map[string]any
+	if err := json.Unmarshal(b.Bytes(), &record); err != nil {
+		t.Fatalf("unexpected error: %v; line: %q\n", err, b.String())
+	}
+
+	expected := map[string]any{
+		"msg":        "user loaded",
+		"request_id": "req-1",
+		"method":     http.MethodGet,
+		"route":      "/users/{id}",
+		"id":         "1",
+	}
+
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("expected %s: %v; got: %v\n", k, v, record[k])
+		}
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	type testCase struct {
+		name  string
+		opts  []routerOptionFunc
+		level *slog.Level
+
+		expectedLines []string
+	}
+
+	var debugLevel = slog.LevelDebug
+
+	tt := []testCase{
+		{
+			name:          "skips the debug logs by default",
+			expectedLines: []string{"WARN slow"},
+		},
+		{
+			name:          "writes the debug logs with the option",
+			opts:          []routerOptionFunc{WithLogLevel(slog.LevelDebug)},
+			expectedLines: []string{"DEBUG route registered method GET url /", "DEBUG details method GET route /", "WARN slow"},
+		},
+		{
+			name:          "writes the debug logs after the level is changed",
+			level:         &debugLevel,
+			expectedLines: []string{"DEBUG details method GET route /", "WARN slow"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &recordingLogger{}
+
+			r := New(append(tc.opts, WithLogger(l))...)
+
+			r.Get("/", func(ctx Context) {
+				ctx.GetLogger().Debug("details")
+				r.GetLogger().Warn("slow")
+			})
+
+			if tc.level != nil {
+				r.SetLogLevel(*tc.level)
+			}
+
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := strings.Join(l.lines, "\n"); got != strings.Join(tc.expectedLines, "\n") {
+				t.Errorf("expected lines: %q; got: %q\n", tc.expectedLines, l.lines)
+			}
+		})
+	}
+}
+
+func TestLoggerHandler(t *testing.T) {
+	l := &recordingLogger{}
+
+	slog.New(&loggerHandler{logger: l}).
+		With("server", "api").
+		WithGroup("user").
+		Error("failed", "id", 1, slog.Group("role", "name", "admin"))
+
+	expected := "ERROR failed server api user.id 1 user.role.name admin"
+
+	if len(l.lines) != 1 || l.lines[0] != expected {
+		t.Errorf("expected line: %q; got: %q\n", expected, l.lines)
+	}
+}
+
+// recordingLegacyLogger is an implementation of the LegacyLogger interface.
+type recordingLegacyLogger struct {
+	lines []string
+}
+
+func (l *recordingLegacyLogger) Info(format string, v ...any) {
+	l.lines = append(l.lines, "INFO "+fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLegacyLogger) Error(format string, v ...any) {
+	l.lines = append(l.lines, "ERROR "+fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLegacyLogger) Warning(format string, v ...any) {
+	l.lines = append(l.lines, "WARNING "+fmt.Sprintf(format, v...))
+}
+
+func TestAdaptLegacyLogger(t *testing.T) {
+	l := &recordingLegacyLogger{}
+
+	r := New(WithLogger(AdaptLegacyLogger(l)), WithLogLevel(WarningLogLevel))
+
+	r.GetLogger().Info("skipped")
+	r.GetLogger().Warn("slow 100%", "route", "/users/{id}")
+	r.GetLogger().With("server", "api").Error("failed", "id", 1)
+
+	expected := []string{"WARNING slow 100% route=/users/{id}", "ERROR failed server=api id=1"}
+
+	if got := strings.Join(l.lines, "\n"); got != strings.Join(expected, "\n") {
+		t.Errorf("expected lines: %q; got: %q\n", expected, l.lines)
+	}
+}
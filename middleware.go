@@ -79,10 +79,12 @@ func MiddlewareWithType(mwType MiddlewareType) MiddlewareOptionFunc {
 
 // NewMiddleware creates and returns a new middleware based
 // upon the given MiddlewareFunc and matchers.
+// By default the middleware is a PreRunner.
 func NewMiddleware(handler MiddlewareFunc, opts ...MiddlewareOptionFunc) Middleware {
 	mw := &middleware{
 		handler: handler,
 		matcher: defaultMatcher,
+		mwType:  MiddlewarePreRunner,
 	}
 
 	for _, o := range opts {
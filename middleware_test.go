@@ -1,6 +1,8 @@
 package gorouter
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -87,3 +89,56 @@ func TestDoesMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestNewMiddlewareType(t *testing.T) {
+	type testCase struct {
+		name string
+		opts []MiddlewareOptionFunc
+
+		expectedType MiddlewareType
+	}
+
+	tt := []testCase{
+		{
+			name:         "the middleware is a PreRunner by default",
+			expectedType: MiddlewarePreRunner,
+		},
+		{
+			name:         "the middleware is a PostRunner if it is configured",
+			opts:         []MiddlewareOptionFunc{MiddlewareWithType(MiddlewarePostRunner)},
+			expectedType: MiddlewarePostRunner,
+		},
+		{
+			name:         "the invalid type is ignored",
+			opts:         []MiddlewareOptionFunc{MiddlewareWithType("unknown")},
+			expectedType: MiddlewarePreRunner,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := NewMiddleware(func(_ Context) {}, tc.opts...).Type(); got != tc.expectedType {
+				t.Errorf("expected type: %s; got: %s\n", tc.expectedType, got)
+			}
+		})
+	}
+}
+
+func TestRouteMiddlewareWithoutType(t *testing.T) {
+	var (
+		called bool
+
+		r = New()
+	)
+
+	r.Get("/api/users", func(ctx Context) {}).RegisterMiddlewares(NewMiddleware(func(ctx Context) {
+		called = true
+		ctx.Next()
+	}))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+	if !called {
+		t.Errorf("expected the route middleware to be called\n")
+	}
+}
@@ -0,0 +1,466 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+type AccessLogFormat uint8
+
+const (
+	// One JSON object per line with stable fields, eg. duration_ms.
+	AccessLogJSON AccessLogFormat = iota
+	// The Common Log Format of the Apache HTTP Server.
+	AccessLogCommon
+	// The Combined Log Format, which is the Common extended
+	// by the Referer and the User-Agent headers.
+	AccessLogCombined
+	// Space separated key=value pairs.
+	AccessLogLogfmt
+)
+
+const (
+	clfTimeLayout string = "02/Jan/2006:15:04:05 -0700"
+
+	// The placeholder prefix of the request headers, eg. ${header:X-Tenant}.
+	headerPlaceholderPrefix string = "header:"
+)
+
+var (
+	ErrInvalidAccessLogTemplate = errors.New("the template of the access log is invalid")
+)
+
+// AccessLogConfig is the configuration of the access log middleware.
+type AccessLogConfig struct {
+	// The writer of the log. By default it is the standard output.
+	Output io.Writer
+
+	// The format of the lines. By default it is AccessLogJSON.
+	Format AccessLogFormat
+
+	// If it is set, then the lines are written by the template instead of the
+	// Format, eg. "${remote_ip} ${method} ${route} ${status} ${latency}".
+	// The available placeholders are: time, method, path, uri, route, proto,
+	// host, status, bytes, latency, latency_ms, remote_ip, user_agent, referer,
+	// request_id, user and header:<name>. It panics, if the template is invalid.
+	Template string
+
+	// The headers of the client address, eg. X-Forwarded-For,
+	// which must be set only behind a trusted proxy.
+	TrustedHeaders []string
+
+	// The registered urls of the routes – or the paths –, which are not logged.
+	ExcludePaths []string
+
+	// If it is set, then only the responses with
+	// at least this status code are logged, eg. 400.
+	MinStatusCode int
+
+	// If it returns true, then the request is not logged.
+	Skip func(ctx gorouter.Context) bool
+
+	// The rates of the logged requests of the routes between 0 and 1 by their
+	// registered urls, eg. 0.01 logs every hundredth request of a high volume
+	// route. The responses with status code 400 or above are always logged.
+	SampleRates map[string]float64
+}
+
+// accessLogEntry holds the fields of a single line.
+type accessLogEntry struct {
+	ctx gorouter.Context
+
+	time      time.Time
+	method    string
+	path      string
+	uri       string
+	route     string
+	proto     string
+	host      string
+	status    int
+	bytes     int64
+	latency   time.Duration
+	remoteIP  string
+	userAgent string
+	referer   string
+	requestID string
+	user      string
+}
+
+// The JSON representation of the entry – the order of the fields is stable.
+type accessLogRecord struct {
+	Time       string  `json:"time"`
+	Method     string  `json:"method"`
+	Path       string  `json:"path"`
+	Route      string  `json:"route,omitempty"`
+	Proto      string  `json:"proto"`
+	Host       string  `json:"host"`
+	Status     int     `json:"status"`
+	Bytes      int64   `json:"bytes"`
+	DurationMs float64 `json:"duration_ms"`
+	RemoteIP   string  `json:"remote_ip"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	Referer    string  `json:"referer,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
+	User       string  `json:"user,omitempty"`
+}
+
+type accessLogField func(e *accessLogEntry) string
+
+var accessLogFields = map[string]accessLogField{
+	"time":       func(e *accessLogEntry) string { return e.time.Format(time.RFC3339) },
+	"method":     func(e *accessLogEntry) string { return e.method },
+	"path":       func(e *accessLogEntry) string { return e.path },
+	"uri":        func(e *accessLogEntry) string { return e.uri },
+	"route":      func(e *accessLogEntry) string { return e.route },
+	"proto":      func(e *accessLogEntry) string { return e.proto },
+	"host":       func(e *accessLogEntry) string { return e.host },
+	"status":     func(e *accessLogEntry) string { return strconv.Itoa(e.status) },
+	"bytes":      func(e *accessLogEntry) string { return strconv.FormatInt(e.bytes, 10) },
+	"latency":    func(e *accessLogEntry) string { return e.latency.String() },
+	"latency_ms": func(e *accessLogEntry) string { return formatMilliseconds(e.latency) },
+	"remote_ip":  func(e *accessLogEntry) string { return e.remoteIP },
+	"user_agent": func(e *accessLogEntry) string { return e.userAgent },
+	"referer":    func(e *accessLogEntry) string { return e.referer },
+	"request_id": func(e *accessLogEntry) string { return e.requestID },
+	"user":       func(e *accessLogEntry) string { return e.user },
+}
+
+// accessLogPart is either a literal or a placeholder of a template.
+type accessLogPart struct {
+	literal string
+	field   accessLogField
+}
+
+type accessLog struct {
+	conf     AccessLogConfig
+	template []accessLogPart
+	remoteIP RateLimitKeyFunc
+
+	mu sync.Mutex
+}
+
+// AccessLog creates and returns a middleware, which writes a line about
+// every request after its response is written. It should be registered
+// as the first global middleware, so the latency covers the whole chain.
+func AccessLog(conf ...AccessLogConfig) gorouter.Middleware {
+	var c AccessLogConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Output == nil {
+		c.Output = os.Stdout
+	}
+
+	al := &accessLog{
+		conf:     c,
+		remoteIP: KeyByIP(c.TrustedHeaders...),
+	}
+
+	if c.Template != "" {
+		template, err := parseAccessLogTemplate(c.Template)
+		if err != nil {
+			panic(err)
+		}
+		al.template = template
+	}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		if c.Skip != nil && c.Skip(ctx) {
+			ctx.Next()
+
+			return
+		}
+
+		ctx.Defer(func() {
+			al.log(ctx)
+		})
+
+		ctx.Next()
+	})
+}
+
+func (al *accessLog) log(ctx gorouter.Context) {
+	info := ctx.GetInfo()
+
+	if info.StatusCode < al.conf.MinStatusCode || al.isExcluded(ctx) || !al.isSampled(ctx, info.StatusCode) {
+		return
+	}
+
+	e := &accessLogEntry{
+		ctx:       ctx,
+		time:      info.StartTime,
+		method:    info.Method,
+		path:      ctx.GetCleanedUrl(),
+		uri:       info.Url,
+		route:     ctx.GetRegisteredUrl(),
+		status:    info.StatusCode,
+		bytes:     info.WrittenBytes,
+		latency:   time.Since(info.StartTime),
+		remoteIP:  al.remoteIP(ctx),
+		userAgent: ctx.GetRequestHeader("User-Agent"),
+		referer:   ctx.GetRequestHeader("Referer"),
+		requestID: gorouter.GetRequestID(ctx),
+	}
+
+	if r := ctx.GetRequest(); r != nil {
+		e.proto = r.Proto
+		e.host = r.Host
+		e.uri = r.RequestURI
+	}
+
+	if p, ok := gorouter.GetPrincipal(ctx); ok {
+		e.user = p.Subject
+	}
+
+	var b bytes.Buffer
+
+	switch {
+	case al.template != nil:
+		writeAccessLogTemplate(&b, al.template, e)
+	case al.conf.Format == AccessLogCommon:
+		writeCommonLog(&b, e)
+	case al.conf.Format == AccessLogCombined:
+		writeCommonLog(&b, e)
+		b.WriteString(" " + quoteOrDash(e.referer) + " " + quoteOrDash(e.userAgent))
+	case al.conf.Format == AccessLogLogfmt:
+		writeLogfmt(&b, e)
+	default:
+		writeJSONLog(&b, e)
+	}
+
+	b.WriteByte('\n')
+
+	// The lines of the concurrent requests must not interleave.
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	al.conf.Output.Write(b.Bytes())
+}
+
+func (al *accessLog) isExcluded(ctx gorouter.Context) bool {
+	if len(al.conf.ExcludePaths) == 0 {
+		return false
+	}
+
+	return slices.Contains(al.conf.ExcludePaths, ctx.GetRegisteredUrl()) ||
+		slices.Contains(al.conf.ExcludePaths, ctx.GetCleanedUrl())
+}
+
+func (al *accessLog) isSampled(ctx gorouter.Context, statusCode int) bool {
+	if statusCode >= http.StatusBadRequest {
+		return true
+	}
+
+	rate, exists := al.conf.SampleRates[ctx.GetRegisteredUrl()]
+	if !exists || rate >= 1 {
+		return true
+	}
+
+	return rand.Float64() < rate
+}
+
+// parseAccessLogTemplate splits the template into
+// literals and placeholders, eg. ${method}.
+func parseAccessLogTemplate(template string) ([]accessLogPart, error) {
+	parts := make([]accessLogPart, 0)
+
+	for template != "" {
+		start := strings.Index(template, "${")
+		if start == -1 {
+			parts = append(parts, accessLogPart{literal: template})
+
+			break
+		}
+
+		if start > 0 {
+			parts = append(parts, accessLogPart{literal: template[:start]})
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end == -1 {
+			return nil, ErrInvalidAccessLogTemplate
+		}
+
+		name := template[start+2 : start+end]
+
+		field, err := getAccessLogField(name)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, accessLogPart{field: field})
+		template = template[start+end+1:]
+	}
+
+	return parts, nil
+}
+
+func getAccessLogField(name string) (accessLogField, error) {
+	if header, ok := strings.CutPrefix(name, headerPlaceholderPrefix); ok {
+		if header == "" {
+			return nil, ErrInvalidAccessLogTemplate
+		}
+
+		return func(e *accessLogEntry) string {
+			return e.ctx.GetRequestHeader(header)
+		}, nil
+	}
+
+	field, exists := accessLogFields[name]
+	if !exists {
+		return nil, ErrInvalidAccessLogTemplate
+	}
+
+	return field, nil
+}
+
+func writeAccessLogTemplate(b *bytes.Buffer, template []accessLogPart, e *accessLogEntry) {
+	for _, p := range template {
+		if p.field == nil {
+			b.WriteString(p.literal)
+
+			continue
+		}
+
+		// The client controlled values must not break the line.
+		b.WriteString(lineBreakReplacer.Replace(p.field(e)))
+	}
+}
+
+var lineBreakReplacer = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+
+// writeCommonLog writes the entry in the Common Log Format, eg.:
+// 127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326.
+func writeCommonLog(b *bytes.Buffer, e *accessLogEntry) {
+	size := "-"
+	if e.bytes > 0 {
+		size = strconv.FormatInt(e.bytes, 10)
+	}
+
+	user := "-"
+	if e.user != "" {
+		user = escapeCommonLog(e.user)
+	}
+
+	b.WriteString(escapeCommonLog(orDash(e.remoteIP)))
+	b.WriteString(" - ")
+	b.WriteString(user)
+	b.WriteString(" [")
+	b.WriteString(e.time.Format(clfTimeLayout))
+	b.WriteString("] ")
+	b.WriteString(strconv.Quote(e.method + " " + e.uri + " " + e.proto))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(e.status))
+	b.WriteByte(' ')
+	b.WriteString(size)
+}
+
+func writeJSONLog(b *bytes.Buffer, e *accessLogEntry) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+
+	enc.Encode(&accessLogRecord{
+		Time:       e.time.Format(time.RFC3339Nano),
+		Method:     e.method,
+		Path:       e.path,
+		Route:      e.route,
+		Proto:      e.proto,
+		Host:       e.host,
+		Status:     e.status,
+		Bytes:      e.bytes,
+		DurationMs: e.latency.Seconds() * 1000,
+		RemoteIP:   e.remoteIP,
+		UserAgent:  e.userAgent,
+		Referer:    e.referer,
+		RequestID:  e.requestID,
+		User:       e.user,
+	})
+
+	// The line break is written by the caller.
+	b.Truncate(b.Len() - 1)
+}
+
+func writeLogfmt(b *bytes.Buffer, e *accessLogEntry) {
+	pairs := [][2]string{
+		{"time", e.time.Format(time.RFC3339Nano)},
+		{"method", e.method},
+		{"path", e.path},
+		{"route", e.route},
+		{"proto", e.proto},
+		{"host", e.host},
+		{"status", strconv.Itoa(e.status)},
+		{"bytes", strconv.FormatInt(e.bytes, 10)},
+		{"duration_ms", formatMilliseconds(e.latency)},
+		{"remote_ip", e.remoteIP},
+		{"user_agent", e.userAgent},
+		{"referer", e.referer},
+		{"request_id", e.requestID},
+		{"user", e.user},
+	}
+
+	for i, p := range pairs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+
+		b.WriteString(p[0])
+		b.WriteByte('=')
+		b.WriteString(quoteLogfmt(p[1]))
+	}
+}
+
+// quoteLogfmt quotes the value, if it is empty or it contains
+// any character, which would break the key=value pairs.
+func quoteLogfmt(v string) string {
+	if v == "" {
+		return `""`
+	}
+
+	if strings.ContainsFunc(v, func(r rune) bool {
+		return r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f
+	}) {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
+
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds()*1000, 'f', 3, 64)
+}
+
+func quoteOrDash(v string) string {
+	if v == "" {
+		return `"-"`
+	}
+	return strconv.Quote(v)
+}
+
+func orDash(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+// escapeCommonLog escapes the unquoted fields of the Common Log Format,
+// so a client controlled value could not forge the fields of the line.
+func escapeCommonLog(v string) string {
+	q := strconv.Quote(v)
+	q = q[1 : len(q)-1]
+
+	return strings.ReplaceAll(q, " ", `\x20`)
+}
@@ -0,0 +1,212 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func newAccessLogRouter(conf AccessLogConfig) gorouter.Router {
+	r := gorouter.New()
+
+	r.RegisterMiddlewares(AccessLog(conf))
+
+	r.Get("/users/{id}", func(ctx gorouter.Context) {
+		ctx.Render(http.StatusOK, &gorouter.DefaultResponse{Data: []byte("hello")})
+	})
+
+	r.Get("/healthz", func(ctx gorouter.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	r.Get("/fail", func(ctx gorouter.Context) {
+		ctx.Status(http.StatusInternalServerError)
+	})
+
+	return r
+}
+
+func newAccessLogRequest(url string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("X-Tenant", "acme")
+
+	return req
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	type testCase struct {
+		name string
+		conf AccessLogConfig
+
+		expectedLine *regexp.Regexp
+	}
+
+	tt := []testCase{
+		{
+			name:         "common log format",
+			conf:         AccessLogConfig{Format: AccessLogCommon},
+			expectedLine: regexp.MustCompile(`^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /users/1\?q=1 HTTP/1\.1" 200 5\n$`),
+		},
+		{
+			name:         "combined log format",
+			conf:         AccessLogConfig{Format: AccessLogCombined},
+			expectedLine: regexp.MustCompile(`^10\.0\.0\.1 - - \[[^\]]+\] "GET /users/1\?q=1 HTTP/1\.1" 200 5 "https://example\.com/" "curl/8\.0"\n$`),
+		},
+		{
+			name:         "logfmt",
+			conf:         AccessLogConfig{Format: AccessLogLogfmt},
+			expectedLine: regexp.MustCompile(`^time=\S+ method=GET path=/users/1 route=/users/\{id\} proto=HTTP/1\.1 host=example\.com status=200 bytes=5 duration_ms=\d+\.\d{3} remote_ip=10\.0\.0\.1 user_agent=curl/8\.0 referer=https://example\.com/ request_id="" user=""\n$`),
+		},
+		{
+			name:         "template",
+			conf:         AccessLogConfig{Template: "${remote_ip} ${method} ${route} ${status} ${bytes} tenant=${header:X-Tenant} ${latency}"},
+			expectedLine: regexp.MustCompile(`^10\.0\.0\.1 GET /users/\{id\} 200 5 tenant=acme \S+s\n$`),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			tc.conf.Output = &b
+			r := newAccessLogRouter(tc.conf)
+
+			r.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/users/1?q=1"))
+
+			if !tc.expectedLine.Match(b.Bytes()) {
+				t.Errorf("expected line to match: %s; got: %q\n", tc.expectedLine, b.String())
+			}
+		})
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	var b bytes.Buffer
+
+	r := newAccessLogRouter(AccessLogConfig{Output: &b})
+	r.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest("/users/1"))
+
+	var record map[string]any
+	if err := json.Unmarshal(b.Bytes(), &record); err != nil {
+		t.Fatalf("unexpected error: %v; line: %q\n", err, b.String())
+	}
+
+	expected := map[string]any{
+		"method":     "GET",
+		"path":       "/users/1",
+		"route":      "/users/{id}",
+		"status":     float64(200),
+		"bytes":      float64(5),
+		"remote_ip":  "10.0.0.1",
+		"user_agent": "curl/8.0",
+	}
+
+	for k, v := range expected {
+		if record[k] != v {
+			t.Errorf("expected %s: %v; got: %v\n", k, v, record[k])
+		}
+	}
+
+	if _, ok := record["duration_ms"].(float64); !ok {
+		t.Errorf("expected numeric duration_ms; got: %v\n", record["duration_ms"])
+	}
+}
+
+func TestAccessLogFilters(t *testing.T) {
+	type testCase struct {
+		name string
+		conf AccessLogConfig
+		urls []string
+
+		expectedLines int
+	}
+
+	tt := []testCase{
+		{
+			name:          "logs every request by default",
+			urls:          []string{"/users/1", "/healthz", "/fail"},
+			expectedLines: 3,
+		},
+		{
+			name:          "excludes the given paths",
+			conf:          AccessLogConfig{ExcludePaths: []string{"/healthz"}},
+			urls:          []string{"/users/1", "/healthz", "/fail"},
+			expectedLines: 2,
+		},
+		{
+			name:          "excludes the given routes",
+			conf:          AccessLogConfig{ExcludePaths: []string{"/users/{id}"}},
+			urls:          []string{"/users/1", "/users/2", "/fail"},
+			expectedLines: 1,
+		},
+		{
+			name:          "logs only the responses with at least the minimum status code",
+			conf:          AccessLogConfig{MinStatusCode: http.StatusBadRequest},
+			urls:          []string{"/users/1", "/healthz", "/fail", "/not-found"},
+			expectedLines: 2,
+		},
+		{
+			name:          "skips the requests",
+			conf:          AccessLogConfig{Skip: func(ctx gorouter.Context) bool { return ctx.GetRegisteredUrl() == "/fail" }},
+			urls:          []string{"/users/1", "/fail"},
+			expectedLines: 1,
+		},
+		{
+			name:          "samples the requests of the routes",
+			conf:          AccessLogConfig{SampleRates: map[string]float64{"/users/{id}": 0, "/fail": 0}},
+			urls:          []string{"/users/1", "/users/2", "/healthz", "/fail"},
+			expectedLines: 2,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			tc.conf.Output = &b
+			r := newAccessLogRouter(tc.conf)
+
+			for _, url := range tc.urls {
+				r.ServeHTTP(httptest.NewRecorder(), newAccessLogRequest(url))
+			}
+
+			if got := bytes.Count(b.Bytes(), []byte("\n")); got != tc.expectedLines {
+				t.Errorf("expected lines: %d; got: %d\n", tc.expectedLines, got)
+			}
+		})
+	}
+}
+
+func TestParseAccessLogTemplate(t *testing.T) {
+	type testCase struct {
+		name     string
+		template string
+
+		expectedError error
+	}
+
+	tt := []testCase{
+		{name: "accepts the literal template", template: "request"},
+		{name: "accepts the placeholders", template: "${method} ${header:X-Tenant} done"},
+		{name: "rejects the unknown placeholder", template: "${unknown}", expectedError: ErrInvalidAccessLogTemplate},
+		{name: "rejects the unclosed placeholder", template: "${method", expectedError: ErrInvalidAccessLogTemplate},
+		{name: "rejects the empty header placeholder", template: "${header:}", expectedError: ErrInvalidAccessLogTemplate},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := parseAccessLogTemplate(tc.template); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got: %v\n", tc.expectedError, err)
+			}
+		})
+	}
+}
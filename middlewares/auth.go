@@ -0,0 +1,245 @@
+package middlewares
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	authorizationHeaderKey   string = "Authorization"
+	wwwAuthenticateHeaderKey string = "WWW-Authenticate"
+
+	schemeBasic  string = "Basic"
+	schemeBearer string = "Bearer"
+
+	defaultRealm string = "Restricted"
+)
+
+// BasicAuthValidator validates the given credentials, and returns the
+// authenticated principal. It returns false for invalid credentials.
+type BasicAuthValidator func(ctx gorouter.Context, username string, password string) (*gorouter.Principal, bool)
+
+// BasicAuthConfig is the configuration of the Basic authentication middleware.
+type BasicAuthConfig struct {
+	// The realm of the challenge. By default it is Restricted.
+	Realm string
+}
+
+// BasicAuth creates and returns a middleware, which authenticates the requests
+// by the HTTP Basic scheme. The authenticated principal is bound to the Context,
+// otherwise 401 is responded with the challenge.
+func BasicAuth(validator BasicAuthValidator, conf ...BasicAuthConfig) gorouter.Middleware {
+	var c BasicAuthConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Realm == "" {
+		c.Realm = defaultRealm
+	}
+
+	challenge := fmt.Sprintf(`%s realm=%s, charset="UTF-8"`, schemeBasic, quote(c.Realm))
+
+	return gorouter.NewMiddleware(
+		func(ctx gorouter.Context) {
+			r := ctx.GetRequest()
+			if r == nil {
+				unauthorized(ctx, challenge)
+
+				return
+			}
+
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				unauthorized(ctx, challenge)
+
+				return
+			}
+
+			principal, ok := validator(ctx, username, password)
+			if !ok || principal == nil {
+				unauthorized(ctx, challenge)
+
+				return
+			}
+
+			if principal.Scheme == "" {
+				principal.Scheme = schemeBasic
+			}
+
+			ctx.BindValue(gorouter.PrincipalKey, principal)
+
+			ctx.Next()
+		},
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+// BasicAuthUsers returns a validator, which accepts the given username-password
+// pairs. The credentials are compared in constant time, so neither the existence
+// of a user, nor the length of the password is leaked by the response time.
+func BasicAuthUsers(users map[string]string) BasicAuthValidator {
+	type hashedCredentials struct {
+		username [sha256.Size]byte
+		password [sha256.Size]byte
+	}
+
+	hashed := make([]hashedCredentials, 0, len(users))
+	for u, p := range users {
+		hashed = append(hashed, hashedCredentials{
+			username: sha256.Sum256([]byte(u)),
+			password: sha256.Sum256([]byte(p)),
+		})
+	}
+
+	return func(_ gorouter.Context, username string, password string) (*gorouter.Principal, bool) {
+		var (
+			u = sha256.Sum256([]byte(username))
+			p = sha256.Sum256([]byte(password))
+
+			match int
+		)
+
+		// Every entry is compared, so the time does not depend on the position.
+		for _, h := range hashed {
+			match |= subtle.ConstantTimeCompare(u[:], h.username[:]) & subtle.ConstantTimeCompare(p[:], h.password[:])
+		}
+
+		if match != 1 {
+			return nil, false
+		}
+
+		return &gorouter.Principal{Subject: username}, true
+	}
+}
+
+// BearerTokenValidator validates the given token, and returns the authenticated
+// principal. The returned error is only logged – the error_description of the
+// challenge is a fixed text based on its class, eg. ErrTokenExpired.
+type BearerTokenValidator func(ctx gorouter.Context, token string) (*gorouter.Principal, error)
+
+// BearerAuthConfig is the configuration of the Bearer authentication middleware.
+type BearerAuthConfig struct {
+	// The realm of the challenge. By default it is Restricted.
+	Realm string
+
+	// The scope sent in the challenge – if it is set.
+	Scope string
+
+	// If it is set, then the token is looked for in this query param as well.
+	QueryParam string
+
+	// If it is set, then the token is looked for in this cookie as well.
+	Cookie string
+}
+
+// BearerAuth creates and returns a middleware, which authenticates the requests
+// by a Bearer token sent in the Authorization header – or in the configured query
+// param or cookie. The authenticated principal is bound to the Context, otherwise
+// 401 is responded with the challenge according to RFC 6750.
+func BearerAuth(validator BearerTokenValidator, conf ...BearerAuthConfig) gorouter.Middleware {
+	var c BearerAuthConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Realm == "" {
+		c.Realm = defaultRealm
+	}
+
+	challenge := fmt.Sprintf("%s realm=%s", schemeBearer, quote(c.Realm))
+	if c.Scope != "" {
+		challenge += ", scope=" + quote(c.Scope)
+	}
+
+	return gorouter.NewMiddleware(
+		func(ctx gorouter.Context) {
+			token := getBearerToken(ctx, c)
+			if token == "" {
+				// There is no error code, if the request lacks any authentication.
+				unauthorized(ctx, challenge)
+
+				return
+			}
+
+			principal, err := validator(ctx, token)
+			if err != nil || principal == nil {
+				// The details of the error are not revealed to the client.
+				if err != nil {
+					ctx.GetLogger().Info("invalid bearer token", "error", err)
+				}
+
+				unauthorized(ctx, fmt.Sprintf(`%s, error="invalid_token", error_description=%s`, challenge, quote(getTokenErrorDescription(err))))
+
+				return
+			}
+
+			if principal.Scheme == "" {
+				principal.Scheme = schemeBearer
+			}
+
+			ctx.BindValue(gorouter.PrincipalKey, principal)
+
+			ctx.Next()
+		},
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+// getTokenErrorDescription returns the fixed description
+// of the class of the given error of the validation.
+func getTokenErrorDescription(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "the access token expired"
+	case errors.Is(err, ErrTokenNotYetValid):
+		return "the access token is not valid yet"
+	default:
+		return "the access token is invalid"
+	}
+}
+
+func getBearerToken(ctx gorouter.Context, conf BearerAuthConfig) string {
+	if token := parseBearerToken(ctx.GetRequestHeader(authorizationHeaderKey)); token != "" {
+		return token
+	}
+
+	if conf.QueryParam != "" {
+		if token := ctx.GetQueryParam(conf.QueryParam); token != "" {
+			return token
+		}
+	}
+
+	if conf.Cookie != "" {
+		if cookie, err := ctx.GetCookie(conf.Cookie); err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+// parseBearerToken returns the token of the given Authorization header.
+func parseBearerToken(header string) string {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, schemeBearer) {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func unauthorized(ctx gorouter.Context, challenge string) {
+	ctx.GetResponseHeaders().Set(wwwAuthenticateHeaderKey, challenge)
+	ctx.StatusText(http.StatusUnauthorized)
+}
+
+// quote returns the given value as a quoted-string.
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+}
@@ -0,0 +1,198 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestBasicAuth(t *testing.T) {
+	type testCase struct {
+		name     string
+		username string
+		password string
+		noAuth   bool
+
+		expectedStatusCode int
+		expectedSubject    string
+		expectedChallenge  string
+	}
+
+	var validator = BasicAuthUsers(map[string]string{
+		"admin": "s3cret",
+		"guest": "guest",
+	})
+
+	tt := []testCase{
+		{
+			name:               "valid credentials",
+			username:           "admin",
+			password:           "s3cret",
+			expectedStatusCode: http.StatusOK,
+			expectedSubject:    "admin",
+		},
+		{
+			name:               "invalid password",
+			username:           "admin",
+			password:           "guest",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Basic realm="Admin \"area\"", charset="UTF-8"`,
+		},
+		{
+			name:               "unknown user",
+			username:           "root",
+			password:           "s3cret",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Basic realm="Admin \"area\"", charset="UTF-8"`,
+		},
+		{
+			name:               "missing credentials",
+			noAuth:             true,
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Basic realm="Admin \"area\"", charset="UTF-8"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				subject string
+
+				r = gorouter.New()
+			)
+
+			r.RegisterMiddlewares(BasicAuth(validator, BasicAuthConfig{Realm: `Admin "area"`}))
+			r.Get("/admin", func(ctx gorouter.Context) {
+				if p, ok := gorouter.GetPrincipal(ctx); ok {
+					subject = p.Subject
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if !tc.noAuth {
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if subject != tc.expectedSubject {
+				t.Errorf("expected subject: %q; got: %q\n", tc.expectedSubject, subject)
+			}
+
+			if got := rec.Header().Get("WWW-Authenticate"); got != tc.expectedChallenge {
+				t.Errorf("expected challenge: %s; got: %s\n", tc.expectedChallenge, got)
+			}
+		})
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	type testCase struct {
+		name    string
+		prepare func(*http.Request)
+
+		expectedStatusCode int
+		expectedSubject    string
+		expectedChallenge  string
+	}
+
+	var (
+		conf = BearerAuthConfig{Realm: "api", Scope: "read", QueryParam: "access_token", Cookie: "token"}
+
+		validator = func(_ gorouter.Context, token string) (*gorouter.Principal, error) {
+			switch token {
+			case "expired":
+				return nil, fmt.Errorf("token of user-1: %w", ErrTokenExpired)
+			case "invalid":
+				return nil, errors.New("lookup failed: dial tcp 10.0.0.5:5432: connection refused")
+			}
+			return &gorouter.Principal{Subject: "user-1", Permissions: []string{"read"}}, nil
+		}
+	)
+
+	tt := []testCase{
+		{
+			name:               "token from header",
+			prepare:            func(r *http.Request) { r.Header.Set("Authorization", "bearer valid") },
+			expectedStatusCode: http.StatusOK,
+			expectedSubject:    "user-1",
+		},
+		{
+			name:               "token from query",
+			prepare:            func(r *http.Request) { r.URL.RawQuery = "access_token=valid" },
+			expectedStatusCode: http.StatusOK,
+			expectedSubject:    "user-1",
+		},
+		{
+			name:               "token from cookie",
+			prepare:            func(r *http.Request) { r.AddCookie(&http.Cookie{Name: "token", Value: "valid"}) },
+			expectedStatusCode: http.StatusOK,
+			expectedSubject:    "user-1",
+		},
+		{
+			name:               "missing token",
+			prepare:            func(r *http.Request) {},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Bearer realm="api", scope="read"`,
+		},
+		{
+			name:               "other scheme",
+			prepare:            func(r *http.Request) { r.SetBasicAuth("user", "valid") },
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Bearer realm="api", scope="read"`,
+		},
+		{
+			name:               "invalid token",
+			prepare:            func(r *http.Request) { r.Header.Set("Authorization", "Bearer invalid") },
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Bearer realm="api", scope="read", error="invalid_token", error_description="the access token is invalid"`,
+		},
+		{
+			name:               "expired token",
+			prepare:            func(r *http.Request) { r.Header.Set("Authorization", "Bearer expired") },
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedChallenge:  `Bearer realm="api", scope="read", error="invalid_token", error_description="the access token expired"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				principal *gorouter.Principal
+
+				r = gorouter.New()
+			)
+
+			r.Get("/api/me", func(ctx gorouter.Context) {
+				principal, _ = gorouter.GetPrincipal(ctx)
+			}).RegisterMiddlewares(BearerAuth(validator, conf))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+			tc.prepare(req)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if tc.expectedSubject != "" && (principal == nil || principal.Subject != tc.expectedSubject || principal.Scheme != "Bearer") {
+				t.Errorf("expected principal: %q; got: %+v\n", tc.expectedSubject, principal)
+			}
+
+			if got := rec.Header().Get("WWW-Authenticate"); got != tc.expectedChallenge {
+				t.Errorf("expected challenge: %s; got: %s\n", tc.expectedChallenge, got)
+			}
+		})
+	}
+}
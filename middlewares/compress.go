@@ -0,0 +1,449 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	acceptEncodingHeaderKey  string = "Accept-Encoding"
+	contentEncodingHeaderKey string = "Content-Encoding"
+	contentLengthHeaderKey   string = "Content-Length"
+	contentTypeHeaderKey     string = "Content-Type"
+	cacheControlHeaderKey    string = "Cache-Control"
+	acceptRangesHeaderKey    string = "Accept-Ranges"
+	etagHeaderKey            string = "ETag"
+
+	EncodingGzip    string = "gzip"
+	EncodingDeflate string = "deflate"
+
+	defaultCompressMinSize    int = 1024
+	defaultCompressBufferSize int = 1 << 20
+)
+
+var defaultCompressContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+	"+json",
+	"+xml",
+}
+
+// CompressConfig is the configuration of the compression middleware.
+type CompressConfig struct {
+	// The compression level, from 1 to 9 – or -1 for the default one.
+	Level int
+
+	// The responses smaller than this are not compressed. By default it is
+	// 1024 bytes. Streamed responses are compressed regardless of the size.
+	MinSize int
+
+	// The content types which are compressed. An entry ending with /
+	// matches the whole type – eg. text/ –, an entry starting with +
+	// matches the suffix – eg. +json –, otherwise it is an exact match.
+	// By default the common textual types are compressed.
+	ContentTypes []string
+
+	// The supported encodings in the order of preference.
+	// By default both gzip and deflate are supported, gzip preferred.
+	Encodings []string
+
+	// The maximum size of the response buffered in order to send the exact
+	// Content-Length of the compressed body. Larger responses are compressed
+	// on the fly. By default it is 1MB.
+	MaxBufferSize int
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+type compressor struct {
+	conf  CompressConfig
+	pools map[string]*sync.Pool
+}
+
+// Compress creates and returns a middleware, which compresses the
+// response based on the Accept-Encoding of the request. The responses
+// are compressed with exact Content-Length, while the streamed – flushed –
+// responses are compressed on the fly.
+func Compress(conf CompressConfig) gorouter.Middleware {
+	c := newCompressor(conf)
+
+	return gorouter.NewMiddleware(
+		func(ctx gorouter.Context) {
+			// There is no body to compress in the response of HEAD.
+			if ctx.GetRequestMethod() == http.MethodHead {
+				ctx.Next()
+
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: ctx.GetResponseWriter(),
+				c:              c,
+				encoding:       negotiateEncoding(ctx.GetRequestHeader(acceptEncodingHeaderKey), c.conf.Encodings),
+				statusCode:     http.StatusOK,
+			}
+
+			ctx.SetResponseWriter(cw)
+			ctx.Defer(cw.Close)
+
+			ctx.Next()
+		},
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+func newCompressor(conf CompressConfig) *compressor {
+	if conf.Level == 0 {
+		conf.Level = gzip.DefaultCompression
+	}
+
+	if conf.MinSize <= 0 {
+		conf.MinSize = defaultCompressMinSize
+	}
+
+	if len(conf.ContentTypes) == 0 {
+		conf.ContentTypes = defaultCompressContentTypes
+	}
+
+	if len(conf.Encodings) == 0 {
+		conf.Encodings = []string{EncodingGzip, EncodingDeflate}
+	}
+
+	if conf.MaxBufferSize <= 0 {
+		conf.MaxBufferSize = defaultCompressBufferSize
+	}
+
+	c := &compressor{
+		conf:  conf,
+		pools: make(map[string]*sync.Pool),
+	}
+
+	for _, enc := range conf.Encodings {
+		var newEncoder func() (encoder, error)
+
+		switch enc {
+		case EncodingGzip:
+			newEncoder = func() (encoder, error) { return gzip.NewWriterLevel(io.Discard, conf.Level) }
+		case EncodingDeflate:
+			newEncoder = func() (encoder, error) { return zlib.NewWriterLevel(io.Discard, conf.Level) }
+		default:
+			panic("unsupported encoding: " + enc)
+		}
+
+		// The level is validated here, so the pool never fails.
+		if _, err := newEncoder(); err != nil {
+			panic(err)
+		}
+
+		c.pools[enc] = &sync.Pool{
+			New: func() any {
+				e, _ := newEncoder()
+				return e
+			},
+		}
+	}
+
+	return c
+}
+
+func (c *compressor) getEncoder(encoding string, w io.Writer) encoder {
+	e := c.pools[encoding].Get().(encoder)
+	e.Reset(w)
+
+	return e
+}
+
+func (c *compressor) putEncoder(encoding string, e encoder) {
+	c.pools[encoding].Put(e)
+}
+
+func (c *compressor) isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range c.conf.ContentTypes {
+		switch {
+		case strings.HasSuffix(t, "/"):
+			if strings.HasPrefix(mediaType, t) {
+				return true
+			}
+		case strings.HasPrefix(t, "+"):
+			if strings.HasSuffix(mediaType, t) {
+				return true
+			}
+		default:
+			if mediaType == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// compressWriter buffers the response until it is closed or flushed,
+// then decides whether it should be compressed or not.
+type compressWriter struct {
+	http.ResponseWriter
+
+	c *compressor
+
+	// The negotiated encoding. If it is empty, then the
+	// client does not accept any of the supported encodings.
+	encoding   string
+	statusCode int
+	buf        bytes.Buffer
+
+	// Whether the status code is written to the underlying writer.
+	isStarted bool
+	enc       encoder
+}
+
+var (
+	_ http.ResponseWriter = (*compressWriter)(nil)
+	_ http.Flusher        = (*compressWriter)(nil)
+)
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.isStarted {
+		return
+	}
+	cw.statusCode = statusCode
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.isStarted {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	n, _ := cw.buf.Write(b)
+
+	// The response is too large to be buffered, so it is compressed on the fly.
+	if cw.buf.Len() > cw.c.conf.MaxBufferSize {
+		if err := cw.start(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Flush starts streaming the – compressed – response.
+func (cw *compressWriter) Flush() {
+	if !cw.isStarted {
+		cw.start()
+	}
+
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, so it
+// could be used with http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close writes the buffered response with exact Content-Length,
+// or finishes the compression in case of streaming.
+func (cw *compressWriter) Close() {
+	if cw.isStarted {
+		if cw.enc != nil {
+			cw.enc.Close()
+			cw.c.putEncoder(cw.encoding, cw.enc)
+			cw.enc = nil
+		}
+
+		return
+	}
+
+	cw.isStarted = true
+
+	if !cw.shouldCompress(true) {
+		cw.ResponseWriter.WriteHeader(cw.statusCode)
+		cw.buf.WriteTo(cw.ResponseWriter)
+
+		return
+	}
+
+	var (
+		compressed bytes.Buffer
+
+		enc = cw.c.getEncoder(cw.encoding, &compressed)
+	)
+
+	cw.buf.WriteTo(enc)
+	enc.Close()
+	cw.c.putEncoder(cw.encoding, enc)
+
+	cw.setEncodingHeaders()
+	cw.Header().Set(contentLengthHeaderKey, strconv.Itoa(compressed.Len()))
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+	compressed.WriteTo(cw.ResponseWriter)
+}
+
+// start writes the status code, then the buffered response
+// – compressed, if it should be – to the underlying writer.
+func (cw *compressWriter) start() error {
+	cw.isStarted = true
+
+	if cw.shouldCompress(false) {
+		cw.setEncodingHeaders()
+		cw.enc = cw.c.getEncoder(cw.encoding, cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+
+	var w io.Writer = cw.ResponseWriter
+	if cw.enc != nil {
+		w = cw.enc
+	}
+
+	_, err := cw.buf.WriteTo(w)
+
+	return err
+}
+
+// shouldCompress returns whether the response should be compressed.
+// If the response could be compressed, then Vary is set regardless
+// of the accepted encodings of the client.
+func (cw *compressWriter) shouldCompress(isComplete bool) bool {
+	header := cw.Header()
+
+	switch cw.statusCode {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+
+	if cw.statusCode < http.StatusOK || header.Get(contentEncodingHeaderKey) != "" {
+		return false
+	}
+
+	if strings.Contains(strings.ToLower(header.Get(cacheControlHeaderKey)), "no-transform") {
+		return false
+	}
+
+	// The size of streamed responses is unknown.
+	if isComplete && cw.buf.Len() < cw.c.conf.MinSize {
+		return false
+	}
+
+	contentType := header.Get(contentTypeHeaderKey)
+	if contentType == "" {
+		if cw.buf.Len() == 0 {
+			return false
+		}
+
+		// Otherwise net/http would sniff the compressed content.
+		contentType = http.DetectContentType(cw.buf.Bytes())
+		header.Set(contentTypeHeaderKey, contentType)
+	}
+
+	if !cw.c.isCompressible(contentType) {
+		return false
+	}
+
+	addVary(header, acceptEncodingHeaderKey)
+
+	return cw.encoding != ""
+}
+
+func (cw *compressWriter) setEncodingHeaders() {
+	header := cw.Header()
+
+	header.Set(contentEncodingHeaderKey, cw.encoding)
+	header.Del(contentLengthHeaderKey)
+
+	// The ranges of the compressed body would not match the ranges of the original.
+	header.Del(acceptRangesHeaderKey)
+
+	// The compressed body is not byte-for-byte identical.
+	if etag := header.Get(etagHeaderKey); strings.HasPrefix(etag, `"`) {
+		header.Set(etagHeaderKey, "W/"+etag)
+	}
+}
+
+// negotiateEncoding returns the most preferred supported
+// encoding, which is accepted by the client – if any.
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	var (
+		qValues   = make(map[string]float64)
+		wildcardQ = -1.0
+	)
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcardQ = q
+
+			continue
+		}
+
+		qValues[name] = q
+	}
+
+	var (
+		best  string
+		bestQ float64
+	)
+
+	for _, enc := range supported {
+		q, ok := qValues[enc]
+		if !ok {
+			q = wildcardQ
+		}
+
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+
+	return best
+}
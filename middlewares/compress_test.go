@@ -0,0 +1,220 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	type testCase struct {
+		name           string
+		acceptEncoding string
+		expected       string
+	}
+
+	var supported = []string{EncodingGzip, EncodingDeflate}
+
+	tt := []testCase{
+		{name: "empty header", acceptEncoding: "", expected: ""},
+		{name: "gzip", acceptEncoding: "gzip", expected: EncodingGzip},
+		{name: "server preference wins on equal q", acceptEncoding: "deflate, gzip", expected: EncodingGzip},
+		{name: "higher q wins", acceptEncoding: "gzip;q=0.5, deflate", expected: EncodingDeflate},
+		{name: "q=0 refuses", acceptEncoding: "gzip;q=0", expected: ""},
+		{name: "wildcard", acceptEncoding: "*", expected: EncodingGzip},
+		{name: "wildcard with explicit refusal", acceptEncoding: "gzip;q=0, *", expected: EncodingDeflate},
+		{name: "unsupported only", acceptEncoding: "br", expected: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := negotiateEncoding(tc.acceptEncoding, supported); got != tc.expected {
+				t.Errorf("expected encoding: %q; got: %q\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	type testCase struct {
+		name           string
+		acceptEncoding string
+		handler        gorouter.HandlerFunc
+
+		expectedEncoding      string
+		expectedVary          string
+		expectedContentLength bool
+		expectedBody          string
+	}
+
+	var (
+		largeBody = strings.Repeat("compress me please ", 200)
+
+		writeText = func(contentType string, body string) gorouter.HandlerFunc {
+			return func(ctx gorouter.Context) {
+				ctx.AppendHttpHeader("Content-Type", contentType)
+				ctx.Copy(strings.NewReader(body))
+			}
+		}
+	)
+
+	tt := []testCase{
+		{
+			name:                  "compresses large text with gzip",
+			acceptEncoding:        "gzip, deflate",
+			handler:               writeText("text/plain; charset=utf-8", largeBody),
+			expectedEncoding:      EncodingGzip,
+			expectedVary:          "Accept-Encoding",
+			expectedContentLength: true,
+			expectedBody:          largeBody,
+		},
+		{
+			name:                  "compresses with deflate",
+			acceptEncoding:        "deflate",
+			handler:               writeText("application/problem+json", largeBody),
+			expectedEncoding:      EncodingDeflate,
+			expectedVary:          "Accept-Encoding",
+			expectedContentLength: true,
+			expectedBody:          largeBody,
+		},
+		{
+			name:                  "does not compress small response",
+			acceptEncoding:        "gzip",
+			handler:               writeText("text/plain", "small"),
+			expectedContentLength: false,
+			expectedBody:          "small",
+		},
+		{
+			name:           "does not compress without accepted encoding, but sets vary",
+			acceptEncoding: "",
+			handler:        writeText("text/plain", largeBody),
+			expectedVary:   "Accept-Encoding",
+			expectedBody:   largeBody,
+		},
+		{
+			name:           "does not compress not allowed content type",
+			acceptEncoding: "gzip",
+			handler:        writeText("image/png", largeBody),
+			expectedBody:   largeBody,
+		},
+		{
+			name:           "does not compress no-transform response",
+			acceptEncoding: "gzip",
+			handler: func(ctx gorouter.Context) {
+				ctx.AppendHttpHeader("Cache-Control", "no-transform")
+				writeText("text/plain", largeBody)(ctx)
+			},
+			expectedBody: largeBody,
+		},
+		{
+			name:           "does not compress already encoded response",
+			acceptEncoding: "gzip",
+			handler: func(ctx gorouter.Context) {
+				ctx.AppendHttpHeader("Content-Encoding", "br")
+				writeText("text/plain", largeBody)(ctx)
+			},
+			expectedEncoding: "br",
+			expectedBody:     largeBody,
+		},
+		{
+			name:           "compresses streamed response on the fly",
+			acceptEncoding: "gzip",
+			handler: func(ctx gorouter.Context) {
+				ctx.AppendHttpHeader("Content-Type", "text/event-stream")
+				ctx.Copy(strings.NewReader("data: 1\n\n"))
+				ctx.Flush()
+				ctx.Copy(strings.NewReader("data: 2\n\n"))
+			},
+			expectedEncoding: EncodingGzip,
+			expectedVary:     "Accept-Encoding",
+			expectedBody:     "data: 1\n\ndata: 2\n\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gorouter.New()
+			r.RegisterMiddlewares(Compress(CompressConfig{}))
+			r.Get("/", tc.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tc.expectedEncoding {
+				t.Errorf("expected Content-Encoding: %q; got: %q\n", tc.expectedEncoding, got)
+			}
+
+			if got := rec.Header().Get("Vary"); got != tc.expectedVary {
+				t.Errorf("expected Vary: %q; got: %q\n", tc.expectedVary, got)
+			}
+
+			contentLength := rec.Header().Get("Content-Length")
+			if tc.expectedContentLength && contentLength != strconv.Itoa(rec.Body.Len()) {
+				t.Errorf("expected Content-Length: %d; got: %q\n", rec.Body.Len(), contentLength)
+			}
+
+			var body io.Reader = rec.Body
+
+			switch tc.expectedEncoding {
+			case EncodingGzip:
+				zr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("expected gzip body; got error: %v\n", err)
+				}
+				body = zr
+			case EncodingDeflate:
+				zr, err := zlib.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("expected deflate body; got error: %v\n", err)
+				}
+				body = zr
+			}
+
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("expected no error; got: %v\n", err)
+			}
+
+			if string(b) != tc.expectedBody {
+				t.Errorf("expected body length: %d; got: %d\n", len(tc.expectedBody), len(b))
+			}
+		})
+	}
+}
+
+func TestCompressWeakensEtag(t *testing.T) {
+	r := gorouter.New()
+	r.RegisterMiddlewares(Compress(CompressConfig{MinSize: 1}))
+	r.Get("/", func(ctx gorouter.Context) {
+		ctx.AppendHttpHeader("ETag", `"abc"`)
+		ctx.AppendHttpHeader("Accept-Ranges", "bytes")
+		ctx.AppendHttpHeader("Content-Type", "text/plain")
+		ctx.Copy(strings.NewReader("hello"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("ETag"); got != `W/"abc"` {
+		t.Errorf("expected ETag: %q; got: %q\n", `W/"abc"`, got)
+	}
+
+	if got := rec.Header().Get("Accept-Ranges"); got != "" {
+		t.Errorf("expected no Accept-Ranges; got: %q\n", got)
+	}
+}
@@ -0,0 +1,283 @@
+package middlewares
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	originHeaderKey string = "Origin"
+	varyHeaderKey   string = "Vary"
+
+	accessControlAllowOriginHeaderKey           string = "Access-Control-Allow-Origin"
+	accessControlAllowCredentialsHeaderKey      string = "Access-Control-Allow-Credentials"
+	accessControlAllowMethodsHeaderKey          string = "Access-Control-Allow-Methods"
+	accessControlAllowHeadersHeaderKey          string = "Access-Control-Allow-Headers"
+	accessControlExposeHeadersHeaderKey         string = "Access-Control-Expose-Headers"
+	accessControlMaxAgeHeaderKey                string = "Access-Control-Max-Age"
+	accessControlAllowPrivateNetworkHeaderKey   string = "Access-Control-Allow-Private-Network"
+	accessControlRequestMethodHeaderKey         string = "Access-Control-Request-Method"
+	accessControlRequestHeadersHeaderKey        string = "Access-Control-Request-Headers"
+	accessControlRequestPrivateNetworkHeaderKey string = "Access-Control-Request-Private-Network"
+
+	allOrigins string = "*"
+)
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORSConfig is the configuration of the CORS middleware.
+type CORSConfig struct {
+	// The origins which are allowed to access the resources.
+	// An origin could be an exact match – eg. https://example.com –,
+	// a wildcard – eg. https://*.example.com – or * for all origins.
+	AllowOrigins []string
+
+	// The origins matching any of these expressions are allowed as well.
+	AllowOriginRegexps []*regexp.Regexp
+
+	// If it is set, then the origins accepted by it are allowed as well.
+	AllowOriginFunc func(origin string) bool
+
+	// The methods allowed in the preflight response.
+	// If it is empty, then the simple methods alongside
+	// PUT, PATCH and DELETE are allowed.
+	AllowMethods []string
+
+	// The headers allowed in the preflight response.
+	// If it is empty, then the requested headers are reflected.
+	AllowHeaders []string
+
+	// The headers which could be accessed by the client.
+	ExposeHeaders []string
+
+	// Whether the request could include credentials – cookies,
+	// authorization headers. In this case the origin is always
+	// reflected instead of *, as the browsers require it.
+	AllowCredentials bool
+
+	// How long the result of the preflight could be cached.
+	// If it is zero, then the header is not sent.
+	MaxAge time.Duration
+
+	// Whether requests from public networks to private
+	// networks are allowed, according to the Private Network Access.
+	AllowPrivateNetwork bool
+}
+
+type cors struct {
+	conf CORSConfig
+
+	allowsAll bool
+	exact     map[string]struct{}
+	wildcards [][2]string
+	methods   string
+	headers   string
+	exposed   string
+	maxAge    string
+}
+
+// CORS creates and returns a middleware which handles the Cross-Origin
+// Resource Sharing. The preflight requests are answered right away,
+// without invoking the handler of the route – or the OPTIONS handler
+// of the router –, while every other request is passed through
+// with the necessary headers.
+func CORS(conf CORSConfig) gorouter.Middleware {
+	c := newCors(conf)
+
+	return gorouter.NewMiddleware(
+		c.handle,
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+func newCors(conf CORSConfig) *cors {
+	c := &cors{
+		conf:  conf,
+		exact: make(map[string]struct{}),
+	}
+
+	for _, o := range conf.AllowOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+
+		if o == allOrigins {
+			c.allowsAll = true
+
+			continue
+		}
+
+		if prefix, suffix, isWildcard := strings.Cut(o, "*"); isWildcard {
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+
+			continue
+		}
+
+		c.exact[o] = struct{}{}
+	}
+
+	methods := conf.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+
+	c.methods = strings.Join(methods, ", ")
+	c.headers = strings.Join(conf.AllowHeaders, ", ")
+	c.exposed = strings.Join(conf.ExposeHeaders, ", ")
+
+	if conf.MaxAge > 0 {
+		c.maxAge = strconv.FormatInt(int64(conf.MaxAge.Seconds()), 10)
+	}
+
+	return c
+}
+
+func (c *cors) handle(ctx gorouter.Context) {
+	var (
+		header      = ctx.GetResponseHeaders()
+		origin      = ctx.GetRequestHeader(originHeaderKey)
+		isPreflight = ctx.GetRequestMethod() == http.MethodOptions &&
+			ctx.GetRequestHeader(accessControlRequestMethodHeaderKey) != ""
+	)
+
+	// The response differs based on the origin – unless every origin
+	// is allowed without credentials –, so the caches must know it.
+	if c.dependsOnOrigin() {
+		addVary(header, originHeaderKey)
+	}
+
+	if isPreflight {
+		addVary(header, accessControlRequestMethodHeaderKey, accessControlRequestHeadersHeaderKey)
+
+		if c.conf.AllowPrivateNetwork {
+			addVary(header, accessControlRequestPrivateNetworkHeaderKey)
+		}
+	}
+
+	if origin == "" || !c.isOriginAllowed(origin) {
+		// The browser is going to block the preflight
+		// anyway, without the access control headers.
+		if isPreflight && origin != "" {
+			ctx.Status(http.StatusNoContent)
+
+			return
+		}
+
+		ctx.Next()
+
+		return
+	}
+
+	c.setOrigin(header, origin)
+
+	if !isPreflight {
+		if c.exposed != "" {
+			header.Set(accessControlExposeHeadersHeaderKey, c.exposed)
+		}
+
+		ctx.Next()
+
+		return
+	}
+
+	header.Set(accessControlAllowMethodsHeaderKey, c.methods)
+
+	allowedHeaders := c.headers
+	if allowedHeaders == "" {
+		allowedHeaders = ctx.GetRequestHeader(accessControlRequestHeadersHeaderKey)
+	}
+
+	if allowedHeaders != "" {
+		header.Set(accessControlAllowHeadersHeaderKey, allowedHeaders)
+	}
+
+	if c.maxAge != "" {
+		header.Set(accessControlMaxAgeHeaderKey, c.maxAge)
+	}
+
+	if c.conf.AllowPrivateNetwork && ctx.GetRequestHeader(accessControlRequestPrivateNetworkHeaderKey) == "true" {
+		header.Set(accessControlAllowPrivateNetworkHeaderKey, "true")
+	}
+
+	// The preflight is answered here, so neither
+	// the handler nor the next middlewares are called.
+	ctx.Status(http.StatusNoContent)
+}
+
+func (c *cors) dependsOnOrigin() bool {
+	return !c.allowsAll || c.conf.AllowCredentials
+}
+
+func (c *cors) setOrigin(header http.Header, origin string) {
+	if c.dependsOnOrigin() {
+		header.Set(accessControlAllowOriginHeaderKey, origin)
+	} else {
+		header.Set(accessControlAllowOriginHeaderKey, allOrigins)
+	}
+
+	if c.conf.AllowCredentials {
+		header.Set(accessControlAllowCredentialsHeaderKey, "true")
+	}
+}
+
+func (c *cors) isOriginAllowed(origin string) bool {
+	if c.allowsAll {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+
+	if _, ok := c.exact[lower]; ok {
+		return true
+	}
+
+	for _, w := range c.wildcards {
+		// The wildcard must match at least one character.
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			return true
+		}
+	}
+
+	for _, re := range c.conf.AllowOriginRegexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	if c.conf.AllowOriginFunc != nil {
+		return c.conf.AllowOriginFunc(origin)
+	}
+
+	return false
+}
+
+// addVary adds the given header names to the Vary header,
+// unless they are already present in it.
+func addVary(header http.Header, names ...string) {
+	existing := make(map[string]struct{})
+
+	for _, v := range header.Values(varyHeaderKey) {
+		for _, e := range strings.Split(v, ",") {
+			existing[strings.ToLower(strings.TrimSpace(e))] = struct{}{}
+		}
+	}
+
+	for _, n := range names {
+		if _, ok := existing[strings.ToLower(n)]; ok {
+			continue
+		}
+
+		header.Add(varyHeaderKey, n)
+		existing[strings.ToLower(n)] = struct{}{}
+	}
+}
@@ -0,0 +1,267 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestCORS(t *testing.T) {
+	type testCase struct {
+		name    string
+		conf    CORSConfig
+		method  string
+		headers map[string]string
+
+		expectedStatusCode     int
+		expectedHandlerCalled  bool
+		expectedHeaders        map[string]string
+		expectedMissingHeaders []string
+	}
+
+	var conf = CORSConfig{
+		AllowOrigins:       []string{"https://example.com", "https://*.example.org"},
+		AllowOriginRegexps: []*regexp.Regexp{regexp.MustCompile(`^https://app-\d+\.test$`)},
+		AllowOriginFunc:    func(origin string) bool { return origin == "https://func.test" },
+		ExposeHeaders:      []string{"X-Total-Count"},
+		MaxAge:             10 * time.Minute,
+	}
+
+	tt := []testCase{
+		{
+			name:                   "request without origin is passed through with vary",
+			conf:                   conf,
+			method:                 http.MethodGet,
+			expectedStatusCode:     http.StatusOK,
+			expectedHandlerCalled:  true,
+			expectedHeaders:        map[string]string{"Vary": "Origin"},
+			expectedMissingHeaders: []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:                  "exact origin is allowed",
+			conf:                  conf,
+			method:                http.MethodGet,
+			headers:               map[string]string{"Origin": "https://example.com"},
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+			expectedHeaders: map[string]string{
+				"Vary":                          "Origin",
+				"Access-Control-Allow-Origin":   "https://example.com",
+				"Access-Control-Expose-Headers": "X-Total-Count",
+			},
+		},
+		{
+			name:                  "wildcard origin is allowed",
+			conf:                  conf,
+			method:                http.MethodGet,
+			headers:               map[string]string{"Origin": "https://api.example.org"},
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+			expectedHeaders:       map[string]string{"Access-Control-Allow-Origin": "https://api.example.org"},
+		},
+		{
+			name:                   "wildcard does not match the bare domain",
+			conf:                   conf,
+			method:                 http.MethodGet,
+			headers:                map[string]string{"Origin": "https://example.org"},
+			expectedStatusCode:     http.StatusOK,
+			expectedHandlerCalled:  true,
+			expectedMissingHeaders: []string{"Access-Control-Allow-Origin"},
+		},
+		{
+			name:                  "regexp origin is allowed",
+			conf:                  conf,
+			method:                http.MethodGet,
+			headers:               map[string]string{"Origin": "https://app-42.test"},
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+			expectedHeaders:       map[string]string{"Access-Control-Allow-Origin": "https://app-42.test"},
+		},
+		{
+			name:                  "func origin is allowed",
+			conf:                  conf,
+			method:                http.MethodGet,
+			headers:               map[string]string{"Origin": "https://func.test"},
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+			expectedHeaders:       map[string]string{"Access-Control-Allow-Origin": "https://func.test"},
+		},
+		{
+			name:                   "not allowed origin gets no access control headers",
+			conf:                   conf,
+			method:                 http.MethodGet,
+			headers:                map[string]string{"Origin": "https://evil.com"},
+			expectedStatusCode:     http.StatusOK,
+			expectedHandlerCalled:  true,
+			expectedHeaders:        map[string]string{"Vary": "Origin"},
+			expectedMissingHeaders: []string{"Access-Control-Allow-Origin", "Access-Control-Expose-Headers"},
+		},
+		{
+			name:   "preflight is answered without calling the handler",
+			conf:   conf,
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://example.com",
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "Content-Type, X-Custom",
+			},
+			expectedStatusCode:    http.StatusNoContent,
+			expectedHandlerCalled: false,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Allow-Methods": "GET, HEAD, POST, PUT, PATCH, DELETE",
+				"Access-Control-Allow-Headers": "Content-Type, X-Custom",
+				"Access-Control-Max-Age":       "600",
+			},
+			expectedMissingHeaders: []string{"Access-Control-Expose-Headers"},
+		},
+		{
+			name:   "preflight of not allowed origin is answered without headers",
+			conf:   conf,
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://evil.com",
+				"Access-Control-Request-Method": http.MethodPut,
+			},
+			expectedStatusCode:     http.StatusNoContent,
+			expectedHandlerCalled:  false,
+			expectedMissingHeaders: []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Methods"},
+		},
+		{
+			name:                  "options without request method is not a preflight",
+			conf:                  conf,
+			method:                http.MethodOptions,
+			headers:               map[string]string{"Origin": "https://example.com"},
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+		},
+		{
+			name: "preflight with configured headers and private network",
+			conf: CORSConfig{
+				AllowOrigins:        []string{"https://example.com"},
+				AllowMethods:        []string{http.MethodGet, http.MethodPost},
+				AllowHeaders:        []string{"Content-Type"},
+				AllowPrivateNetwork: true,
+			},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                                 "https://example.com",
+				"Access-Control-Request-Method":          http.MethodPost,
+				"Access-Control-Request-Headers":         "X-Custom",
+				"Access-Control-Request-Private-Network": "true",
+			},
+			expectedStatusCode: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Methods":         "GET, POST",
+				"Access-Control-Allow-Headers":         "Content-Type",
+				"Access-Control-Allow-Private-Network": "true",
+			},
+			expectedMissingHeaders: []string{"Access-Control-Max-Age"},
+		},
+		{
+			name:                   "all origins without credentials responds with star and no vary",
+			conf:                   CORSConfig{AllowOrigins: []string{"*"}},
+			method:                 http.MethodGet,
+			headers:                map[string]string{"Origin": "https://example.com"},
+			expectedStatusCode:     http.StatusOK,
+			expectedHandlerCalled:  true,
+			expectedHeaders:        map[string]string{"Access-Control-Allow-Origin": "*"},
+			expectedMissingHeaders: []string{"Vary", "Access-Control-Allow-Credentials"},
+		},
+		{
+			name:                  "all origins with credentials reflects the origin",
+			conf:                  CORSConfig{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			method:                http.MethodGet,
+			headers:               map[string]string{"Origin": "https://example.com"},
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+			expectedHeaders: map[string]string{
+				"Vary":                             "Origin",
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				handlerCalled bool
+
+				handler = func(ctx gorouter.Context) {
+					handlerCalled = true
+					ctx.Status(http.StatusOK)
+				}
+
+				r = gorouter.New()
+			)
+
+			r.RegisterMiddlewares(CORS(tc.conf))
+			r.Get("/api/users", handler)
+			r.Options("/api/users", handler)
+
+			req := httptest.NewRequest(tc.method, "/api/users", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if handlerCalled != tc.expectedHandlerCalled {
+				t.Errorf("expected handler called: %v; got: %v\n", tc.expectedHandlerCalled, handlerCalled)
+			}
+
+			for k, v := range tc.expectedHeaders {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expected %s header: %q; got: %q\n", k, v, got)
+				}
+			}
+
+			for _, k := range tc.expectedMissingHeaders {
+				if got := rec.Header().Get(k); got != "" {
+					t.Errorf("expected no %s header; got: %q\n", k, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSWithOptionsHandler(t *testing.T) {
+	var optionsCalled bool
+
+	r := gorouter.New(gorouter.WithOptionsHandler(func(ctx gorouter.Context) {
+		optionsCalled = true
+		ctx.Status(http.StatusOK)
+	}))
+
+	r.RegisterMiddlewares(CORS(CORSConfig{AllowOrigins: []string{"https://example.com"}}))
+	r.Get("/api/users", func(ctx gorouter.Context) {})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if optionsCalled {
+		t.Errorf("expected the options handler not to be called for preflight\n")
+	}
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusNoContent, rec.Code)
+	}
+
+	if got := rec.Header().Values("Vary"); len(got) != 3 {
+		t.Errorf("expected vary headers: 3; got: %v\n", got)
+	}
+}
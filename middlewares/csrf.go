@@ -0,0 +1,329 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	secFetchSiteHeaderKey string = "Sec-Fetch-Site"
+
+	// CSRFTokenKey is the key of the token bound to the Context.
+	CSRFTokenKey gorouter.ContextKey = "__csrfToken__"
+
+	defaultCSRFCookieName string = "_csrf"
+	defaultCSRFHeaderName string = "X-CSRF-Token"
+	defaultCSRFFormField  string = "_csrf"
+
+	csrfTokenSize int = 32
+)
+
+var (
+	ErrCSRFTokenMissing  = errors.New("the csrf token is missing")
+	ErrCSRFTokenMismatch = errors.New("the csrf token does not match")
+	ErrCSRFOrigin        = errors.New("the origin of the request is not allowed")
+)
+
+// CSRFStore stores the tokens of the synchronizer token pattern
+// on the server side – typically in the session of the user.
+type CSRFStore interface {
+	// GetToken returns the stored token, or an empty string if there is none.
+	GetToken(ctx gorouter.Context) (string, error)
+	SaveToken(ctx gorouter.Context, token string) error
+}
+
+// CSRFConfig is the configuration of the CSRF protection middleware.
+type CSRFConfig struct {
+	// The store of the synchronizer token pattern. If it is <nil>,
+	// then the double-submit cookie pattern is used.
+	Store CSRFStore
+
+	// The cookie of the double-submit cookie pattern. By default
+	// the name is _csrf, the path is / and the SameSite is Lax.
+	// The Value, the HttpOnly and the MaxAge are ignored.
+	Cookie http.Cookie
+
+	// Whether the cookie is signed by the key ring of the router,
+	// so it could not be forged, eg. by a sibling subdomain.
+	SignedCookie bool
+
+	// The header and the form field, where the token is sent by the
+	// client. By default the header is X-CSRF-Token, the field is _csrf.
+	HeaderName string
+	FormField  string
+
+	// The trusted origins of the cross-site requests, eg. https://admin.example.com.
+	TrustedOrigins []string
+
+	// The registered urls of the routes, which are not protected, eg. webhooks.
+	ExemptRoutes []string
+
+	// If it returns true, then the request is not protected.
+	Exempt func(ctx gorouter.Context) bool
+
+	// The handler called when the request is rejected.
+	// By default 403 is responded.
+	OnError func(ctx gorouter.Context, err error)
+}
+
+type csrf struct {
+	conf CSRFConfig
+}
+
+// CSRF creates and returns a middleware, which protects the unsafe requests
+// against cross-site request forgery. The Origin and Sec-Fetch-Site headers
+// are checked, then the token sent in the header or the form field is
+// compared to the token of the store or the cookie. The token is available
+// by GetCSRFToken, eg. for the forms of the templates.
+func CSRF(conf ...CSRFConfig) gorouter.Middleware {
+	var c CSRFConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Cookie.Name == "" {
+		c.Cookie.Name = defaultCSRFCookieName
+	}
+
+	if c.Cookie.Path == "" {
+		c.Cookie.Path = "/"
+	}
+
+	if c.Cookie.SameSite == 0 {
+		c.Cookie.SameSite = http.SameSiteLaxMode
+	}
+
+	if c.HeaderName == "" {
+		c.HeaderName = defaultCSRFHeaderName
+	}
+
+	if c.FormField == "" {
+		c.FormField = defaultCSRFFormField
+	}
+
+	if c.OnError == nil {
+		c.OnError = func(ctx gorouter.Context, _ error) {
+			ctx.StatusText(http.StatusForbidden)
+		}
+	}
+
+	cs := &csrf{conf: c}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		if cs.isExempt(ctx) {
+			ctx.Next()
+
+			return
+		}
+
+		token, err := cs.getToken(ctx)
+		if err != nil {
+			ctx.StatusText(http.StatusInternalServerError)
+
+			return
+		}
+
+		// The token is masked for each request, so it
+		// could not be recovered by compression attacks.
+		ctx.BindValue(CSRFTokenKey, maskCSRFToken(token))
+
+		if isSafeMethod(ctx.GetRequestMethod()) {
+			ctx.Next()
+
+			return
+		}
+
+		if err := cs.verify(ctx, token); err != nil {
+			c.OnError(ctx, err)
+
+			return
+		}
+
+		ctx.Next()
+	})
+}
+
+// GetCSRFToken returns the masked token bound to the given
+// Context by the CSRF middleware – if there is any.
+func GetCSRFToken(ctx gorouter.Context) string {
+	token, _ := ctx.GetBindedValue(CSRFTokenKey).(string)
+	return token
+}
+
+func (cs *csrf) isExempt(ctx gorouter.Context) bool {
+	if cs.conf.Exempt != nil && cs.conf.Exempt(ctx) {
+		return true
+	}
+
+	return slices.Contains(cs.conf.ExemptRoutes, ctx.GetRegisteredUrl())
+}
+
+// getToken returns the token of the client, or generates and saves a new one.
+func (cs *csrf) getToken(ctx gorouter.Context) (string, error) {
+	if cs.conf.Store != nil {
+		token, err := cs.conf.Store.GetToken(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		if token == "" {
+			token = newCSRFToken()
+
+			if err := cs.conf.Store.SaveToken(ctx, token); err != nil {
+				return "", err
+			}
+		}
+
+		return token, nil
+	}
+
+	var (
+		cookie *http.Cookie
+		err    error
+	)
+
+	if cs.conf.SignedCookie {
+		cookie, err = ctx.GetSignedCookie(cs.conf.Cookie.Name)
+	} else {
+		cookie, err = ctx.GetCookie(cs.conf.Cookie.Name)
+	}
+
+	if err == nil && len(cookie.Value) > 0 {
+		return cookie.Value, nil
+	}
+
+	token := newCSRFToken()
+
+	c := cs.conf.Cookie
+	c.Value = token
+	// The token is read by the scripts, which send it in the header.
+	c.HttpOnly = false
+	c.MaxAge = 0
+
+	if cs.conf.SignedCookie {
+		err = ctx.SetSignedCookie(&c)
+	} else {
+		err = ctx.SetCookie(&c)
+	}
+
+	return token, err
+}
+
+func (cs *csrf) verify(ctx gorouter.Context, token string) error {
+	if !cs.isAllowedOrigin(ctx) {
+		return ErrCSRFOrigin
+	}
+
+	sent := ctx.GetRequestHeader(cs.conf.HeaderName)
+	if sent == "" {
+		// The form is parsed only if there is no header,
+		// so the body of the other requests is not read.
+		if v, err := ctx.GetFormValue(cs.conf.FormField); err == nil {
+			sent = v
+		}
+	}
+
+	if sent == "" {
+		return ErrCSRFTokenMissing
+	}
+
+	// The scripts could send the raw token read from the cookie.
+	if len(sent) != len(token) {
+		sent = unmaskCSRFToken(sent)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+		return ErrCSRFTokenMismatch
+	}
+
+	return nil
+}
+
+// isAllowedOrigin checks the headers sent by the browsers about the
+// origin of the request. If there is none of them, then only the
+// token is checked, since it could be a non-browser client.
+func (cs *csrf) isAllowedOrigin(ctx gorouter.Context) bool {
+	origin := ctx.GetRequestHeader(originHeaderKey)
+
+	if origin != "" && slices.Contains(cs.conf.TrustedOrigins, origin) {
+		return true
+	}
+
+	switch ctx.GetRequestHeader(secFetchSiteHeaderKey) {
+	case "same-origin", "none":
+		return true
+	case "same-site", "cross-site":
+		return false
+	}
+
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	r := ctx.GetRequest()
+
+	return r != nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFToken() string {
+	b := make([]byte, csrfTokenSize)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// maskCSRFToken xors the token with a random pad,
+// then returns the pad and the result together.
+func maskCSRFToken(token string) string {
+	var (
+		t = []byte(token)
+		b = make([]byte, 2*len(t))
+	)
+
+	rand.Read(b[:len(t)])
+
+	for i := range t {
+		b[len(t)+i] = t[i] ^ b[i]
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func unmaskCSRFToken(masked string) string {
+	b, err := base64.RawURLEncoding.DecodeString(masked)
+	if err != nil || len(b)%2 != 0 {
+		return ""
+	}
+
+	var (
+		n = len(b) / 2
+		t = make([]byte, n)
+	)
+
+	for i := range t {
+		t[i] = b[n+i] ^ b[i]
+	}
+
+	return string(t)
+}
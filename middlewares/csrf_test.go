@@ -0,0 +1,228 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+type testCSRFStore struct {
+	tokens map[string]string
+}
+
+func (s *testCSRFStore) GetToken(ctx gorouter.Context) (string, error) {
+	return s.tokens[ctx.GetRequestHeader("X-Session")], nil
+}
+
+func (s *testCSRFStore) SaveToken(ctx gorouter.Context, token string) error {
+	s.tokens[ctx.GetRequestHeader("X-Session")] = token
+	return nil
+}
+
+func TestCSRF(t *testing.T) {
+	type testCase struct {
+		name string
+		conf CSRFConfig
+		url  string
+
+		// The way the token is sent: header, raw-header, form or none.
+		sendToken string
+		headers   map[string]string
+
+		expectedStatusCode int
+		expectedError      error
+	}
+
+	tt := []testCase{
+		{
+			name:               "rejects the request without token",
+			url:                "/admin/users",
+			sendToken:          "none",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError:      ErrCSRFTokenMissing,
+		},
+		{
+			name:               "accepts the masked token in the header",
+			url:                "/admin/users",
+			sendToken:          "header",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "accepts the raw token of the cookie in the header",
+			url:                "/admin/users",
+			sendToken:          "raw-header",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "accepts the token in the form",
+			url:                "/admin/users",
+			sendToken:          "form",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "rejects the wrong token",
+			url:                "/admin/users",
+			sendToken:          "wrong",
+			expectedStatusCode: http.StatusForbidden,
+			expectedError:      ErrCSRFTokenMismatch,
+		},
+		{
+			name:               "rejects the cross-site request even with token",
+			url:                "/admin/users",
+			sendToken:          "header",
+			headers:            map[string]string{secFetchSiteHeaderKey: "cross-site"},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError:      ErrCSRFOrigin,
+		},
+		{
+			name:               "rejects the foreign origin",
+			url:                "/admin/users",
+			sendToken:          "header",
+			headers:            map[string]string{originHeaderKey: "https://evil.com"},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError:      ErrCSRFOrigin,
+		},
+		{
+			name:               "accepts the same origin",
+			url:                "/admin/users",
+			sendToken:          "header",
+			headers:            map[string]string{originHeaderKey: "http://example.com", secFetchSiteHeaderKey: "same-origin"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "accepts the trusted origin",
+			conf:               CSRFConfig{TrustedOrigins: []string{"https://admin.example.com"}},
+			url:                "/admin/users",
+			sendToken:          "header",
+			headers:            map[string]string{originHeaderKey: "https://admin.example.com", secFetchSiteHeaderKey: "same-site"},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "does not protect the exempt route",
+			conf:               CSRFConfig{ExemptRoutes: []string{"/webhooks/{provider}"}},
+			url:                "/webhooks/stripe",
+			sendToken:          "none",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "accepts the token of the store",
+			conf:               CSRFConfig{Store: &testCSRFStore{tokens: make(map[string]string)}},
+			url:                "/admin/users",
+			sendToken:          "header",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "rejects the token of another session of the store",
+			conf:               CSRFConfig{Store: &testCSRFStore{tokens: make(map[string]string)}},
+			url:                "/admin/users",
+			sendToken:          "header",
+			headers:            map[string]string{"X-Session": "other"},
+			expectedStatusCode: http.StatusForbidden,
+			expectedError:      ErrCSRFTokenMismatch,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				gotErr error
+
+				r = gorouter.New()
+			)
+
+			tc.conf.OnError = func(ctx gorouter.Context, err error) {
+				gotErr = err
+				ctx.StatusText(http.StatusForbidden)
+			}
+
+			r.RegisterMiddlewares(CSRF(tc.conf))
+
+			r.Get("/admin/users", func(ctx gorouter.Context) {
+				ctx.Copy(strings.NewReader(GetCSRFToken(ctx)))
+			})
+			r.Post("/admin/users", func(ctx gorouter.Context) {})
+			r.Post("/webhooks/{provider}", func(ctx gorouter.Context) {})
+
+			// Getting the token by a safe request.
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			req.Header.Set("X-Session", "abc")
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status code: %d; got: %d\n", http.StatusOK, rec.Code)
+			}
+
+			var (
+				masked  = rec.Body.String()
+				cookies = rec.Result().Cookies()
+				body    = url.Values{}
+			)
+
+			if tc.sendToken == "form" {
+				body.Set(defaultCSRFFormField, masked)
+			}
+
+			req = httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(body.Encode()))
+			req.Header.Set("X-Session", "abc")
+			req.Header.Set("Content-Type", gorouter.FormUrlEncodedContentType)
+
+			for _, c := range cookies {
+				req.AddCookie(c)
+			}
+
+			switch tc.sendToken {
+			case "header":
+				req.Header.Set(defaultCSRFHeaderName, masked)
+			case "raw-header":
+				req.Header.Set(defaultCSRFHeaderName, cookies[0].Value)
+			case "wrong":
+				req.Header.Set(defaultCSRFHeaderName, maskCSRFToken(newCSRFToken()))
+			}
+
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec = httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if !errors.Is(gotErr, tc.expectedError) {
+				t.Errorf("expected error: %v; got: %v\n", tc.expectedError, gotErr)
+			}
+		})
+	}
+}
+
+func TestCSRFTokenMasking(t *testing.T) {
+	var (
+		token = newCSRFToken()
+
+		a = maskCSRFToken(token)
+		b = maskCSRFToken(token)
+	)
+
+	if a == b {
+		t.Errorf("expected different masked tokens; got: %q twice\n", a)
+	}
+
+	for _, masked := range []string{a, b} {
+		if got := unmaskCSRFToken(masked); got != token {
+			t.Errorf("expected token: %q; got: %q\n", token, got)
+		}
+	}
+
+	if got := unmaskCSRFToken("not base64!"); got != "" {
+		t.Errorf("expected empty token; got: %q\n", got)
+	}
+}
@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	defaultDecompressMaxSize int64 = 32 << 20
+
+	encodingIdentity string = "identity"
+)
+
+// DecompressConfig is the configuration of the request decompression middleware.
+type DecompressConfig struct {
+	// The maximum size of the decompressed body. Reading beyond it
+	// results in *http.MaxBytesError. By default it is 32MB.
+	MaxSize int64
+}
+
+// decompressBody reads the decompressed body, then closes both the
+// decompressor and the original body.
+type decompressBody struct {
+	io.Reader
+
+	closers []io.Closer
+}
+
+func (db *decompressBody) Close() error {
+	var err error
+	for i := len(db.closers) - 1; i >= 0; i-- {
+		if e := db.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Decompress creates and returns a middleware, which transparently
+// decompresses the gzip or deflate encoded request bodies, so the
+// handlers could read them as they were sent uncompressed.
+// In case of unsupported encoding 415 is responded, while
+// in case of a malformed body 400 is responded.
+func Decompress(conf DecompressConfig) gorouter.Middleware {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = defaultDecompressMaxSize
+	}
+
+	var supported = EncodingGzip + ", " + EncodingDeflate
+
+	return gorouter.NewMiddleware(
+		func(ctx gorouter.Context) {
+			r := ctx.GetRequest()
+			if r == nil || r.Body == nil || r.Body == http.NoBody {
+				ctx.Next()
+
+				return
+			}
+
+			encodings := parseContentEncoding(r.Header.Get(contentEncodingHeaderKey))
+			if len(encodings) == 0 {
+				ctx.Next()
+
+				return
+			}
+
+			for _, enc := range encodings {
+				if enc != EncodingGzip && enc != EncodingDeflate {
+					ctx.GetResponseHeaders().Set(acceptEncodingHeaderKey, supported)
+					ctx.StatusText(http.StatusUnsupportedMediaType)
+
+					return
+				}
+			}
+
+			body := &decompressBody{
+				Reader:  r.Body,
+				closers: []io.Closer{r.Body},
+			}
+
+			// The encodings are listed in the order they were applied.
+			for i := len(encodings) - 1; i >= 0; i-- {
+				var (
+					dec io.ReadCloser
+					err error
+				)
+
+				if encodings[i] == EncodingGzip {
+					dec, err = gzip.NewReader(body.Reader)
+				} else {
+					dec, err = zlib.NewReader(body.Reader)
+				}
+
+				if err != nil {
+					body.Close()
+					ctx.StatusText(http.StatusBadRequest)
+
+					return
+				}
+
+				body.Reader = dec
+				body.closers = append(body.closers, dec)
+			}
+
+			r.Body = http.MaxBytesReader(ctx.GetResponseWriter(), body, conf.MaxSize)
+			r.ContentLength = -1
+			r.Header.Del(contentEncodingHeaderKey)
+			r.Header.Del(contentLengthHeaderKey)
+
+			ctx.Next()
+		},
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+// parseContentEncoding returns the encodings of the
+// given header in the order they were applied.
+func parseContentEncoding(header string) []string {
+	var encodings []string
+
+	for _, e := range strings.Split(header, ",") {
+		e = strings.ToLower(strings.TrimSpace(e))
+
+		if e == "" || e == encodingIdentity {
+			continue
+		}
+
+		// x-gzip is an alias of gzip according to RFC 9110.
+		if e == "x-gzip" {
+			e = EncodingGzip
+		}
+
+		encodings = append(encodings, e)
+	}
+
+	return encodings
+}
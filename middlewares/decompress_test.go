@@ -0,0 +1,151 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func compressBody(t *testing.T, encoding string, body []byte) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+
+	if encoding == EncodingGzip {
+		w = gzip.NewWriter(&buf)
+	} else {
+		w = zlib.NewWriter(&buf)
+	}
+
+	w.Write(body)
+	w.Close()
+
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	type testCase struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		maxSize         int64
+
+		expectedStatusCode int
+		expectedBody       string
+		expectedTooLarge   bool
+	}
+
+	var payload = []byte(`{"name":"gopher","tags":["a","b","c"]}`)
+
+	tt := []testCase{
+		{
+			name:               "passes uncompressed body through",
+			body:               payload,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       string(payload),
+		},
+		{
+			name:               "decompresses gzip body",
+			contentEncoding:    "gzip",
+			body:               compressBody(t, EncodingGzip, payload),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       string(payload),
+		},
+		{
+			name:               "decompresses deflate body",
+			contentEncoding:    "deflate",
+			body:               compressBody(t, EncodingDeflate, payload),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       string(payload),
+		},
+		{
+			name:               "decompresses multiple encodings in reverse order",
+			contentEncoding:    "deflate, gzip",
+			body:               compressBody(t, EncodingGzip, compressBody(t, EncodingDeflate, payload)),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       string(payload),
+		},
+		{
+			name:               "responds with 415 for unsupported encoding",
+			contentEncoding:    "br",
+			body:               payload,
+			expectedStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:               "responds with 400 for malformed body",
+			contentEncoding:    "gzip",
+			body:               payload,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "limits the decompressed size",
+			contentEncoding:    "gzip",
+			body:               compressBody(t, EncodingGzip, bytes.Repeat([]byte{'a'}, 1<<20)),
+			maxSize:            1024,
+			expectedStatusCode: http.StatusRequestEntityTooLarge,
+			expectedTooLarge:   true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				readBody string
+				tooLarge bool
+
+				r = gorouter.New()
+			)
+
+			r.RegisterMiddlewares(Decompress(DecompressConfig{MaxSize: tc.maxSize}))
+			r.Post("/api/upload", func(ctx gorouter.Context) {
+				b, err := io.ReadAll(ctx.GetBody())
+
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					tooLarge = true
+					ctx.Status(http.StatusRequestEntityTooLarge)
+
+					return
+				}
+
+				readBody = string(b)
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/upload", bytes.NewReader(tc.body))
+			if tc.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tc.contentEncoding)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if readBody != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, readBody)
+			}
+
+			if tooLarge != tc.expectedTooLarge {
+				t.Errorf("expected too large: %v; got: %v\n", tc.expectedTooLarge, tooLarge)
+			}
+
+			if rec.Code == http.StatusUnsupportedMediaType && !strings.Contains(rec.Header().Get("Accept-Encoding"), "gzip") {
+				t.Errorf("expected Accept-Encoding header; got: %q\n", rec.Header().Get("Accept-Encoding"))
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package middlewares
+
+import (
+	stdcontext "context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultJWKSCacheTTL           time.Duration = time.Hour
+	defaultJWKSMinRefreshInterval time.Duration = time.Minute
+	defaultJWKSClientTimeout      time.Duration = 10 * time.Second
+
+	// The maximum size of a fetched key set.
+	maxJWKSSize int64 = 1 << 20
+)
+
+var (
+	ErrJWKSFetch = errors.New("the key set could not be fetched")
+)
+
+// JWKSConfig is the configuration of a JSON Web Key Set.
+type JWKSConfig struct {
+	// The client fetching the remote key set. By default a client
+	// with 10 seconds timeout, so a hanging source does not block the requests.
+	Client *http.Client
+
+	// How long the keys are cached before they are reloaded. By default one hour.
+	CacheTTL time.Duration
+
+	// The minimum time between two reloads – triggered either by an unknown key
+	// id or by the expired cache –, so the source is not flooded with tokens of
+	// random key ids, nor with the retries while it is failing. Meanwhile the
+	// cached keys are used. By default one minute.
+	MinRefreshInterval time.Duration
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC and OKP
+	X string `json:"x"`
+	Y string `json:"y"`
+
+	// Symmetric
+	K string `json:"k"`
+}
+
+type jwkKey struct {
+	alg string
+	key any
+}
+
+// JWKS is a JSON Web Key Set loaded from a file or a url. The keys are cached,
+// then reloaded periodically or when a token with an unknown key id arrives,
+// so the keys could be rotated at the source without restarting the service.
+type JWKS struct {
+	conf  JWKSConfig
+	fetch func(ctx stdcontext.Context) ([]byte, error)
+
+	// Only one reload is done at a time.
+	refreshMu sync.Mutex
+
+	mu          sync.RWMutex
+	keys        map[string]jwkKey
+	loadedAt    time.Time
+	refreshedAt time.Time
+}
+
+var _ JWTKeyProvider = (*JWKS)(nil)
+
+// NewFileJWKS creates and returns a key set loaded from the given file.
+func NewFileJWKS(path string, conf ...JWKSConfig) (*JWKS, error) {
+	ks := newJWKS(func(_ stdcontext.Context) ([]byte, error) {
+		return os.ReadFile(path)
+	}, conf)
+
+	if err := ks.Refresh(stdcontext.Background()); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// NewRemoteJWKS creates and returns a key set fetched from the
+// given url. The keys are fetched lazily, on the first use.
+func NewRemoteJWKS(url string, conf ...JWKSConfig) *JWKS {
+	var ks *JWKS
+
+	ks = newJWKS(func(ctx stdcontext.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := ks.conf.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrJWKSFetch, err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%w: status code %d", ErrJWKSFetch, res.StatusCode)
+		}
+
+		return io.ReadAll(io.LimitReader(res.Body, maxJWKSSize))
+	}, conf)
+
+	return ks
+}
+
+func newJWKS(fetch func(stdcontext.Context) ([]byte, error), conf []JWKSConfig) *JWKS {
+	var c JWKSConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: defaultJWKSClientTimeout}
+	}
+
+	if c.CacheTTL <= 0 {
+		c.CacheTTL = defaultJWKSCacheTTL
+	}
+
+	if c.MinRefreshInterval <= 0 {
+		c.MinRefreshInterval = defaultJWKSMinRefreshInterval
+	}
+
+	return &JWKS{
+		conf:  c,
+		fetch: fetch,
+		keys:  make(map[string]jwkKey),
+	}
+}
+
+// GetKey returns the key with the given id, which could verify the given algorithm.
+func (ks *JWKS) GetKey(ctx stdcontext.Context, kid string, alg string) (any, error) {
+	ks.mu.RLock()
+	var (
+		k, found    = ks.keys[kid]
+		isStale     = time.Since(ks.loadedAt) > ks.conf.CacheTTL
+		canRetry    = time.Since(ks.refreshedAt) > ks.conf.MinRefreshInterval
+		refreshedAt = ks.refreshedAt
+		loadedAt    = ks.loadedAt
+	)
+	ks.mu.RUnlock()
+
+	// The failing reloads of the stale keys are retried only after the
+	// minimum interval as well, meanwhile the cached keys are used.
+	if (isStale || !found) && canRetry {
+		// If the reload fails, then the cached keys are used.
+		if err := ks.refreshSince(ctx, refreshedAt, loadedAt); err != nil && !found {
+			return nil, err
+		}
+
+		ks.mu.RLock()
+		k, found = ks.keys[kid]
+		ks.mu.RUnlock()
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrJWTKeyNotFound, kid)
+	}
+
+	if k.alg != "" && k.alg != alg {
+		return nil, ErrInvalidJWTKey
+	}
+
+	return k.key, nil
+}
+
+// Refresh reloads the keys from the source.
+func (ks *JWKS) Refresh(ctx stdcontext.Context) error {
+	ks.refreshMu.Lock()
+	defer ks.refreshMu.Unlock()
+
+	return ks.reload(ctx)
+}
+
+// refreshSince reloads the keys from the source, unless an other goroutine
+// has reloaded them since the given times were observed – while this one was
+// waiting –, so the concurrent requests with the same unknown key id trigger
+// only one reload. The reload in flight at the time of the observation counts
+// as well, since it changes the time of the loading.
+func (ks *JWKS) refreshSince(ctx stdcontext.Context, refreshedAt time.Time, loadedAt time.Time) error {
+	ks.refreshMu.Lock()
+	defer ks.refreshMu.Unlock()
+
+	ks.mu.RLock()
+	isRefreshed := !ks.refreshedAt.Equal(refreshedAt) || !ks.loadedAt.Equal(loadedAt)
+	ks.mu.RUnlock()
+
+	if isRefreshed {
+		return nil
+	}
+
+	return ks.reload(ctx)
+}
+
+// reload fetches and parses the keys. The caller must hold refreshMu.
+func (ks *JWKS) reload(ctx stdcontext.Context) error {
+	ks.mu.Lock()
+	ks.refreshedAt = time.Now()
+	ks.mu.Unlock()
+
+	b, err := ks.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.loadedAt = time.Now()
+	ks.mu.Unlock()
+
+	return nil
+}
+
+func parseJWKS(b []byte) (map[string]jwkKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrJWKSFetch, err)
+	}
+
+	keys := make(map[string]jwkKey, len(set.Keys))
+
+	for _, k := range set.Keys {
+		// The keys for encryption are not used.
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// An unsupported key does not invalidate the whole set.
+			continue
+		}
+
+		keys[k.Kid] = jwkKey{alg: k.Alg, key: key}
+	}
+
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := jwtEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := jwtEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidJWTKey
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, ErrInvalidJWTKey
+		}
+
+		x, errX := jwtEncoding.DecodeString(k.X)
+		y, errY := jwtEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != es256KeySize || len(y) != es256KeySize {
+			return nil, ErrInvalidJWTKey
+		}
+
+		// Validating, that the point is on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, ErrInvalidJWTKey
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, ErrInvalidJWTKey
+		}
+
+		x, err := jwtEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidJWTKey
+		}
+
+		return ed25519.PublicKey(x), nil
+
+	case "oct":
+		secret, err := jwtEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, ErrInvalidJWTKey
+		}
+
+		return secret, nil
+	}
+
+	return nil, ErrInvalidJWTKey
+}
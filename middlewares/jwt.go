@@ -0,0 +1,464 @@
+package middlewares
+
+import (
+	stdcontext "context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	// JWTClaimsKey is the key of the verified claims bound to the Context.
+	JWTClaimsKey gorouter.ContextKey = "__jwtClaims__"
+
+	AlgHS256 string = "HS256"
+	AlgRS256 string = "RS256"
+	AlgES256 string = "ES256"
+	AlgEdDSA string = "EdDSA"
+
+	es256KeySize int = 32
+)
+
+var (
+	ErrTokenMalformed        = errors.New("the token is malformed")
+	ErrTokenSignature        = errors.New("the signature of the token is invalid")
+	ErrTokenExpired          = errors.New("the token is expired")
+	ErrTokenNotYetValid      = errors.New("the token is not valid yet")
+	ErrTokenIssuedInFuture   = errors.New("the token is issued in the future")
+	ErrTokenMissingExp       = errors.New("the token has no expiration")
+	ErrTokenIssuer           = errors.New("the issuer of the token is invalid")
+	ErrTokenAudience         = errors.New("the audience of the token is invalid")
+	ErrUnsupportedAlgorithm  = errors.New("the algorithm of the token is not allowed")
+	ErrJWTKeyNotFound        = errors.New("there is no key for the token")
+	ErrInvalidJWTKey         = errors.New("the key does not match the algorithm of the token")
+	ErrMissingJWTKey         = errors.New("either a key or a key provider must be configured")
+	ErrUnsupportedCritHeader = errors.New("the token has critical headers, which are not supported")
+)
+
+var (
+	jwtEncoding = base64.RawURLEncoding
+
+	defaultJWTAlgorithms = []string{AlgHS256, AlgRS256, AlgES256, AlgEdDSA}
+)
+
+// JWTKeyProvider provides the key, which verifies the tokens
+// with the given key id and algorithm, eg. a JWKS.
+type JWTKeyProvider interface {
+	GetKey(ctx stdcontext.Context, kid string, alg string) (any, error)
+}
+
+// JWTConfig is the configuration of the JWT verification.
+type JWTConfig struct {
+	// The key verifying every token. It could be a []byte secret
+	// for HS256, *rsa.PublicKey for RS256, *ecdsa.PublicKey for
+	// ES256 or ed25519.PublicKey for EdDSA.
+	Key any
+
+	// The provider of the keys by the key id of the tokens.
+	// It is used, if the Key is not set.
+	KeyProvider JWTKeyProvider
+
+	// The allowed algorithms. By default all the supported are allowed,
+	// however the type of the key must always match the algorithm.
+	Algorithms []string
+
+	// If it is set, then the iss claim must be equal to it.
+	Issuer string
+
+	// If it is set, then the aud claim must contain at least one of them.
+	Audience []string
+
+	// The tolerance of the time based validations.
+	ClockSkew time.Duration
+
+	// Whether the tokens without exp claim are rejected.
+	RequireExpiration bool
+
+	// The function creating the principal from the verified claims. By default
+	// the subject is the sub, the roles are the roles, while the permissions are
+	// the space separated scope – or the permissions – claim.
+	PrincipalFunc func(*JWTClaims) *gorouter.Principal
+
+	// The configuration of the token extraction and the challenges.
+	Bearer BearerAuthConfig
+
+	// Returns the current time. Used for testing.
+	now func() time.Time
+}
+
+// JWTClaims are the verified claims of a token.
+type JWTClaims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+
+	// All the claims of the token – including the registered ones.
+	Raw map[string]any
+
+	payload []byte
+}
+
+// Decode decodes the payload of the token into the given
+// value, so custom claims could be accessed in a typed way.
+func (c *JWTClaims) Decode(v any) error {
+	return json.Unmarshal(c.payload, v)
+}
+
+// GetJWTClaims returns the verified claims of the token
+// bound to the Context by the JWT middleware – if there is any.
+func GetJWTClaims(ctx gorouter.Context) (*JWTClaims, bool) {
+	c, ok := ctx.GetBindedValue(JWTClaimsKey).(*JWTClaims)
+	if !ok || c == nil {
+		return nil, false
+	}
+	return c, true
+}
+
+// JWTVerifier verifies the signature and the claims of tokens.
+type JWTVerifier struct {
+	conf JWTConfig
+}
+
+// NewJWTVerifier creates and returns a new verifier with the given configuration.
+func NewJWTVerifier(conf JWTConfig) (*JWTVerifier, error) {
+	if conf.Key == nil && conf.KeyProvider == nil {
+		return nil, ErrMissingJWTKey
+	}
+
+	if len(conf.Algorithms) == 0 {
+		conf.Algorithms = defaultJWTAlgorithms
+	}
+
+	if conf.now == nil {
+		conf.now = time.Now
+	}
+
+	if conf.PrincipalFunc == nil {
+		conf.PrincipalFunc = defaultPrincipalFunc
+	}
+
+	return &JWTVerifier{conf: conf}, nil
+}
+
+// JWT creates and returns a middleware, which authenticates the requests
+// by a JWT sent as a Bearer token. The verified claims are bound to the
+// Context – accessed by GetJWTClaims –, alongside the principal.
+// It panics, if the configuration is invalid.
+func JWT(conf JWTConfig) gorouter.Middleware {
+	v, err := NewJWTVerifier(conf)
+	if err != nil {
+		panic(err)
+	}
+
+	return BearerAuth(
+		func(ctx gorouter.Context, token string) (*gorouter.Principal, error) {
+			reqCtx := stdcontext.Background()
+			if r := ctx.GetRequest(); r != nil {
+				reqCtx = r.Context()
+			}
+
+			claims, err := v.Verify(reqCtx, token)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx.BindValue(JWTClaimsKey, claims)
+
+			return v.conf.PrincipalFunc(claims), nil
+		},
+		conf.Bearer,
+	)
+}
+
+type jwtHeader struct {
+	Alg  string   `json:"alg"`
+	Kid  string   `json:"kid"`
+	Typ  string   `json:"typ"`
+	Crit []string `json:"crit"`
+}
+
+type registeredClaims struct {
+	Iss string   `json:"iss"`
+	Sub string   `json:"sub"`
+	Aud audience `json:"aud"`
+	Exp *float64 `json:"exp"`
+	Nbf *float64 `json:"nbf"`
+	Iat *float64 `json:"iat"`
+	Jti string   `json:"jti"`
+}
+
+// audience could be either a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+
+	*a = multiple
+
+	return nil
+}
+
+// Verify verifies the given token, then returns its claims.
+func (v *JWTVerifier) Verify(ctx stdcontext.Context, token string) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	if len(header.Crit) > 0 {
+		return nil, ErrUnsupportedCritHeader
+	}
+
+	if !slices.Contains(v.conf.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, header.Alg)
+	}
+
+	key := v.conf.Key
+	if key == nil {
+		k, err := v.conf.KeyProvider.GetKey(ctx, header.Kid, header.Alg)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	}
+
+	signature, err := jwtEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	payload, err := jwtEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	claims, err := parseClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *JWTVerifier) validateClaims(c *JWTClaims) error {
+	var (
+		now  = v.conf.now()
+		skew = v.conf.ClockSkew
+	)
+
+	if c.ExpiresAt.IsZero() {
+		if v.conf.RequireExpiration {
+			return ErrTokenMissingExp
+		}
+	} else if !now.Before(c.ExpiresAt.Add(skew)) {
+		return ErrTokenExpired
+	}
+
+	if !c.NotBefore.IsZero() && now.Before(c.NotBefore.Add(-skew)) {
+		return ErrTokenNotYetValid
+	}
+
+	if !c.IssuedAt.IsZero() && now.Before(c.IssuedAt.Add(-skew)) {
+		return ErrTokenIssuedInFuture
+	}
+
+	if v.conf.Issuer != "" && c.Issuer != v.conf.Issuer {
+		return ErrTokenIssuer
+	}
+
+	if len(v.conf.Audience) > 0 && !slices.ContainsFunc(c.Audience, func(aud string) bool {
+		return slices.Contains(v.conf.Audience, aud)
+	}) {
+		return ErrTokenAudience
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := jwtEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrTokenMalformed
+	}
+
+	return nil
+}
+
+func parseClaims(payload []byte) (*JWTClaims, error) {
+	var (
+		rc  registeredClaims
+		raw map[string]any
+	)
+
+	if err := json.Unmarshal(payload, &rc); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	return &JWTClaims{
+		Issuer:    rc.Iss,
+		Subject:   rc.Sub,
+		Audience:  rc.Aud,
+		ExpiresAt: numericDate(rc.Exp),
+		NotBefore: numericDate(rc.Nbf),
+		IssuedAt:  numericDate(rc.Iat),
+		ID:        rc.Jti,
+		Raw:       raw,
+		payload:   payload,
+	}, nil
+}
+
+// numericDate converts the given seconds since the epoch to time.
+func numericDate(v *float64) time.Time {
+	if v == nil {
+		return time.Time{}
+	}
+
+	sec, frac := math.Modf(*v)
+
+	return time.Unix(int64(sec), int64(frac*float64(time.Second)))
+}
+
+func verifySignature(alg string, key any, signingInput string, signature []byte) error {
+	switch alg {
+	case AlgHS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrInvalidJWTKey
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrTokenSignature
+		}
+
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidJWTKey
+		}
+
+		hash := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], signature); err != nil {
+			return ErrTokenSignature
+		}
+
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve.Params().BitSize != 256 {
+			return ErrInvalidJWTKey
+		}
+
+		// The signature is the concatenation of r and s.
+		if len(signature) != 2*es256KeySize {
+			return ErrTokenSignature
+		}
+
+		var (
+			r    = new(big.Int).SetBytes(signature[:es256KeySize])
+			s    = new(big.Int).SetBytes(signature[es256KeySize:])
+			hash = sha256.Sum256([]byte(signingInput))
+		)
+
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return ErrTokenSignature
+		}
+
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrInvalidJWTKey
+		}
+
+		if !ed25519.Verify(pub, []byte(signingInput), signature) {
+			return ErrTokenSignature
+		}
+
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+func defaultPrincipalFunc(c *JWTClaims) *gorouter.Principal {
+	p := &gorouter.Principal{
+		Subject: c.Subject,
+		Roles:   stringsClaim(c.Raw["roles"]),
+		Claims:  c.Raw,
+	}
+
+	if scope, ok := c.Raw["scope"].(string); ok {
+		p.Permissions = strings.Fields(scope)
+	} else {
+		p.Permissions = stringsClaim(c.Raw["permissions"])
+	}
+
+	return p
+}
+
+// stringsClaim returns the strings of the given array claim.
+func stringsClaim(v any) []string {
+	values, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+
+	res := make([]string, 0, len(values))
+	for _, e := range values {
+		if s, ok := e.(string); ok {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,555 @@
+package middlewares
+
+import (
+	stdcontext "context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+type testKeys struct {
+	secret []byte
+	rsa    *rsa.PrivateKey
+	ec     *ecdsa.PrivateKey
+	ed     ed25519.PrivateKey
+}
+
+func generateTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testKeys{
+		secret: []byte("a-very-secret-key-for-hmac-signing"),
+		rsa:    rsaKey,
+		ec:     ecKey,
+		ed:     edKey,
+	}
+}
+
+func signTestToken(t *testing.T, alg string, kid string, key any, claims map[string]any) string {
+	t.Helper()
+
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+
+	var (
+		signingInput = jwtEncoding.EncodeToString(h) + "." + jwtEncoding.EncodeToString(c)
+		hash         = sha256.Sum256([]byte(signingInput))
+
+		signature []byte
+		err       error
+	)
+
+	switch alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case AlgRS256:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, hash[:])
+	case AlgES256:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), hash[:])
+		if err == nil {
+			signature = make([]byte, 2*es256KeySize)
+			r.FillBytes(signature[:es256KeySize])
+			s.FillBytes(signature[es256KeySize:])
+		}
+	case AlgEdDSA:
+		signature = ed25519.Sign(key.(ed25519.PrivateKey), []byte(signingInput))
+	default:
+		signature = []byte("signature")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signingInput + "." + jwtEncoding.EncodeToString(signature)
+}
+
+func testJWKS(keys *testKeys, ids map[string]string) []byte {
+	var (
+		ecPub = keys.ec.Public().(*ecdsa.PublicKey)
+		edPub = keys.ed.Public().(ed25519.PublicKey)
+
+		set = []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": ids[AlgRS256],
+				"alg": AlgRS256,
+				"use": "sig",
+				"n":   jwtEncoding.EncodeToString(keys.rsa.N.Bytes()),
+				"e":   jwtEncoding.EncodeToString(big.NewInt(int64(keys.rsa.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": ids[AlgES256],
+				"crv": "P-256",
+				"x":   jwtEncoding.EncodeToString(ecPub.X.FillBytes(make([]byte, es256KeySize))),
+				"y":   jwtEncoding.EncodeToString(ecPub.Y.FillBytes(make([]byte, es256KeySize))),
+			},
+			{
+				"kty": "OKP",
+				"kid": ids[AlgEdDSA],
+				"crv": "Ed25519",
+				"x":   jwtEncoding.EncodeToString(edPub),
+			},
+			{
+				"kty": "RSA",
+				"kid": "encryption",
+				"use": "enc",
+				"n":   jwtEncoding.EncodeToString(keys.rsa.N.Bytes()),
+				"e":   "AQAB",
+			},
+		}
+	)
+
+	b, _ := json.Marshal(map[string]any{"keys": set})
+
+	return b
+}
+
+func TestJWTVerify(t *testing.T) {
+	type testCase struct {
+		name   string
+		conf   JWTConfig
+		token  func() string
+		hasErr error
+	}
+
+	var (
+		keys = generateTestKeys(t)
+		now  = time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+		validClaims = map[string]any{
+			"sub":   "user-1",
+			"iss":   "https://issuer.test",
+			"aud":   []string{"api", "web"},
+			"iat":   now.Add(-time.Minute).Unix(),
+			"nbf":   now.Add(-time.Minute).Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"scope": "read write",
+		}
+
+		withClaims = func(overrides map[string]any) map[string]any {
+			c := make(map[string]any)
+			for k, v := range validClaims {
+				c[k] = v
+			}
+			for k, v := range overrides {
+				if v == nil {
+					delete(c, k)
+					continue
+				}
+				c[k] = v
+			}
+			return c
+		}
+
+		clock = func() time.Time { return now }
+	)
+
+	tt := []testCase{
+		{
+			name:  "valid HS256",
+			conf:  JWTConfig{Key: keys.secret, Issuer: "https://issuer.test", Audience: []string{"api"}},
+			token: func() string { return signTestToken(t, AlgHS256, "", keys.secret, validClaims) },
+		},
+		{
+			name:  "valid RS256",
+			conf:  JWTConfig{Key: &keys.rsa.PublicKey},
+			token: func() string { return signTestToken(t, AlgRS256, "", keys.rsa, validClaims) },
+		},
+		{
+			name:  "valid ES256",
+			conf:  JWTConfig{Key: keys.ec.Public()},
+			token: func() string { return signTestToken(t, AlgES256, "", keys.ec, validClaims) },
+		},
+		{
+			name:  "valid EdDSA",
+			conf:  JWTConfig{Key: keys.ed.Public()},
+			token: func() string { return signTestToken(t, AlgEdDSA, "", keys.ed, validClaims) },
+		},
+		{
+			name:   "rejects invalid signature",
+			conf:   JWTConfig{Key: keys.secret},
+			token:  func() string { return signTestToken(t, AlgHS256, "", []byte("an-other-secret"), validClaims) },
+			hasErr: ErrTokenSignature,
+		},
+		{
+			name:   "rejects none algorithm",
+			conf:   JWTConfig{Key: keys.secret},
+			token:  func() string { return signTestToken(t, "none", "", nil, validClaims) },
+			hasErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			name:   "rejects not allowed algorithm",
+			conf:   JWTConfig{Key: keys.secret, Algorithms: []string{AlgRS256}},
+			token:  func() string { return signTestToken(t, AlgHS256, "", keys.secret, validClaims) },
+			hasErr: ErrUnsupportedAlgorithm,
+		},
+		{
+			name: "rejects key confusion",
+			conf: JWTConfig{Key: &keys.rsa.PublicKey},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.rsa.PublicKey.N.Bytes(), validClaims)
+			},
+			hasErr: ErrInvalidJWTKey,
+		},
+		{
+			name: "rejects expired token",
+			conf: JWTConfig{Key: keys.secret},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.secret, withClaims(map[string]any{"exp": now.Unix()}))
+			},
+			hasErr: ErrTokenExpired,
+		},
+		{
+			name: "accepts expired token within clock skew",
+			conf: JWTConfig{Key: keys.secret, ClockSkew: time.Minute},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.secret, withClaims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}))
+			},
+		},
+		{
+			name: "rejects not yet valid token",
+			conf: JWTConfig{Key: keys.secret, ClockSkew: time.Minute},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.secret, withClaims(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()}))
+			},
+			hasErr: ErrTokenNotYetValid,
+		},
+		{
+			name: "rejects token issued in the future",
+			conf: JWTConfig{Key: keys.secret},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.secret, withClaims(map[string]any{"iat": now.Add(time.Hour).Unix()}))
+			},
+			hasErr: ErrTokenIssuedInFuture,
+		},
+		{
+			name: "rejects token without exp if required",
+			conf: JWTConfig{Key: keys.secret, RequireExpiration: true},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.secret, withClaims(map[string]any{"exp": nil}))
+			},
+			hasErr: ErrTokenMissingExp,
+		},
+		{
+			name:   "rejects invalid issuer",
+			conf:   JWTConfig{Key: keys.secret, Issuer: "https://other.test"},
+			token:  func() string { return signTestToken(t, AlgHS256, "", keys.secret, validClaims) },
+			hasErr: ErrTokenIssuer,
+		},
+		{
+			name: "rejects invalid audience",
+			conf: JWTConfig{Key: keys.secret, Audience: []string{"admin"}},
+			token: func() string {
+				return signTestToken(t, AlgHS256, "", keys.secret, withClaims(map[string]any{"aud": "api"}))
+			},
+			hasErr: ErrTokenAudience,
+		},
+		{
+			name:   "rejects malformed token",
+			conf:   JWTConfig{Key: keys.secret},
+			token:  func() string { return "not.a-token" },
+			hasErr: ErrTokenMalformed,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.conf.now = clock
+
+			v, err := NewJWTVerifier(tc.conf)
+			if err != nil {
+				t.Fatalf("expected no error; got: %v\n", err)
+			}
+
+			claims, err := v.Verify(stdcontext.Background(), tc.token())
+
+			if !errors.Is(err, tc.hasErr) {
+				t.Fatalf("expected error: %v; got: %v\n", tc.hasErr, err)
+			}
+
+			if tc.hasErr == nil && (claims.Subject != "user-1" || len(claims.Audience) == 0) {
+				t.Errorf("expected claims of user-1; got: %+v\n", claims)
+			}
+		})
+	}
+}
+
+func TestRemoteJWKS(t *testing.T) {
+	var (
+		oldKeys = generateTestKeys(t)
+		newKeys = generateTestKeys(t)
+
+		mu      sync.Mutex
+		current = testJWKS(oldKeys, map[string]string{AlgRS256: "rsa-1", AlgES256: "ec-1", AlgEdDSA: "ed-1"})
+		fetches int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fetches++
+		w.Write(current)
+	}))
+	defer srv.Close()
+
+	ks := NewRemoteJWKS(srv.URL, JWKSConfig{Client: srv.Client(), MinRefreshInterval: time.Nanosecond})
+
+	v, err := NewJWTVerifier(JWTConfig{KeyProvider: ks})
+	if err != nil {
+		t.Fatalf("expected no error; got: %v\n", err)
+	}
+
+	claims := map[string]any{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for alg, key := range map[string]any{AlgRS256: oldKeys.rsa, AlgES256: oldKeys.ec, AlgEdDSA: oldKeys.ed} {
+		kid := map[string]string{AlgRS256: "rsa-1", AlgES256: "ec-1", AlgEdDSA: "ed-1"}[alg]
+
+		if _, err := v.Verify(stdcontext.Background(), signTestToken(t, alg, kid, key, claims)); err != nil {
+			t.Errorf("expected valid %s token; got error: %v\n", alg, err)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("expected the key set to be fetched once; got: %d\n", fetches)
+	}
+
+	// The keys are rotated at the source.
+	mu.Lock()
+	current = testJWKS(newKeys, map[string]string{AlgRS256: "rsa-2", AlgES256: "ec-2", AlgEdDSA: "ed-2"})
+	mu.Unlock()
+
+	if _, err := v.Verify(stdcontext.Background(), signTestToken(t, AlgRS256, "rsa-2", newKeys.rsa, claims)); err != nil {
+		t.Errorf("expected valid token of rotated key; got error: %v\n", err)
+	}
+
+	if fetches != 2 {
+		t.Errorf("expected the key set to be fetched again; got: %d\n", fetches)
+	}
+
+	if _, err := v.Verify(stdcontext.Background(), signTestToken(t, AlgRS256, "rsa-2", oldKeys.rsa, claims)); !errors.Is(err, ErrTokenSignature) {
+		t.Errorf("expected signature error; got: %v\n", err)
+	}
+
+	if _, err := v.Verify(stdcontext.Background(), signTestToken(t, AlgRS256, "encryption", newKeys.rsa, claims)); !errors.Is(err, ErrJWTKeyNotFound) {
+		t.Errorf("expected key not found error for encryption key; got: %v\n", err)
+	}
+}
+
+func TestJWKSConcurrentRefresh(t *testing.T) {
+	var (
+		keys = generateTestKeys(t)
+		set  = testJWKS(keys, map[string]string{AlgRS256: "rsa-1"})
+
+		mu      sync.Mutex
+		fetches int
+	)
+
+	ks := newJWKS(func(stdcontext.Context) ([]byte, error) {
+		mu.Lock()
+		fetches++
+		mu.Unlock()
+
+		// Keeping the reload in flight, while the other requests arrive.
+		time.Sleep(20 * time.Millisecond)
+
+		return set, nil
+	}, nil)
+
+	var wg sync.WaitGroup
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, err := ks.GetKey(stdcontext.Background(), "unknown", AlgRS256); !errors.Is(err, ErrJWTKeyNotFound) {
+				t.Errorf("expected error: %v; got: %v\n", ErrJWTKeyNotFound, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("expected the key set to be fetched once; got: %d\n", fetches)
+	}
+}
+
+func TestJWKSStaleReload(t *testing.T) {
+	var (
+		keys = generateTestKeys(t)
+		set  = testJWKS(keys, map[string]string{AlgRS256: "rsa-1"})
+
+		fetches int
+		failing bool
+	)
+
+	ks := newJWKS(func(stdcontext.Context) ([]byte, error) {
+		fetches++
+
+		if failing {
+			return nil, ErrJWKSFetch
+		}
+
+		return set, nil
+	}, []JWKSConfig{{CacheTTL: time.Nanosecond, MinRefreshInterval: time.Hour}})
+
+	if _, err := ks.GetKey(stdcontext.Background(), "rsa-1", AlgRS256); err != nil {
+		t.Fatalf("expected key; got error: %v\n", err)
+	}
+
+	// The source is failing, when the minimum interval is over.
+	failing = true
+
+	ks.mu.Lock()
+	ks.refreshedAt = time.Now().Add(-2 * time.Hour)
+	ks.mu.Unlock()
+
+	for range 10 {
+		if _, err := ks.GetKey(stdcontext.Background(), "rsa-1", AlgRS256); err != nil {
+			t.Errorf("expected cached key; got error: %v\n", err)
+		}
+	}
+
+	if fetches != 2 {
+		t.Errorf("expected the key set to be fetched twice; got: %d\n", fetches)
+	}
+}
+
+func TestFileJWKS(t *testing.T) {
+	var (
+		keys = generateTestKeys(t)
+		path = filepath.Join(t.TempDir(), "jwks.json")
+	)
+
+	if err := os.WriteFile(path, testJWKS(keys, map[string]string{AlgRS256: "rsa", AlgES256: "ec", AlgEdDSA: "ed"}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ks, err := NewFileJWKS(path)
+	if err != nil {
+		t.Fatalf("expected no error; got: %v\n", err)
+	}
+
+	if _, err := ks.GetKey(stdcontext.Background(), "ec", AlgES256); err != nil {
+		t.Errorf("expected key; got error: %v\n", err)
+	}
+
+	// The algorithm of the key must match the token.
+	if _, err := ks.GetKey(stdcontext.Background(), "rsa", AlgHS256); !errors.Is(err, ErrInvalidJWTKey) {
+		t.Errorf("expected invalid key error; got: %v\n", err)
+	}
+
+	if _, err := NewFileJWKS(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing file\n")
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	type customClaims struct {
+		TenantId string `json:"tenant_id"`
+	}
+
+	var (
+		keys = generateTestKeys(t)
+
+		principal *gorouter.Principal
+		custom    customClaims
+
+		r = gorouter.New()
+	)
+
+	r.RegisterMiddlewares(JWT(JWTConfig{Key: keys.ed.Public(), Bearer: BearerAuthConfig{Realm: "api"}}))
+	r.Get("/api/me", func(ctx gorouter.Context) {
+		principal, _ = gorouter.GetPrincipal(ctx)
+
+		if claims, ok := GetJWTClaims(ctx); ok {
+			claims.Decode(&custom)
+		}
+	})
+
+	token := signTestToken(t, AlgEdDSA, "", keys.ed, map[string]any{
+		"sub":       "user-1",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"roles":     []string{"admin"},
+		"scope":     "read write",
+		"tenant_id": "acme",
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code: %d; got: %d\n", http.StatusOK, rec.Code)
+	}
+
+	if principal == nil || principal.Subject != "user-1" || !principal.HasRole("admin") || !principal.HasPermission("write") {
+		t.Errorf("expected principal of user-1; got: %+v\n", principal)
+	}
+
+	if custom.TenantId != "acme" {
+		t.Errorf("expected tenant id: %q; got: %q\n", "acme", custom.TenantId)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token+"x")
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusUnauthorized, rec.Code)
+	}
+
+	expected := `Bearer realm="api", error="invalid_token", error_description="the access token is invalid"`
+	if got := rec.Header().Get("WWW-Authenticate"); got != expected {
+		t.Errorf("expected challenge: %s; got: %s\n", expected, got)
+	}
+}
@@ -42,14 +42,21 @@ func Logger(w ...io.Writer) gorouter.Middleware {
 				timeValue = elapsedTime.Milliseconds()
 			}
 
-			l.Info("incoming request",
+			attrs := []any{
 				"id", i.Id,
 				"method", i.Method,
 				"url", i.Url,
 				"status", i.StatusCode,
 				"response_bytes", i.WrittenBytes,
 				measurementUnit, timeValue,
-			)
+			}
+
+			// The id bound by the RequestID middleware.
+			if requestID := gorouter.GetRequestID(ctx); requestID != "" {
+				attrs = append(attrs, "request_id", requestID)
+			}
+
+			l.Info("incoming request", attrs...)
 
 			ctx.Next()
 		},
@@ -0,0 +1,318 @@
+package middlewares
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	// The content type of the Prometheus text exposition format.
+	metricsContentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+	// The route label of the requests, which do not match any registered route.
+	unmatchedRouteLabel string = "unmatched"
+
+	// The method label of the non-standard methods.
+	otherMethodLabel string = "OTHER"
+)
+
+var (
+	// DefaultDurationBuckets are the upper bounds of the buckets of the request
+	// duration histogram in seconds – the same as of the Prometheus client.
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultSizeBuckets are the upper bounds of the buckets
+	// of the response size histogram in bytes.
+	DefaultSizeBuckets = []float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}
+)
+
+var standardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// MetricsConfig is the configuration of the metrics.
+type MetricsConfig struct {
+	// The prefix of the names of the metrics, eg. myapp results
+	// in myapp_http_requests_total. By default there is none.
+	Namespace string
+
+	// The upper bounds of the buckets of the request duration histogram in
+	// seconds and of the response size histogram in bytes. By default
+	// DefaultDurationBuckets and DefaultSizeBuckets are used.
+	DurationBuckets []float64
+	SizeBuckets     []float64
+
+	// If it returns true, then the request is not recorded, eg. the scrapes.
+	Skip func(ctx gorouter.Context) bool
+}
+
+type metricsLabels struct {
+	method string
+	route  string
+	status string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+}
+
+type metricsSeries struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+// Metrics records the count, the duration and the response size of the
+// requests labeled by the method, the class of the status code and the
+// registered url of the route, and the count of the requests in flight.
+// The metrics are served in the Prometheus text exposition format.
+type Metrics struct {
+	conf MetricsConfig
+
+	inFlight atomic.Int64
+
+	mu     sync.Mutex
+	series map[metricsLabels]*metricsSeries
+}
+
+var _ http.Handler = (*Metrics)(nil)
+
+// NewMetrics creates and returns new metrics. The requests are recorded
+// by the middleware returned by Middleware, and the metrics are served
+// by the handler returned by Handler.
+func NewMetrics(conf ...MetricsConfig) *Metrics {
+	var c MetricsConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if len(c.DurationBuckets) == 0 {
+		c.DurationBuckets = DefaultDurationBuckets
+	}
+
+	if len(c.SizeBuckets) == 0 {
+		c.SizeBuckets = DefaultSizeBuckets
+	}
+
+	c.DurationBuckets = sortBuckets(c.DurationBuckets)
+	c.SizeBuckets = sortBuckets(c.SizeBuckets)
+
+	if c.Namespace != "" && !strings.HasSuffix(c.Namespace, "_") {
+		c.Namespace += "_"
+	}
+
+	return &Metrics{
+		conf:   c,
+		series: make(map[metricsLabels]*metricsSeries),
+	}
+}
+
+// Middleware returns a middleware, which records the metrics of the requests.
+// It should be registered as the first global middleware, so the duration
+// covers the whole chain. The request is recorded after the response is written.
+func (m *Metrics) Middleware() gorouter.Middleware {
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		if m.conf.Skip != nil && m.conf.Skip(ctx) {
+			ctx.Next()
+
+			return
+		}
+
+		m.inFlight.Add(1)
+
+		ctx.Defer(func() {
+			m.inFlight.Add(-1)
+			m.observe(ctx.GetInfo(), ctx.GetRegisteredUrl())
+		})
+
+		ctx.Next()
+	})
+}
+
+// Handler returns a handler, which serves the metrics, eg.:
+// r.Get("/metrics", metrics.Handler()).
+func (m *Metrics) Handler() gorouter.HandlerFunc {
+	return func(ctx gorouter.Context) {
+		var b bytes.Buffer
+		m.write(&b)
+
+		ctx.GetResponseHeaders().Set("Content-Type", metricsContentType)
+		ctx.Status(http.StatusOK)
+		ctx.Copy(&b)
+	}
+}
+
+// ServeHTTP serves the metrics, so they could be served by
+// a separate listener, eg. which is not publicly available.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	var b bytes.Buffer
+	m.write(&b)
+
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+func (m *Metrics) observe(info gorouter.ContextInfo, route string) {
+	labels := metricsLabels{
+		method: getMethodLabel(info.Method),
+		route:  route,
+		status: getStatusLabel(info.StatusCode),
+	}
+
+	if labels.route == "" {
+		labels.route = unmatchedRouteLabel
+	}
+
+	var (
+		duration = time.Since(info.StartTime).Seconds()
+		size     = float64(info.WrittenBytes)
+	)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, exists := m.series[labels]
+	if !exists {
+		s = &metricsSeries{
+			duration: histogram{counts: make([]uint64, len(m.conf.DurationBuckets))},
+			size:     histogram{counts: make([]uint64, len(m.conf.SizeBuckets))},
+		}
+		m.series[labels] = s
+	}
+
+	s.count++
+	s.duration.observe(m.conf.DurationBuckets, duration)
+	s.size.observe(m.conf.SizeBuckets, size)
+}
+
+// observe increments the count of the first bucket, which the value fits in.
+// The counts are made cumulative when they are written.
+func (h *histogram) observe(buckets []float64, v float64) {
+	h.sum += v
+
+	if i, _ := slices.BinarySearch(buckets, v); i < len(buckets) {
+		h.counts[i]++
+	}
+}
+
+// write writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) write(b *bytes.Buffer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := make([]metricsLabels, 0, len(m.series))
+	for l := range m.series {
+		labels = append(labels, l)
+	}
+
+	// The series are sorted, so the output is stable.
+	slices.SortFunc(labels, func(a, b metricsLabels) int {
+		return strings.Compare(a.route+" "+a.method+" "+a.status, b.route+" "+b.method+" "+b.status)
+	})
+
+	var (
+		ns = m.conf.Namespace
+
+		requests = ns + "http_requests_total"
+		duration = ns + "http_request_duration_seconds"
+		size     = ns + "http_response_size_bytes"
+		inFlight = ns + "http_requests_in_flight"
+	)
+
+	writeMetricHeader(b, requests, "counter", "The count of the handled HTTP requests.")
+	for _, l := range labels {
+		fmt.Fprintf(b, "%s{%s} %d\n", requests, l.String(), m.series[l].count)
+	}
+
+	writeMetricHeader(b, duration, "histogram", "The duration of the HTTP requests in seconds.")
+	for _, l := range labels {
+		s := m.series[l]
+		writeHistogram(b, duration, l.String(), m.conf.DurationBuckets, &s.duration, s.count)
+	}
+
+	writeMetricHeader(b, size, "histogram", "The size of the HTTP responses in bytes.")
+	for _, l := range labels {
+		s := m.series[l]
+		writeHistogram(b, size, l.String(), m.conf.SizeBuckets, &s.size, s.count)
+	}
+
+	writeMetricHeader(b, inFlight, "gauge", "The count of the HTTP requests being handled.")
+	fmt.Fprintf(b, "%s %d\n", inFlight, m.inFlight.Load())
+}
+
+func writeMetricHeader(b *bytes.Buffer, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeHistogram(b *bytes.Buffer, name, labels string, buckets []float64, h *histogram, count uint64) {
+	var cumulative uint64
+
+	for i, le := range buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(le), cumulative)
+	}
+
+	fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, count)
+}
+
+func (l metricsLabels) String() string {
+	return fmt.Sprintf(
+		"method=\"%s\",route=\"%s\",status=\"%s\"",
+		escapeLabelValue(l.method), escapeLabelValue(l.route), escapeLabelValue(l.status),
+	)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// getMethodLabel returns the method, unless it is non-standard,
+// so the clients could not blow up the count of the series.
+func getMethodLabel(method string) string {
+	if slices.Contains(standardMethods, method) {
+		return method
+	}
+	return otherMethodLabel
+}
+
+// getStatusLabel returns the class of the status code, eg. 2xx.
+func getStatusLabel(statusCode int) string {
+	if statusCode < 100 || statusCode > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
+func sortBuckets(buckets []float64) []float64 {
+	b := slices.Clone(buckets)
+	slices.Sort(b)
+
+	return slices.Compact(b)
+}
@@ -0,0 +1,192 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestMetrics(t *testing.T) {
+	var (
+		r = gorouter.New()
+		m = NewMetrics(MetricsConfig{
+			Namespace:       "app",
+			DurationBuckets: []float64{1, 0.5},
+			SizeBuckets:     []float64{10, 100},
+			Skip: func(ctx gorouter.Context) bool {
+				return ctx.GetRegisteredUrl() == "/metrics"
+			},
+		})
+	)
+
+	r.RegisterMiddlewares(m.Middleware())
+
+	r.Get("/users/{id}", func(ctx gorouter.Context) {
+		ctx.Render(http.StatusOK, &gorouter.DefaultResponse{Data: []byte("hello world")})
+	})
+	r.Post("/users", func(ctx gorouter.Context) {
+		ctx.Status(http.StatusInternalServerError)
+	})
+	r.Get("/metrics", m.Handler())
+
+	requests := []struct {
+		method string
+		url    string
+	}{
+		{http.MethodGet, "/users/1"},
+		{http.MethodGet, "/users/2"},
+		{http.MethodPost, "/users"},
+		{http.MethodGet, "/not-found"},
+		{"PROPFIND", "/users/1"},
+	}
+
+	for _, req := range requests {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.url, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if expected, got := metricsContentType, rec.Header().Get("Content-Type"); got != expected {
+		t.Errorf("expected content type: %s; got: %s\n", expected, got)
+	}
+
+	body := rec.Body.String()
+
+	expectedLines := []string{
+		"# TYPE app_http_requests_total counter",
+		`app_http_requests_total{method="GET",route="/users/{id}",status="2xx"} 2`,
+		`app_http_requests_total{method="POST",route="/users",status="5xx"} 1`,
+		`app_http_requests_total{method="GET",route="unmatched",status="4xx"} 1`,
+		`app_http_requests_total{method="OTHER",route="unmatched",status="4xx"} 1`,
+		"# TYPE app_http_request_duration_seconds histogram",
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="0.5"} 2`,
+		`app_http_request_duration_seconds_bucket{method="GET",route="/users/{id}",status="2xx",le="+Inf"} 2`,
+		`app_http_request_duration_seconds_count{method="GET",route="/users/{id}",status="2xx"} 2`,
+		"# TYPE app_http_response_size_bytes histogram",
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/{id}",status="2xx",le="10"} 0`,
+		`app_http_response_size_bytes_bucket{method="GET",route="/users/{id}",status="2xx",le="100"} 2`,
+		`app_http_response_size_bytes_sum{method="GET",route="/users/{id}",status="2xx"} 22`,
+		`app_http_response_size_bytes_bucket{method="POST",route="/users",status="5xx",le="10"} 1`,
+		"# TYPE app_http_requests_in_flight gauge",
+		"app_http_requests_in_flight 0",
+	}
+
+	for _, line := range expectedLines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line: %s; got body:\n%s\n", line, body)
+		}
+	}
+
+	if strings.Contains(body, `route="/metrics"`) {
+		t.Errorf("expected the skipped route not to be recorded; got body:\n%s\n", body)
+	}
+}
+
+func TestMetricsTimeout(t *testing.T) {
+	var (
+		r = gorouter.New()
+		m = NewMetrics(MetricsConfig{})
+
+		release = make(chan struct{})
+	)
+
+	r.RegisterMiddlewares(m.Middleware())
+
+	r.Get("/slow", func(ctx gorouter.Context) {
+		<-release
+
+		ctx.Render(http.StatusOK, &gorouter.DefaultResponse{Data: []byte("too late")})
+	}).Timeout(10 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status code: %d; got: %d\n", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	// The request is recorded, once the handler returns.
+	close(release)
+
+	var (
+		expected = `http_requests_total{method="GET",route="/slow",status="5xx"} 1` + "\n"
+		deadline = time.Now().Add(time.Second)
+
+		body string
+	)
+
+	for time.Now().Before(deadline) {
+		var b bytes.Buffer
+		m.write(&b)
+
+		if body = b.String(); strings.Contains(body, `route="/slow"`) {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if !strings.Contains(body, expected) {
+		t.Errorf("expected line: %s; got body:\n%s\n", expected, body)
+	}
+}
+
+func TestMetricsLabels(t *testing.T) {
+	type testCase struct {
+		name   string
+		labels metricsLabels
+
+		expected string
+	}
+
+	tt := []testCase{
+		{
+			name:     "writes the labels",
+			labels:   metricsLabels{method: "GET", route: "/users/{id}", status: "2xx"},
+			expected: `method="GET",route="/users/{id}",status="2xx"`,
+		},
+		{
+			name:     "escapes the label values",
+			labels:   metricsLabels{method: "GET", route: "/a\"b\\c\nd", status: "2xx"},
+			expected: `method="GET",route="/a\"b\\c\nd",status="2xx"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.labels.String(); got != tc.expected {
+				t.Errorf("expected labels: %s; got: %s\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetStatusLabel(t *testing.T) {
+	type testCase struct {
+		name       string
+		statusCode int
+
+		expected string
+	}
+
+	tt := []testCase{
+		{name: "2xx", statusCode: http.StatusNoContent, expected: "2xx"},
+		{name: "3xx", statusCode: http.StatusFound, expected: "3xx"},
+		{name: "5xx", statusCode: http.StatusServiceUnavailable, expected: "5xx"},
+		{name: "unknown", statusCode: 0, expected: "unknown"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := getStatusLabel(tc.statusCode); got != tc.expected {
+				t.Errorf("expected label: %s; got: %s\n", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	rateLimitLimitHeaderKey     string = "RateLimit-Limit"
+	rateLimitRemainingHeaderKey string = "RateLimit-Remaining"
+	rateLimitResetHeaderKey     string = "RateLimit-Reset"
+	rateLimitPolicyHeaderKey    string = "RateLimit-Policy"
+	retryAfterHeaderKey         string = "Retry-After"
+
+	defaultRateLimitWindow time.Duration = time.Minute
+	defaultStoreShards     int           = 32
+)
+
+var (
+	ErrInvalidRateLimit = errors.New("the limit and the window of the rate limit must be positive")
+)
+
+// RateLimitAlgorithm determines how the requests are counted.
+type RateLimitAlgorithm uint8
+
+const (
+	// The bucket holds at most Burst tokens, and it is refilled
+	// continuously by Limit tokens per Window. Every request takes
+	// one token, thus short bursts are allowed.
+	TokenBucket RateLimitAlgorithm = iota
+
+	// At most Limit requests are allowed in any Window, where the
+	// count of the previous window is weighted by its overlap.
+	SlidingWindow
+)
+
+// RateLimitKeyFunc returns the key, which the requests are counted by.
+// If it returns an empty string, then the request is not limited.
+type RateLimitKeyFunc func(gorouter.Context) string
+
+// RateLimitRule describes the limit applied to a single key.
+type RateLimitRule struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Burst     int
+	Window    time.Duration
+}
+
+// RateLimitResult is the outcome of taking a request from the limit.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// The time until the limit is fully available again.
+	Reset time.Duration
+	// The time until the next request is allowed – if it is not allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore stores the state of the limits. The Take must be
+// atomic per key, so an external – eg. shared – store must implement
+// the algorithm of the rule on its side.
+type RateLimitStore interface {
+	Take(key string, rule RateLimitRule, now time.Time) (RateLimitResult, error)
+}
+
+// RateLimitConfig is the configuration of the rate limiting middleware.
+type RateLimitConfig struct {
+	Algorithm RateLimitAlgorithm
+
+	// The count of requests allowed in a window. Required.
+	Limit int
+
+	// The length of the window. By default it is one minute.
+	Window time.Duration
+
+	// The capacity of the bucket in case of TokenBucket.
+	// By default it is the same as the Limit.
+	Burst int
+
+	// The function which identifies the client.
+	// By default the requests are limited by the client IP.
+	KeyFunc RateLimitKeyFunc
+
+	// The store of the limits. By default an in-memory store is used.
+	Store RateLimitStore
+
+	// The handler called when the limit is exceeded. The headers are
+	// already set at that point. By default 429 is responded.
+	OnLimited gorouter.HandlerFunc
+
+	// The handler of the store errors. By default the error is ignored
+	// and the request is allowed, so an unavailable store does not
+	// make the whole service unavailable.
+	OnError func(gorouter.Context, error)
+}
+
+// RateLimit creates and returns a middleware, which limits the rate of the
+// requests. It could be registered globally, or for specific routes as well.
+func RateLimit(conf RateLimitConfig) gorouter.Middleware {
+	if conf.Limit <= 0 || conf.Window < 0 {
+		panic(ErrInvalidRateLimit)
+	}
+
+	if conf.Window == 0 {
+		conf.Window = defaultRateLimitWindow
+	}
+
+	if conf.Burst <= 0 {
+		conf.Burst = conf.Limit
+	}
+
+	if conf.KeyFunc == nil {
+		conf.KeyFunc = KeyByIP()
+	}
+
+	if conf.Store == nil {
+		conf.Store = NewMemoryStore()
+	}
+
+	if conf.OnLimited == nil {
+		conf.OnLimited = func(ctx gorouter.Context) {
+			ctx.StatusText(http.StatusTooManyRequests)
+		}
+	}
+
+	var (
+		rule = RateLimitRule{
+			Algorithm: conf.Algorithm,
+			Limit:     conf.Limit,
+			Burst:     conf.Burst,
+			Window:    conf.Window,
+		}
+
+		policy = fmt.Sprintf("%d;w=%d", conf.Limit, int64(math.Ceil(conf.Window.Seconds())))
+	)
+
+	return gorouter.NewMiddleware(
+		func(ctx gorouter.Context) {
+			key := conf.KeyFunc(ctx)
+			if key == "" {
+				ctx.Next()
+
+				return
+			}
+
+			res, err := conf.Store.Take(key, rule, time.Now())
+			if err != nil {
+				if conf.OnError != nil {
+					conf.OnError(ctx, err)
+
+					return
+				}
+
+				ctx.Next()
+
+				return
+			}
+
+			header := ctx.GetResponseHeaders()
+			header.Set(rateLimitLimitHeaderKey, strconv.Itoa(res.Limit))
+			header.Set(rateLimitRemainingHeaderKey, strconv.Itoa(res.Remaining))
+			header.Set(rateLimitResetHeaderKey, formatSeconds(res.Reset))
+			header.Set(rateLimitPolicyHeaderKey, policy)
+
+			if !res.Allowed {
+				header.Set(retryAfterHeaderKey, formatSeconds(res.RetryAfter))
+				conf.OnLimited(ctx)
+
+				return
+			}
+
+			ctx.Next()
+		},
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+// KeyByIP returns a key function, which identifies the clients by their IP.
+// If any header is given – eg. X-Forwarded-For –, then the first address of
+// the first present header is used, so it must be set only behind a trusted proxy.
+func KeyByIP(trustedHeaders ...string) RateLimitKeyFunc {
+	return func(ctx gorouter.Context) string {
+		for _, h := range trustedHeaders {
+			v := ctx.GetRequestHeader(h)
+			if v == "" {
+				continue
+			}
+
+			first, _, _ := strings.Cut(v, ",")
+			if first = strings.TrimSpace(first); first != "" {
+				return first
+			}
+		}
+
+		r := ctx.GetRequest()
+		if r == nil {
+			return ""
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+
+		return host
+	}
+}
+
+// KeyByHeader returns a key function, which identifies
+// the clients by the value of the given request header.
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(ctx gorouter.Context) string {
+		return ctx.GetRequestHeader(name)
+	}
+}
+
+// KeyByAPIKey returns a key function, which identifies the clients by their
+// API key sent in the given header or – if it is missing – in the given query param.
+func KeyByAPIKey(header string, queryParam string) RateLimitKeyFunc {
+	return func(ctx gorouter.Context) string {
+		if v := ctx.GetRequestHeader(header); v != "" {
+			return v
+		}
+
+		if queryParam == "" {
+			return ""
+		}
+
+		return ctx.GetQueryParam(queryParam)
+	}
+}
+
+// KeyByRoute returns a key function, which limits the requests of
+// each registered route – eg. GET /users/{id} – separately,
+// for the clients identified by the given key function.
+func KeyByRoute(key RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(ctx gorouter.Context) string {
+		k := key(ctx)
+		if k == "" {
+			return ""
+		}
+
+		return ctx.GetRequestMethod() + " " + ctx.GetRegisteredUrl() + " " + k
+	}
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+type limitState struct {
+	// State of the token bucket.
+	tokens float64
+	last   time.Time
+
+	// State of the sliding window.
+	windowStart time.Time
+	prevCount   int
+	currCount   int
+
+	expiresAt time.Time
+}
+
+type storeShard struct {
+	mu        sync.Mutex
+	states    map[string]*limitState
+	lastSweep time.Time
+}
+
+// MemoryStore is an in-memory RateLimitStore, which is sharded by
+// the keys, so the concurrent requests rarely wait for each other.
+// The states are evicted after they are fully reset.
+type MemoryStore struct {
+	shards []*storeShard
+}
+
+var _ RateLimitStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates and returns a new MemoryStore
+// with the given count of shards – 32 by default.
+func NewMemoryStore(shards ...int) *MemoryStore {
+	count := defaultStoreShards
+	if len(shards) > 0 && shards[0] > 0 {
+		count = shards[0]
+	}
+
+	s := &MemoryStore{shards: make([]*storeShard, count)}
+	for i := range s.shards {
+		s.shards[i] = &storeShard{states: make(map[string]*limitState)}
+	}
+
+	return s
+}
+
+// Take takes one request from the limit of the given key.
+func (s *MemoryStore) Take(key string, rule RateLimitRule, now time.Time) (RateLimitResult, error) {
+	if rule.Limit <= 0 || rule.Window <= 0 {
+		return RateLimitResult{}, ErrInvalidRateLimit
+	}
+
+	shard := s.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.sweep(now, rule.Window)
+
+	state, ok := shard.states[key]
+	if !ok {
+		state = &limitState{tokens: float64(rule.burst()), last: now}
+		shard.states[key] = state
+	}
+
+	var res RateLimitResult
+	if rule.Algorithm == SlidingWindow {
+		res = state.takeSlidingWindow(rule, now)
+	} else {
+		res = state.takeTokenBucket(rule, now)
+	}
+
+	state.expiresAt = now.Add(res.Reset)
+
+	return res, nil
+}
+
+// Len returns the count of the stored states.
+func (s *MemoryStore) Len() int {
+	var l int
+	for _, shard := range s.shards {
+		shard.mu.Lock()
+		l += len(shard.states)
+		shard.mu.Unlock()
+	}
+	return l
+}
+
+func (s *MemoryStore) getShard(key string) *storeShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// sweep removes the expired states, at most once in every interval.
+func (sh *storeShard) sweep(now time.Time, interval time.Duration) {
+	if now.Sub(sh.lastSweep) < interval {
+		return
+	}
+	sh.lastSweep = now
+
+	for k, st := range sh.states {
+		if !now.Before(st.expiresAt) {
+			delete(sh.states, k)
+		}
+	}
+}
+
+func (r RateLimitRule) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+func (st *limitState) takeTokenBucket(rule RateLimitRule, now time.Time) RateLimitResult {
+	var (
+		capacity = float64(rule.burst())
+		// Tokens per nanosecond.
+		rate = float64(rule.Limit) / float64(rule.Window)
+	)
+
+	if elapsed := now.Sub(st.last); elapsed > 0 {
+		st.tokens = math.Min(capacity, st.tokens+float64(elapsed)*rate)
+	}
+	st.last = now
+
+	res := RateLimitResult{Limit: rule.burst()}
+
+	if st.tokens >= 1 {
+		st.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - st.tokens) / rate)
+	}
+
+	res.Remaining = int(st.tokens)
+	res.Reset = time.Duration((capacity - st.tokens) / rate)
+
+	return res
+}
+
+func (st *limitState) takeSlidingWindow(rule RateLimitRule, now time.Time) RateLimitResult {
+	start := now.Truncate(rule.Window)
+
+	switch diff := start.Sub(st.windowStart); {
+	case diff == rule.Window:
+		st.prevCount, st.currCount = st.currCount, 0
+	case diff > rule.Window:
+		st.prevCount, st.currCount = 0, 0
+	}
+	st.windowStart = start
+
+	var (
+		elapsed   = now.Sub(start)
+		weight    = 1 - float64(elapsed)/float64(rule.Window)
+		estimated = int(math.Floor(float64(st.prevCount)*weight)) + st.currCount
+
+		res = RateLimitResult{Limit: rule.Limit}
+	)
+
+	if estimated < rule.Limit {
+		st.currCount++
+		estimated++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = st.retryAfter(rule, elapsed)
+	}
+
+	res.Remaining = max(rule.Limit-estimated, 0)
+
+	// The requests of the current window weigh
+	// in until the end of the next window.
+	res.Reset = rule.Window - elapsed
+	if st.currCount > 0 {
+		res.Reset += rule.Window
+	}
+
+	return res
+}
+
+// retryAfter returns the time until the weighted count drops below the limit.
+func (st *limitState) retryAfter(rule RateLimitRule, elapsed time.Duration) time.Duration {
+	var (
+		window = float64(rule.Window)
+		free   = float64(rule.Limit - 1)
+	)
+
+	// It is enough to wait for the previous window to fade out.
+	if st.currCount <= rule.Limit-1 && st.prevCount > 0 {
+		w := (free - float64(st.currCount)) / float64(st.prevCount)
+
+		return max(time.Duration(window*(1-w))-elapsed, 0)
+	}
+
+	// Otherwise the current window must fade out in the next one.
+	return rule.Window - elapsed + time.Duration(window*(1-free/float64(st.currCount)))
+}
@@ -0,0 +1,274 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	type take struct {
+		after             time.Duration
+		expectedAllowed   bool
+		expectedRemaining int
+	}
+
+	type testCase struct {
+		name  string
+		rule  RateLimitRule
+		takes []take
+	}
+
+	var start = time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []testCase{
+		{
+			name: "token bucket allows the burst, then refills",
+			rule: RateLimitRule{Algorithm: TokenBucket, Limit: 2, Window: time.Second},
+			takes: []take{
+				{after: 0, expectedAllowed: true, expectedRemaining: 1},
+				{after: 0, expectedAllowed: true, expectedRemaining: 0},
+				{after: 0, expectedAllowed: false, expectedRemaining: 0},
+				{after: 500 * time.Millisecond, expectedAllowed: true, expectedRemaining: 0},
+				{after: 2 * time.Second, expectedAllowed: true, expectedRemaining: 1},
+			},
+		},
+		{
+			name: "token bucket with larger burst",
+			rule: RateLimitRule{Algorithm: TokenBucket, Limit: 1, Burst: 3, Window: time.Second},
+			takes: []take{
+				{after: 0, expectedAllowed: true, expectedRemaining: 2},
+				{after: 0, expectedAllowed: true, expectedRemaining: 1},
+				{after: 0, expectedAllowed: true, expectedRemaining: 0},
+				{after: 0, expectedAllowed: false, expectedRemaining: 0},
+			},
+		},
+		{
+			name: "sliding window weights the previous window",
+			rule: RateLimitRule{Algorithm: SlidingWindow, Limit: 2, Window: time.Minute},
+			takes: []take{
+				{after: 0, expectedAllowed: true, expectedRemaining: 1},
+				{after: 0, expectedAllowed: true, expectedRemaining: 0},
+				{after: 30 * time.Second, expectedAllowed: false, expectedRemaining: 0},
+				// 1m15s: the previous window weighs 75%, thus 1 request counts.
+				{after: 45 * time.Second, expectedAllowed: true, expectedRemaining: 0},
+				{after: 0, expectedAllowed: false, expectedRemaining: 0},
+				// 3m: both windows are over.
+				{after: 105 * time.Second, expectedAllowed: true, expectedRemaining: 1},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				store = NewMemoryStore(4)
+				now   = start
+			)
+
+			for i, tk := range tc.takes {
+				now = now.Add(tk.after)
+
+				res, err := store.Take("client", tc.rule, now)
+				if err != nil {
+					t.Fatalf("expected no error; got: %v\n", err)
+				}
+
+				if res.Allowed != tk.expectedAllowed {
+					t.Errorf("take %d: expected allowed: %v; got: %v\n", i, tk.expectedAllowed, res.Allowed)
+				}
+
+				if res.Remaining != tk.expectedRemaining {
+					t.Errorf("take %d: expected remaining: %d; got: %d\n", i, tk.expectedRemaining, res.Remaining)
+				}
+
+				if !res.Allowed && res.RetryAfter <= 0 {
+					t.Errorf("take %d: expected positive retry after; got: %v\n", i, res.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	var (
+		store = NewMemoryStore(1)
+		rule  = RateLimitRule{Algorithm: TokenBucket, Limit: 10, Window: time.Second}
+		now   = time.Now()
+	)
+
+	store.Take("a", rule, now)
+	store.Take("b", rule, now)
+
+	if l := store.Len(); l != 2 {
+		t.Fatalf("expected len: 2; got: %d\n", l)
+	}
+
+	// The buckets are full again, so they are evicted on the next sweep.
+	store.Take("c", rule, now.Add(2*time.Second))
+
+	if l := store.Len(); l != 1 {
+		t.Errorf("expected len: 1; got: %d\n", l)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(string, RateLimitRule, time.Time) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	type testCase struct {
+		name     string
+		conf     RateLimitConfig
+		perRoute bool
+		requests int
+
+		expectedStatusCodes []int
+	}
+
+	tt := []testCase{
+		{
+			name:                "global limit responds with 429 after the limit",
+			conf:                RateLimitConfig{Limit: 2, Window: time.Minute},
+			requests:            3,
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:                "route limit responds with 429 after the limit",
+			conf:                RateLimitConfig{Algorithm: SlidingWindow, Limit: 1, Window: time.Minute},
+			perRoute:            true,
+			requests:            2,
+			expectedStatusCodes: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:                "requests without key are not limited",
+			conf:                RateLimitConfig{Limit: 1, KeyFunc: KeyByAPIKey("X-Api-Key", "api_key")},
+			requests:            2,
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:                "store errors allow the request by default",
+			conf:                RateLimitConfig{Limit: 1, Store: failingStore{}},
+			requests:            2,
+			expectedStatusCodes: []int{http.StatusOK, http.StatusOK},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				r  = gorouter.New()
+				mw = RateLimit(tc.conf)
+
+				handler = func(ctx gorouter.Context) { ctx.Status(http.StatusOK) }
+			)
+
+			if tc.perRoute {
+				r.Get("/api/users", handler).RegisterMiddlewares(mw)
+			} else {
+				r.RegisterMiddlewares(mw)
+				r.Get("/api/users", handler)
+			}
+
+			for i := 0; i < tc.requests; i++ {
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+				if rec.Code != tc.expectedStatusCodes[i] {
+					t.Errorf("request %d: expected status code: %d; got: %d\n", i, tc.expectedStatusCodes[i], rec.Code)
+				}
+
+				if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: expected Retry-After header\n", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	r := gorouter.New()
+	r.RegisterMiddlewares(RateLimit(RateLimitConfig{Limit: 1, Window: time.Minute}))
+	r.Get("/api/users", func(ctx gorouter.Context) {})
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	}
+
+	expected := map[string]string{
+		"RateLimit-Limit":     "1",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "1;w=60",
+		"Retry-After":         "60",
+	}
+
+	for k, v := range expected {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("expected %s header: %q; got: %q\n", k, v, got)
+		}
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	type testCase struct {
+		name     string
+		keyFunc  RateLimitKeyFunc
+		url      string
+		headers  map[string]string
+		expected string
+	}
+
+	tt := []testCase{
+		{
+			name:     "ip from remote address",
+			keyFunc:  KeyByIP(),
+			url:      "/",
+			expected: "192.0.2.1",
+		},
+		{
+			name:     "ip from trusted header",
+			keyFunc:  KeyByIP("X-Forwarded-For"),
+			url:      "/",
+			headers:  map[string]string{"X-Forwarded-For": "203.0.113.5, 10.0.0.1"},
+			expected: "203.0.113.5",
+		},
+		{
+			name:     "api key from header",
+			keyFunc:  KeyByAPIKey("X-Api-Key", "api_key"),
+			url:      "/?api_key=query",
+			headers:  map[string]string{"X-Api-Key": "header"},
+			expected: "header",
+		},
+		{
+			name:     "api key from query",
+			keyFunc:  KeyByAPIKey("X-Api-Key", "api_key"),
+			url:      "/?api_key=query",
+			expected: "query",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			ctx := gorouter.NewContext(gorouter.ContextConfig{})
+			ctx.Reset(httptest.NewRecorder(), req)
+
+			if got := tc.keyFunc(ctx); got != tc.expected {
+				t.Errorf("expected key: %q; got: %q\n", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	RequestIDHeaderKey string = "X-Request-ID"
+
+	maxRequestIDLength int = 128
+
+	// The alphabet of the ULIDs – Crockford's Base32.
+	ulidAlphabet string = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// RequestIDConfig is the configuration of the request id middleware.
+type RequestIDConfig struct {
+	// The header of the request id. By default it is X-Request-ID.
+	Header string
+
+	// The generator of the new ids. By default NewUUIDv7.
+	Generator func() string
+
+	// The validator of the incoming ids. By default at most 128 characters
+	// of letters, digits and -_.: are accepted. The invalid ones are replaced.
+	Validator func(id string) bool
+
+	// Whether the incoming ids are ignored, eg. in case of a public
+	// facing service, where the clients must not choose the id.
+	IgnoreIncoming bool
+}
+
+// RequestID creates and returns a middleware, which accepts the id of the
+// request from the incoming header – if it is valid –, otherwise generates
+// a new one. The id is sent back in the response header, and bound to
+// the context, so it is available by gorouter.GetRequestID.
+func RequestID(conf ...RequestIDConfig) gorouter.Middleware {
+	var c RequestIDConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Header == "" {
+		c.Header = RequestIDHeaderKey
+	}
+
+	if c.Generator == nil {
+		c.Generator = NewUUIDv7
+	}
+
+	if c.Validator == nil {
+		c.Validator = isValidRequestID
+	}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		var id string
+
+		if !c.IgnoreIncoming {
+			id = ctx.GetRequestHeader(c.Header)
+		}
+
+		if id == "" || !c.Validator(id) {
+			id = c.Generator()
+		}
+
+		ctx.BindValue(gorouter.RequestIDKey, id)
+		ctx.GetResponseHeaders().Set(c.Header, id)
+
+		ctx.Next()
+	})
+}
+
+// PropagateRequestID sets the id of the request bound to the context
+// on the given outgoing request, so the id is passed to the downstream
+// services. The header is X-Request-ID, unless another is given.
+func PropagateRequestID(ctx gorouter.Context, req *http.Request, header ...string) {
+	id := gorouter.GetRequestID(ctx)
+	if id == "" {
+		return
+	}
+
+	key := RequestIDHeaderKey
+	if len(header) > 0 && header[0] != "" {
+		key = header[0]
+	}
+
+	req.Header.Set(key, id)
+}
+
+// NewUUIDv7 generates and returns a new UUID version 7, which is
+// ordered by the time of the generation – see RFC 9562.
+func NewUUIDv7() string {
+	b := newTimestampedID()
+
+	// The version and the variant.
+	b[6] = (b[6] & 0x0f) | 0x70
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	var dst [36]byte
+	hex.Encode(dst[0:8], b[0:4])
+	dst[8] = '-'
+	hex.Encode(dst[9:13], b[4:6])
+	dst[13] = '-'
+	hex.Encode(dst[14:18], b[6:8])
+	dst[18] = '-'
+	hex.Encode(dst[19:23], b[8:10])
+	dst[23] = '-'
+	hex.Encode(dst[24:], b[10:])
+
+	return string(dst[:])
+}
+
+// NewULID generates and returns a new ULID, which is
+// ordered by the time of the generation.
+func NewULID() string {
+	b := newTimestampedID()
+
+	// The 128 bits are encoded by 5 bits from the most significant one,
+	// so the first character holds only the 3 highest bits.
+	var (
+		dst [26]byte
+
+		hi = binary.BigEndian.Uint64(b[:8])
+		lo = binary.BigEndian.Uint64(b[8:])
+	)
+
+	for i := len(dst) - 1; i >= 0; i-- {
+		dst[i] = ulidAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(dst[:])
+}
+
+// newTimestampedID returns 128 bits, where the first 48 bits are the
+// unix timestamp in milliseconds, while the rest is random.
+func newTimestampedID() [16]byte {
+	var b [16]byte
+	rand.Read(b[6:])
+
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+
+	return b
+}
+
+func isValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range []byte(id) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,146 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+var (
+	uuidV7Regexp = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidRegexp   = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+func TestRequestID(t *testing.T) {
+	type testCase struct {
+		name     string
+		conf     []RequestIDConfig
+		incoming string
+
+		expectedId     string
+		expectedRegexp *regexp.Regexp
+	}
+
+	tt := []testCase{
+		{
+			name:           "generates a UUIDv7 without incoming id",
+			expectedRegexp: uuidV7Regexp,
+		},
+		{
+			name:       "accepts the valid incoming id",
+			incoming:   "upstream-1234.abc:5",
+			expectedId: "upstream-1234.abc:5",
+		},
+		{
+			name:           "replaces the invalid incoming id",
+			incoming:       "<script>",
+			expectedRegexp: uuidV7Regexp,
+		},
+		{
+			name:           "replaces the too long incoming id",
+			incoming:       strings.Repeat("a", 129),
+			expectedRegexp: uuidV7Regexp,
+		},
+		{
+			name:           "ignores the incoming id",
+			conf:           []RequestIDConfig{{IgnoreIncoming: true, Generator: NewULID}},
+			incoming:       "upstream-1234",
+			expectedRegexp: ulidRegexp,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				boundId string
+
+				r = gorouter.New()
+			)
+
+			r.RegisterMiddlewares(RequestID(tc.conf...))
+
+			r.Get("/api/users", func(ctx gorouter.Context) {
+				boundId = gorouter.GetRequestID(ctx)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+			if tc.incoming != "" {
+				req.Header.Set(RequestIDHeaderKey, tc.incoming)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeaderKey)
+
+			if tc.expectedId != "" && id != tc.expectedId {
+				t.Errorf("expected id: %q; got: %q\n", tc.expectedId, id)
+			}
+
+			if tc.expectedRegexp != nil && !tc.expectedRegexp.MatchString(id) {
+				t.Errorf("expected id matching: %s; got: %q\n", tc.expectedRegexp, id)
+			}
+
+			if boundId != id {
+				t.Errorf("expected bound id: %q; got: %q\n", id, boundId)
+			}
+		})
+	}
+}
+
+func TestRequestIDGenerators(t *testing.T) {
+	seen := make(map[string]struct{})
+
+	for range 1000 {
+		for _, id := range []string{NewUUIDv7(), NewULID()} {
+			if _, exists := seen[id]; exists {
+				t.Fatalf("expected unique ids; got: %q twice\n", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+
+	// The ids are ordered by the time of the generation.
+	if a, b := NewULID(), NewULID(); a[:10] > b[:10] {
+		t.Errorf("expected ordered timestamps; got: %q > %q\n", a[:10], b[:10])
+	}
+}
+
+func TestRequestIDLogger(t *testing.T) {
+	var (
+		buf bytes.Buffer
+
+		r = gorouter.New()
+	)
+
+	r.RegisterMiddlewares(RequestID())
+	r.RegisterPostMiddlewares(Logger(&buf))
+
+	r.Get("/api/users", func(ctx gorouter.Context) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set(RequestIDHeaderKey, "abc-123")
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), `"request_id":"abc-123"`) {
+		t.Errorf("expected request_id in the log; got: %s\n", buf.String())
+	}
+
+	outgoing := httptest.NewRequest(http.MethodGet, "/downstream", nil)
+
+	r.Get("/api/proxy", func(ctx gorouter.Context) {
+		PropagateRequestID(ctx, outgoing)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/proxy", nil))
+
+	if id := outgoing.Header.Get(RequestIDHeaderKey); !uuidV7Regexp.MatchString(id) {
+		t.Errorf("expected propagated id; got: %q\n", id)
+	}
+}
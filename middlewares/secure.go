@@ -0,0 +1,172 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	strictTransportSecurityHeaderKey   string = "Strict-Transport-Security"
+	contentSecurityPolicyHeaderKey     string = "Content-Security-Policy"
+	contentSecurityPolicyROHeaderKey   string = "Content-Security-Policy-Report-Only"
+	contentTypeOptionsHeaderKey        string = "X-Content-Type-Options"
+	frameOptionsHeaderKey              string = "X-Frame-Options"
+	referrerPolicyHeaderKey            string = "Referrer-Policy"
+	permissionsPolicyHeaderKey         string = "Permissions-Policy"
+	crossOriginOpenerPolicyHeaderKey   string = "Cross-Origin-Opener-Policy"
+	crossOriginEmbedderPolicyHeaderKey string = "Cross-Origin-Embedder-Policy"
+	crossOriginResourcePolicyHeaderKey string = "Cross-Origin-Resource-Policy"
+
+	// CSPNonceKey is the key of the nonce of the Content-Security-Policy bound to the Context.
+	CSPNonceKey gorouter.ContextKey = "__cspNonce__"
+
+	// CSPNoncePlaceholder is replaced by the nonce of the request in the Content-Security-Policy.
+	CSPNoncePlaceholder string = "{nonce}"
+
+	cspNonceSize int = 16
+
+	defaultHSTSMaxAge time.Duration = 365 * 24 * time.Hour
+)
+
+// SecureConfig is the configuration of the security headers middleware.
+// The headers with empty value are not sent.
+type SecureConfig struct {
+	// The max-age of the Strict-Transport-Security. If it is zero, then the header is not sent.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	// The Content-Security-Policy. If it contains the CSPNoncePlaceholder,
+	// then a new nonce is generated for each request, which is available
+	// by GetCSPNonce, eg. for the script tags of the HTML templates.
+	ContentSecurityPolicy string
+
+	// Whether the policy is only reported by the browser instead of enforced,
+	// so a new policy could be tested without breaking the application.
+	CSPReportOnly bool
+
+	ContentTypeOptions        string
+	FrameOptions              string
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginEmbedderPolicy string
+	CrossOriginResourcePolicy string
+}
+
+// SecureAPIConfig returns the recommended configuration for JSON APIs,
+// which are never rendered or embedded by the browsers.
+func SecureAPIConfig() SecureConfig {
+	return SecureConfig{
+		HSTSMaxAge:                defaultHSTSMaxAge,
+		HSTSIncludeSubdomains:     true,
+		ContentSecurityPolicy:     "default-src 'none'; frame-ancestors 'none'",
+		ContentTypeOptions:        "nosniff",
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "no-referrer",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// SecureHTMLConfig returns the recommended configuration for HTML applications,
+// where the inline scripts and styles are allowed only with the nonce of the request.
+func SecureHTMLConfig() SecureConfig {
+	return SecureConfig{
+		HSTSMaxAge:            defaultHSTSMaxAge,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'; " +
+			"script-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; " +
+			"style-src 'self' 'nonce-" + CSPNoncePlaceholder + "'; " +
+			"object-src 'none'; base-uri 'self'; frame-ancestors 'self'",
+		ContentTypeOptions:        "nosniff",
+		FrameOptions:              "SAMEORIGIN",
+		ReferrerPolicy:            "strict-origin-when-cross-origin",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+}
+
+// Secure creates and returns a middleware, which sets the
+// security headers of the responses based on the given config.
+func Secure(conf SecureConfig) gorouter.Middleware {
+	var (
+		// The static headers are built only once.
+		headers = make(map[string]string)
+
+		cspHeaderKey = contentSecurityPolicyHeaderKey
+		hasNonce     = strings.Contains(conf.ContentSecurityPolicy, CSPNoncePlaceholder)
+	)
+
+	if conf.CSPReportOnly {
+		cspHeaderKey = contentSecurityPolicyROHeaderKey
+	}
+
+	if conf.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.FormatInt(int64(conf.HSTSMaxAge/time.Second), 10)
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if conf.HSTSPreload {
+			hsts += "; preload"
+		}
+		headers[strictTransportSecurityHeaderKey] = hsts
+	}
+
+	if conf.ContentSecurityPolicy != "" && !hasNonce {
+		headers[cspHeaderKey] = conf.ContentSecurityPolicy
+	}
+
+	for k, v := range map[string]string{
+		contentTypeOptionsHeaderKey:        conf.ContentTypeOptions,
+		frameOptionsHeaderKey:              conf.FrameOptions,
+		referrerPolicyHeaderKey:            conf.ReferrerPolicy,
+		permissionsPolicyHeaderKey:         conf.PermissionsPolicy,
+		crossOriginOpenerPolicyHeaderKey:   conf.CrossOriginOpenerPolicy,
+		crossOriginEmbedderPolicyHeaderKey: conf.CrossOriginEmbedderPolicy,
+		crossOriginResourcePolicyHeaderKey: conf.CrossOriginResourcePolicy,
+	} {
+		if v != "" {
+			headers[k] = v
+		}
+	}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		header := ctx.GetResponseHeaders()
+
+		for k, v := range headers {
+			header.Set(k, v)
+		}
+
+		if hasNonce {
+			nonce := newCSPNonce()
+
+			ctx.BindValue(CSPNonceKey, nonce)
+			header.Set(cspHeaderKey, strings.ReplaceAll(conf.ContentSecurityPolicy, CSPNoncePlaceholder, nonce))
+		}
+
+		ctx.Next()
+	})
+}
+
+// GetCSPNonce returns the nonce of the Content-Security-Policy bound
+// to the given Context by the Secure middleware – if there is any.
+func GetCSPNonce(ctx gorouter.Context) string {
+	nonce, _ := ctx.GetBindedValue(CSPNonceKey).(string)
+	return nonce
+}
+
+func newCSPNonce() string {
+	b := make([]byte, cspNonceSize)
+	rand.Read(b)
+
+	// The URL-safe alphabet is used, so the nonce
+	// is not escaped by the html/template package.
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,158 @@
+package middlewares
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestSecure(t *testing.T) {
+	type testCase struct {
+		name string
+		conf SecureConfig
+
+		expectedHeaders map[string]string
+		expectedNonce   bool
+	}
+
+	tt := []testCase{
+		{
+			name: "sends the headers of the API config",
+			conf: SecureAPIConfig(),
+			expectedHeaders: map[string]string{
+				strictTransportSecurityHeaderKey:   "max-age=31536000; includeSubDomains",
+				contentSecurityPolicyHeaderKey:     "default-src 'none'; frame-ancestors 'none'",
+				contentTypeOptionsHeaderKey:        "nosniff",
+				frameOptionsHeaderKey:              "DENY",
+				referrerPolicyHeaderKey:            "no-referrer",
+				crossOriginOpenerPolicyHeaderKey:   "same-origin",
+				crossOriginResourcePolicyHeaderKey: "same-origin",
+				permissionsPolicyHeaderKey:         "",
+				crossOriginEmbedderPolicyHeaderKey: "",
+			},
+		},
+		{
+			name: "sends the nonce of the HTML config",
+			conf: SecureHTMLConfig(),
+			expectedHeaders: map[string]string{
+				frameOptionsHeaderKey:      "SAMEORIGIN",
+				permissionsPolicyHeaderKey: "camera=(), microphone=(), geolocation=()",
+			},
+			expectedNonce: true,
+		},
+		{
+			name: "sends the policy in report-only mode",
+			conf: SecureConfig{
+				ContentSecurityPolicy: "script-src 'nonce-{nonce}'",
+				CSPReportOnly:         true,
+				HSTSMaxAge:            time.Hour,
+				HSTSPreload:           true,
+			},
+			expectedHeaders: map[string]string{
+				strictTransportSecurityHeaderKey: "max-age=3600; preload",
+				contentSecurityPolicyHeaderKey:   "",
+				contentTypeOptionsHeaderKey:      "",
+			},
+			expectedNonce: true,
+		},
+		{
+			name: "sends nothing with empty config",
+			conf: SecureConfig{},
+			expectedHeaders: map[string]string{
+				strictTransportSecurityHeaderKey: "",
+				contentSecurityPolicyHeaderKey:   "",
+				frameOptionsHeaderKey:            "",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				nonce string
+
+				r = gorouter.New()
+			)
+
+			r.RegisterMiddlewares(Secure(tc.conf))
+
+			r.Get("/", func(ctx gorouter.Context) {
+				nonce = GetCSPNonce(ctx)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			for k, v := range tc.expectedHeaders {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expected %s: %q; got: %q\n", k, v, got)
+				}
+			}
+
+			if (nonce != "") != tc.expectedNonce {
+				t.Fatalf("expected nonce: %v; got: %q\n", tc.expectedNonce, nonce)
+			}
+
+			if !tc.expectedNonce {
+				return
+			}
+
+			csp := rec.Header().Get(contentSecurityPolicyHeaderKey)
+			if tc.conf.CSPReportOnly {
+				csp = rec.Header().Get(contentSecurityPolicyROHeaderKey)
+			}
+
+			if !strings.Contains(csp, "'nonce-"+nonce+"'") || strings.Contains(csp, CSPNoncePlaceholder) {
+				t.Errorf("expected policy with nonce %q; got: %q\n", nonce, csp)
+			}
+		})
+	}
+}
+
+func TestSecureNonceTemplate(t *testing.T) {
+	var (
+		r    = gorouter.New()
+		tmpl = template.Must(template.New("page").Parse(`<script nonce="{{ .Nonce }}">init()</script>`))
+
+		nonces = make(map[string]struct{})
+	)
+
+	r.RegisterMiddlewares(Secure(SecureHTMLConfig()))
+
+	r.Get("/", func(ctx gorouter.Context) {
+		var b strings.Builder
+		tmpl.Execute(&b, map[string]string{"Nonce": GetCSPNonce(ctx)})
+
+		ctx.Render(http.StatusOK, &gorouter.HtmlResponse{Data: []byte(b.String())})
+	})
+
+	for range 2 {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		nonce := getNonceFromPolicy(rec.Header().Get(contentSecurityPolicyHeaderKey))
+		if nonce == "" || !strings.Contains(rec.Body.String(), `nonce="`+nonce+`"`) {
+			t.Errorf("expected body with nonce %q; got: %s\n", nonce, rec.Body.String())
+		}
+
+		nonces[nonce] = struct{}{}
+	}
+
+	if len(nonces) != 2 {
+		t.Errorf("expected new nonce for each request; got: %v\n", nonces)
+	}
+}
+
+func getNonceFromPolicy(csp string) string {
+	_, rest, found := strings.Cut(csp, "'nonce-")
+	if !found {
+		return ""
+	}
+	nonce, _, _ := strings.Cut(rest, "'")
+	return nonce
+}
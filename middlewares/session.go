@@ -0,0 +1,397 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	// SessionKey is the key of the session bound to the Context.
+	SessionKey gorouter.ContextKey = "__session__"
+
+	defaultSessionCookieName      string        = "session"
+	defaultSessionIdleTimeout     time.Duration = 30 * time.Minute
+	defaultSessionAbsoluteTimeout time.Duration = 24 * time.Hour
+
+	// The last access of a not modified session is saved
+	// only this often, so not every request hits the store.
+	sessionTouchInterval time.Duration = time.Minute
+
+	defaultFlashCategory string = ""
+
+	sessionIdSize int = 32
+
+	csrfSessionKey string = "__csrf__"
+)
+
+var (
+	ErrSessionNotFound       = errors.New("the session is not found")
+	ErrSessionsNotRegistered = errors.New("the sessions middleware is not registered")
+)
+
+// SessionStore stores the encoded sessions. The value is the value of the
+// cookie, which is the id of the session for the server-side stores, while
+// the cookie based stores could hold the whole encoded session in it.
+type SessionStore interface {
+	// Load returns the encoded session of the given cookie value, or ErrSessionNotFound.
+	Load(ctx gorouter.Context, value string) ([]byte, error)
+
+	// Save stores the encoded session with the given id for the given
+	// time to live, then returns the value of the cookie.
+	Save(ctx gorouter.Context, id string, data []byte, ttl time.Duration) (string, error)
+
+	// Delete deletes the session with the given id.
+	Delete(ctx gorouter.Context, id string) error
+}
+
+// SessionConfig is the configuration of the session middleware.
+type SessionConfig struct {
+	// The cookie of the session. By default the name is session, the path is /,
+	// and the SameSite is Lax. The HttpOnly is always true, unless AllowScriptAccess
+	// is set. The Value is ignored.
+	Cookie http.Cookie
+
+	// AllowScriptAccess lets the JavaScript of the page read the cookie
+	// of the session. It should be avoided, since an XSS could steal it.
+	AllowScriptAccess bool
+
+	// The session expires, if it is not used for the IdleTimeout – by default
+	// 30 minutes –, or it was created before the AbsoluteTimeout – by default 24 hours.
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+
+	// The handler of the store errors. By default 500 is responded. If the
+	// session could not be saved, then the response of the handler is
+	// discarded before, so the handler of the error could replace it.
+	OnError func(ctx gorouter.Context, err error)
+}
+
+type sessionRecord struct {
+	Id        string
+	Values    map[string]any
+	Flashes   map[string][]any
+	CreatedAt time.Time
+	LastSeen  time.Time
+}
+
+// Session is the server-side session of the client. It is not safe
+// for concurrent use, as it belongs to a single request.
+type Session struct {
+	record sessionRecord
+
+	// The id of the session before the regeneration, which must be deleted.
+	previousId string
+
+	isNew       bool
+	isModified  bool
+	isDestroyed bool
+}
+
+type sessions struct {
+	store SessionStore
+	conf  SessionConfig
+}
+
+// Sessions creates and returns a middleware, which loads the session of
+// the client from the given store, and binds it to the context, so it is
+// available by GetSession. The session is saved right before the response
+// is written, if it is modified.
+func Sessions(store SessionStore, conf ...SessionConfig) gorouter.Middleware {
+	var c SessionConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Cookie.Name == "" {
+		c.Cookie.Name = defaultSessionCookieName
+	}
+
+	c.Cookie.HttpOnly = !c.AllowScriptAccess
+
+	if c.Cookie.Path == "" {
+		c.Cookie.Path = "/"
+	}
+
+	if c.Cookie.SameSite == 0 {
+		c.Cookie.SameSite = http.SameSiteLaxMode
+	}
+
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = defaultSessionIdleTimeout
+	}
+
+	if c.AbsoluteTimeout <= 0 {
+		c.AbsoluteTimeout = defaultSessionAbsoluteTimeout
+	}
+
+	if c.OnError == nil {
+		c.OnError = func(ctx gorouter.Context, _ error) {
+			ctx.StatusText(http.StatusInternalServerError)
+		}
+	}
+
+	ss := &sessions{store: store, conf: c}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		s, err := ss.load(ctx)
+		if err != nil {
+			c.OnError(ctx, err)
+
+			return
+		}
+
+		ctx.BindValue(SessionKey, s)
+
+		// The cookie must be set before the headers are written.
+		ctx.BeforeWrite(func() {
+			if err := ss.save(ctx, s); err != nil {
+				// The response of the handler must not be sent without the session.
+				ctx.DiscardResponse()
+				c.OnError(ctx, err)
+			}
+		})
+
+		ctx.Next()
+	})
+}
+
+// GetSession returns the session bound to the given
+// Context by the Sessions middleware – if there is any.
+func GetSession(ctx gorouter.Context) (*Session, bool) {
+	s, ok := ctx.GetBindedValue(SessionKey).(*Session)
+	if !ok || s == nil {
+		return nil, false
+	}
+	return s, true
+}
+
+func (ss *sessions) load(ctx gorouter.Context) (*Session, error) {
+	now := time.Now()
+
+	cookie, err := ctx.GetCookie(ss.conf.Cookie.Name)
+	if err != nil || cookie.Value == "" {
+		return newSession(now), nil
+	}
+
+	data, err := ss.store.Load(ctx, cookie.Value)
+	if errors.Is(err, ErrSessionNotFound) {
+		return newSession(now), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record sessionRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+		return newSession(now), nil
+	}
+
+	if now.Sub(record.LastSeen) > ss.conf.IdleTimeout || now.Sub(record.CreatedAt) > ss.conf.AbsoluteTimeout {
+		s := newSession(now)
+		s.previousId = record.Id
+
+		return s, nil
+	}
+
+	return &Session{record: record}, nil
+}
+
+func (ss *sessions) save(ctx gorouter.Context, s *Session) error {
+	if s.previousId != "" {
+		if err := ss.store.Delete(ctx, s.previousId); err != nil {
+			return err
+		}
+		s.previousId = ""
+	}
+
+	cookie := ss.conf.Cookie
+
+	// The new and empty sessions are not saved, so the
+	// clients without session do not get a cookie.
+	if s.isNew && !s.isModified {
+		if s.isDestroyed {
+			return ctx.DeleteCookie(&cookie)
+		}
+		return nil
+	}
+
+	now := time.Now()
+
+	if !s.isModified && now.Sub(s.record.LastSeen) < sessionTouchInterval {
+		return nil
+	}
+
+	s.record.LastSeen = now
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&s.record); err != nil {
+		return err
+	}
+
+	ttl := min(ss.conf.IdleTimeout, ss.conf.AbsoluteTimeout-now.Sub(s.record.CreatedAt))
+
+	value, err := ss.store.Save(ctx, s.record.Id, buf.Bytes(), ttl)
+	if err != nil {
+		return err
+	}
+
+	cookie.Value = value
+
+	return ctx.SetCookie(&cookie)
+}
+
+func newSession(now time.Time) *Session {
+	return &Session{
+		record: sessionRecord{
+			Id:        newSessionId(),
+			Values:    make(map[string]any),
+			Flashes:   make(map[string][]any),
+			CreatedAt: now,
+			LastSeen:  now,
+		},
+		isNew: true,
+	}
+}
+
+func newSessionId() string {
+	b := make([]byte, sessionIdSize)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Id returns the id of the session.
+func (s *Session) Id() string {
+	return s.record.Id
+}
+
+// IsNew returns whether the session has been created by the current request.
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// Get returns the value of the given key – if there is any.
+func (s *Session) Get(key string) any {
+	return s.record.Values[key]
+}
+
+// GetString returns the string value of the given key – if there is any.
+func (s *Session) GetString(key string) string {
+	v, _ := s.record.Values[key].(string)
+	return v
+}
+
+// Set sets the value of the given key. The custom types of
+// the values must be registered by gob.Register.
+func (s *Session) Set(key string, value any) {
+	if s.record.Values == nil {
+		s.record.Values = make(map[string]any)
+	}
+
+	s.record.Values[key] = value
+	s.isModified = true
+}
+
+// Delete deletes the value of the given key.
+func (s *Session) Delete(key string) {
+	if _, exists := s.record.Values[key]; !exists {
+		return
+	}
+
+	delete(s.record.Values, key)
+	s.isModified = true
+}
+
+// AddFlash adds a flash message, which is kept until it is read
+// by Flashes – typically by the next request after a redirect.
+func (s *Session) AddFlash(value any, category ...string) {
+	c := defaultFlashCategory
+	if len(category) > 0 {
+		c = category[0]
+	}
+
+	if s.record.Flashes == nil {
+		s.record.Flashes = make(map[string][]any)
+	}
+
+	s.record.Flashes[c] = append(s.record.Flashes[c], value)
+	s.isModified = true
+}
+
+// Flashes returns and removes the flash messages of the given category.
+func (s *Session) Flashes(category ...string) []any {
+	c := defaultFlashCategory
+	if len(category) > 0 {
+		c = category[0]
+	}
+
+	flashes, exists := s.record.Flashes[c]
+	if !exists {
+		return nil
+	}
+
+	delete(s.record.Flashes, c)
+	s.isModified = true
+
+	return flashes
+}
+
+// Regenerate replaces the id of the session, while the values are kept.
+// It must be called when the privilege level changes – eg. on login –,
+// so a session id fixated by an attacker is not authenticated.
+func (s *Session) Regenerate() {
+	if !s.isNew && s.previousId == "" {
+		s.previousId = s.record.Id
+	}
+
+	s.record.Id = newSessionId()
+	s.record.CreatedAt = time.Now()
+	s.isModified = true
+}
+
+// Destroy deletes the session from the store and the client, eg. on logout.
+// The subsequent modifications are saved in a new session.
+func (s *Session) Destroy() {
+	if !s.isNew && s.previousId == "" {
+		s.previousId = s.record.Id
+	}
+
+	*s = Session{
+		record:      newSession(time.Now()).record,
+		previousId:  s.previousId,
+		isNew:       true,
+		isDestroyed: true,
+	}
+}
+
+// SessionCSRFStore returns a CSRFStore, which stores the tokens in the
+// session, so the CSRF middleware must be registered after the Sessions.
+func SessionCSRFStore() CSRFStore {
+	return sessionCSRFStore{}
+}
+
+type sessionCSRFStore struct{}
+
+func (sessionCSRFStore) GetToken(ctx gorouter.Context) (string, error) {
+	s, ok := GetSession(ctx)
+	if !ok {
+		return "", ErrSessionsNotRegistered
+	}
+	return s.GetString(csrfSessionKey), nil
+}
+
+func (sessionCSRFStore) SaveToken(ctx gorouter.Context, token string) error {
+	s, ok := GetSession(ctx)
+	if !ok {
+		return ErrSessionsNotRegistered
+	}
+	s.Set(csrfSessionKey, token)
+	return nil
+}
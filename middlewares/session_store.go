@@ -0,0 +1,343 @@
+package middlewares
+
+import (
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	defaultMemorySessionStoreSize int = 100_000
+
+	// The expired sessions are swept at most this often.
+	sessionSweepInterval time.Duration = time.Minute
+
+	// The label of the encrypted session cookies.
+	cookieSessionLabel string = "gorouter session"
+
+	sessionFileExt string = ".session"
+)
+
+// MemorySessionStoreConfig is the configuration of the in-memory session store.
+type MemorySessionStoreConfig struct {
+	// The maximum count of the stored sessions. Beyond it the least recently
+	// used sessions are evicted. By default it is 100 000.
+	MaxEntries int
+}
+
+type memorySession struct {
+	id        string
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemorySessionStore stores the sessions in the memory of the process,
+// so they are lost on restart, and not shared between the instances.
+type MemorySessionStore struct {
+	maxEntries int
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List
+	lastSweep time.Time
+}
+
+var _ SessionStore = (*MemorySessionStore)(nil)
+
+// NewMemorySessionStore creates and returns a new in-memory session store.
+func NewMemorySessionStore(conf ...MemorySessionStoreConfig) *MemorySessionStore {
+	var c MemorySessionStoreConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = defaultMemorySessionStoreSize
+	}
+
+	return &MemorySessionStore{
+		maxEntries: c.MaxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		lastSweep:  time.Now(),
+	}
+}
+
+// Load returns the encoded session of the given id.
+func (ms *MemorySessionStore) Load(_ gorouter.Context, id string) ([]byte, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	e, exists := ms.entries[id]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	s := e.Value.(*memorySession)
+	if time.Now().After(s.expiresAt) {
+		ms.remove(e)
+
+		return nil, ErrSessionNotFound
+	}
+
+	ms.lru.MoveToFront(e)
+
+	return s.data, nil
+}
+
+// Save stores the encoded session, then returns its id.
+func (ms *MemorySessionStore) Save(_ gorouter.Context, id string, data []byte, ttl time.Duration) (string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(ms.lastSweep) > sessionSweepInterval {
+		ms.sweep(now)
+	}
+
+	s := &memorySession{id: id, data: data, expiresAt: now.Add(ttl)}
+
+	if e, exists := ms.entries[id]; exists {
+		e.Value = s
+		ms.lru.MoveToFront(e)
+
+		return id, nil
+	}
+
+	ms.entries[id] = ms.lru.PushFront(s)
+
+	for ms.lru.Len() > ms.maxEntries {
+		ms.remove(ms.lru.Back())
+	}
+
+	return id, nil
+}
+
+// Delete deletes the session with the given id.
+func (ms *MemorySessionStore) Delete(_ gorouter.Context, id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if e, exists := ms.entries[id]; exists {
+		ms.remove(e)
+	}
+
+	return nil
+}
+
+// Len returns the count of the stored sessions.
+func (ms *MemorySessionStore) Len() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	return ms.lru.Len()
+}
+
+func (ms *MemorySessionStore) remove(e *list.Element) {
+	ms.lru.Remove(e)
+	delete(ms.entries, e.Value.(*memorySession).id)
+}
+
+func (ms *MemorySessionStore) sweep(now time.Time) {
+	ms.lastSweep = now
+
+	for e := ms.lru.Back(); e != nil; {
+		prev := e.Prev()
+
+		if now.After(e.Value.(*memorySession).expiresAt) {
+			ms.remove(e)
+		}
+
+		e = prev
+	}
+}
+
+// FileSessionStore stores the sessions in the files of a directory,
+// so they survive the restarts. Each session is a separate file.
+type FileSessionStore struct {
+	dir string
+}
+
+var _ SessionStore = (*FileSessionStore)(nil)
+
+// NewFileSessionStore creates and returns a new session store,
+// which stores the sessions in the given directory.
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// Load returns the encoded session of the given id.
+func (fs *FileSessionStore) Load(_ gorouter.Context, id string) ([]byte, error) {
+	path, ok := fs.getPath(id)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The first 8 bytes are the time of the expiration.
+	if len(b) < 8 || time.Now().UnixNano() > int64(binary.BigEndian.Uint64(b)) {
+		os.Remove(path)
+
+		return nil, ErrSessionNotFound
+	}
+
+	return b[8:], nil
+}
+
+// Save stores the encoded session, then returns its id.
+func (fs *FileSessionStore) Save(_ gorouter.Context, id string, data []byte, ttl time.Duration) (string, error) {
+	path, ok := fs.getPath(id)
+	if !ok {
+		return "", ErrSessionNotFound
+	}
+
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Add(ttl).UnixNano()))
+	b = append(b, data...)
+
+	// The file is replaced atomically, so a concurrent
+	// request never reads a partially written session.
+	f, err := os.CreateTemp(fs.dir, "tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Delete deletes the session with the given id.
+func (fs *FileSessionStore) Delete(_ gorouter.Context, id string) error {
+	path, ok := fs.getPath(id)
+	if !ok {
+		return nil
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Cleanup deletes the files of the expired sessions.
+// It should be called periodically.
+func (fs *FileSessionStore) Cleanup() error {
+	paths, err := filepath.Glob(filepath.Join(fs.dir, "*"+sessionFileExt))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixNano()
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		var expiresAt int64
+		err = binary.Read(f, binary.BigEndian, &expiresAt)
+		f.Close()
+
+		if err != nil || now > expiresAt {
+			os.Remove(path)
+		}
+	}
+
+	return nil
+}
+
+// getPath returns the path of the file of the given id. Since the id comes
+// from the cookie, only the ids generated by the sessions are accepted.
+func (fs *FileSessionStore) getPath(id string) (string, bool) {
+	if len(id) == 0 || len(id) > 2*sessionIdSize {
+		return "", false
+	}
+
+	for _, c := range []byte(id) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return "", false
+		}
+	}
+
+	return filepath.Join(fs.dir, id+sessionFileExt), true
+}
+
+// CookieSessionStore stores the whole session encrypted in the cookie,
+// so nothing is stored on the server. The size of the session is limited
+// by the size of the cookie, and the deleted sessions could not be revoked
+// until they expire – the expiration is checked by the encrypted timestamp.
+type CookieSessionStore struct {
+	keyRing *gorouter.KeyRing
+}
+
+var _ SessionStore = (*CookieSessionStore)(nil)
+
+// NewCookieSessionStore creates and returns a new session
+// store, which encrypts the sessions by the given key ring.
+func NewCookieSessionStore(kr *gorouter.KeyRing) *CookieSessionStore {
+	return &CookieSessionStore{keyRing: kr}
+}
+
+// Load decrypts the encoded session from the value of the cookie.
+func (cs *CookieSessionStore) Load(_ gorouter.Context, value string) ([]byte, error) {
+	b, err := cs.keyRing.Decrypt(cookieSessionLabel, value)
+	if err != nil || len(b) < 8 || time.Now().UnixNano() > int64(binary.BigEndian.Uint64(b)) {
+		return nil, ErrSessionNotFound
+	}
+
+	return b[8:], nil
+}
+
+// Save encrypts the encoded session, which becomes the value of the cookie.
+func (cs *CookieSessionStore) Save(_ gorouter.Context, _ string, data []byte, ttl time.Duration) (string, error) {
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Add(ttl).UnixNano()))
+	b = append(b, data...)
+
+	return cs.keyRing.Encrypt(cookieSessionLabel, b)
+}
+
+// Delete does nothing, since the session is only stored by the client.
+func (cs *CookieSessionStore) Delete(_ gorouter.Context, _ string) error {
+	return nil
+}
@@ -0,0 +1,434 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+// sessionClient keeps the cookies between the requests like a browser.
+type sessionClient struct {
+	r       gorouter.Router
+	cookies map[string]*http.Cookie
+}
+
+func (sc *sessionClient) do(method string, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	for _, c := range sc.cookies {
+		req.AddCookie(c)
+	}
+
+	rec := httptest.NewRecorder()
+	sc.r.ServeHTTP(rec, req)
+
+	for _, c := range rec.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(sc.cookies, c.Name)
+			continue
+		}
+		sc.cookies[c.Name] = c
+	}
+
+	return rec
+}
+
+func newSessionRouter(store SessionStore, conf ...SessionConfig) *sessionClient {
+	r := gorouter.New()
+
+	r.RegisterMiddlewares(Sessions(store, conf...))
+
+	r.Post("/login", func(ctx gorouter.Context) {
+		s, _ := GetSession(ctx)
+		s.Regenerate()
+		s.Set("user", "john")
+		s.AddFlash("welcome")
+	})
+
+	r.Post("/logout", func(ctx gorouter.Context) {
+		s, _ := GetSession(ctx)
+		s.Destroy()
+	})
+
+	r.Get("/me", func(ctx gorouter.Context) {
+		s, _ := GetSession(ctx)
+
+		var flashes []string
+		for _, f := range s.Flashes() {
+			flashes = append(flashes, f.(string))
+		}
+
+		ctx.Copy(strings.NewReader(s.GetString("user") + ";" + strings.Join(flashes, ",")))
+	})
+
+	r.Get("/id", func(ctx gorouter.Context) {
+		s, _ := GetSession(ctx)
+		ctx.Copy(strings.NewReader(s.Id()))
+	})
+
+	return &sessionClient{r: r, cookies: make(map[string]*http.Cookie)}
+}
+
+func TestSessions(t *testing.T) {
+	type testCase struct {
+		name  string
+		store func(t *testing.T) SessionStore
+	}
+
+	tt := []testCase{
+		{
+			name: "memory store",
+			store: func(t *testing.T) SessionStore {
+				return NewMemorySessionStore()
+			},
+		},
+		{
+			name: "file store",
+			store: func(t *testing.T) SessionStore {
+				fs, err := NewFileSessionStore(t.TempDir())
+				if err != nil {
+					t.Fatal(err)
+				}
+				return fs
+			},
+		},
+		{
+			name: "cookie store",
+			store: func(t *testing.T) SessionStore {
+				kr, err := gorouter.NewKeyRing([]byte(strings.Repeat("k", 32)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return NewCookieSessionStore(kr)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				store = tc.store(t)
+				c     = newSessionRouter(store)
+			)
+
+			if rec := c.do(http.MethodGet, "/me"); rec.Body.String() != ";" || len(c.cookies) != 0 {
+				t.Fatalf("expected empty session without cookie; got: %q, %v\n", rec.Body.String(), c.cookies)
+			}
+
+			c.do(http.MethodPost, "/login")
+
+			cookie := c.cookies[defaultSessionCookieName]
+			if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("expected http only session cookie; got: %v\n", cookie)
+			}
+
+			if got := c.do(http.MethodGet, "/me").Body.String(); got != "john;welcome" {
+				t.Errorf("expected body: %q; got: %q\n", "john;welcome", got)
+			}
+
+			// The flash messages are read only once.
+			if got := c.do(http.MethodGet, "/me").Body.String(); got != "john;" {
+				t.Errorf("expected body: %q; got: %q\n", "john;", got)
+			}
+
+			// The old id is not valid after the regeneration.
+			firstId := c.do(http.MethodGet, "/id").Body.String()
+			c.do(http.MethodPost, "/login")
+
+			if secondId := c.do(http.MethodGet, "/id").Body.String(); secondId == firstId {
+				t.Errorf("expected new id after regeneration; got: %q\n", secondId)
+			}
+
+			if _, isCookieStore := store.(*CookieSessionStore); !isCookieStore {
+				if _, err := store.Load(nil, firstId); err != ErrSessionNotFound {
+					t.Errorf("expected error: %v; got: %v\n", ErrSessionNotFound, err)
+				}
+			}
+
+			c.do(http.MethodPost, "/logout")
+
+			if _, exists := c.cookies[defaultSessionCookieName]; exists {
+				t.Errorf("expected deleted cookie after logout\n")
+			}
+
+			if got := c.do(http.MethodGet, "/me").Body.String(); got != ";" {
+				t.Errorf("expected body: %q; got: %q\n", ";", got)
+			}
+		})
+	}
+}
+
+func TestSessionCookieDefaults(t *testing.T) {
+	type testCase struct {
+		name string
+		conf SessionConfig
+
+		expectedName     string
+		expectedHttpOnly bool
+	}
+
+	tt := []testCase{
+		{
+			name:             "the default cookie is http only",
+			expectedName:     defaultSessionCookieName,
+			expectedHttpOnly: true,
+		},
+		{
+			name:             "the cookie with custom name is http only",
+			conf:             SessionConfig{Cookie: http.Cookie{Name: "sid", Secure: true}},
+			expectedName:     "sid",
+			expectedHttpOnly: true,
+		},
+		{
+			name:             "the cookie is readable by the scripts if it is allowed",
+			conf:             SessionConfig{Cookie: http.Cookie{Name: "sid"}, AllowScriptAccess: true},
+			expectedName:     "sid",
+			expectedHttpOnly: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newSessionRouter(NewMemorySessionStore(), tc.conf)
+			c.do(http.MethodPost, "/login")
+
+			cookie := c.cookies[tc.expectedName]
+			if cookie == nil {
+				t.Fatalf("expected cookie: %s; got: %v\n", tc.expectedName, c.cookies)
+			}
+
+			if cookie.HttpOnly != tc.expectedHttpOnly {
+				t.Errorf("expected http only: %t; got: %t\n", tc.expectedHttpOnly, cookie.HttpOnly)
+			}
+		})
+	}
+}
+
+func TestSessionPanic(t *testing.T) {
+	c := newSessionRouter(NewMemorySessionStore())
+
+	c.r.Post("/panic", func(ctx gorouter.Context) {
+		s, _ := GetSession(ctx)
+		s.Regenerate()
+		s.Set("user", "john")
+
+		panic("boom")
+	})
+
+	rec := c.do(http.MethodPost, "/panic")
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusInternalServerError, rec.Code)
+	}
+
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no cookies; got: %v\n", cookies)
+	}
+}
+
+// failingSessionStore fails to save every session.
+type failingSessionStore struct {
+	SessionStore
+}
+
+func (fs *failingSessionStore) Save(gorouter.Context, string, []byte, time.Duration) (string, error) {
+	return "", gorouter.ErrCookieTooLarge
+}
+
+func TestSessionSaveError(t *testing.T) {
+	type testCase struct {
+		name    string
+		handler gorouter.HandlerFunc
+
+		expectedStatusCode int
+		expectedBody       string
+	}
+
+	tt := []testCase{
+		{
+			name: "replaces the buffered response",
+			handler: func(ctx gorouter.Context) {
+				ctx.SendJson(http.StatusOK, map[string]string{"ok": "yes"})
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name: "replaces the response before it is flushed",
+			handler: func(ctx gorouter.Context) {
+				ctx.SendJson(http.StatusOK, map[string]string{"ok": "yes"})
+				ctx.Flush()
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       http.StatusText(http.StatusInternalServerError),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gorouter.New()
+
+			r.RegisterMiddlewares(Sessions(&failingSessionStore{SessionStore: NewMemorySessionStore()}))
+
+			r.Post("/login", func(ctx gorouter.Context) {
+				s, _ := GetSession(ctx)
+				s.Set("user", "john")
+
+				tc.handler(ctx)
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+
+			if got := rec.Header().Values("Content-Type"); len(got) != 1 || got[0] != "text/plain" {
+				t.Errorf("expected content type: %q; got: %q\n", "text/plain", got)
+			}
+
+			if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+				t.Errorf("expected no cookies; got: %v\n", cookies)
+			}
+		})
+	}
+}
+
+func TestSessionTimeouts(t *testing.T) {
+	type testCase struct {
+		name string
+		conf SessionConfig
+		wait time.Duration
+
+		expectedBody string
+	}
+
+	tt := []testCase{
+		{
+			name:         "the session is kept within the timeouts",
+			conf:         SessionConfig{IdleTimeout: time.Second, AbsoluteTimeout: time.Second},
+			expectedBody: "john;",
+		},
+		{
+			name:         "the session expires after the idle timeout",
+			conf:         SessionConfig{IdleTimeout: 20 * time.Millisecond, AbsoluteTimeout: time.Second},
+			wait:         40 * time.Millisecond,
+			expectedBody: ";",
+		},
+		{
+			name:         "the session expires after the absolute timeout",
+			conf:         SessionConfig{IdleTimeout: time.Second, AbsoluteTimeout: 20 * time.Millisecond},
+			wait:         40 * time.Millisecond,
+			expectedBody: ";",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newSessionRouter(NewMemorySessionStore(), tc.conf)
+
+			c.do(http.MethodPost, "/login")
+			c.do(http.MethodGet, "/me")
+
+			time.Sleep(tc.wait)
+
+			if got := c.do(http.MethodGet, "/me").Body.String(); got != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, got)
+			}
+		})
+	}
+}
+
+func TestMemorySessionStoreEviction(t *testing.T) {
+	ms := NewMemorySessionStore(MemorySessionStoreConfig{MaxEntries: 2})
+
+	for _, id := range []string{"a", "b"} {
+		ms.Save(nil, id, []byte(id), time.Minute)
+	}
+
+	// Using the first one, so the second one is the least recently used.
+	ms.Load(nil, "a")
+	ms.Save(nil, "c", []byte("c"), time.Minute)
+
+	if ms.Len() != 2 {
+		t.Errorf("expected len: %d; got: %d\n", 2, ms.Len())
+	}
+
+	if _, err := ms.Load(nil, "b"); err != ErrSessionNotFound {
+		t.Errorf("expected evicted session; got: %v\n", err)
+	}
+
+	if _, err := ms.Load(nil, "a"); err != nil {
+		t.Errorf("expected kept session; got: %v\n", err)
+	}
+}
+
+func TestFileSessionStorePath(t *testing.T) {
+	fs, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"../../etc/passwd", "a/b", "", strings.Repeat("a", 100)} {
+		if _, err := fs.Save(nil, id, []byte("x"), time.Minute); err == nil {
+			t.Errorf("expected error for id: %q\n", id)
+		}
+
+		if _, err := fs.Load(nil, id); err != ErrSessionNotFound {
+			t.Errorf("expected error: %v; got: %v\n", ErrSessionNotFound, err)
+		}
+	}
+
+	fs.Save(nil, "expired", []byte("x"), -time.Second)
+
+	if err := fs.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.Load(nil, "expired"); err != ErrSessionNotFound {
+		t.Errorf("expected error: %v; got: %v\n", ErrSessionNotFound, err)
+	}
+}
+
+func TestSessionCSRFStore(t *testing.T) {
+	r := gorouter.New()
+
+	r.RegisterMiddlewares(
+		Sessions(NewMemorySessionStore()),
+		CSRF(CSRFConfig{Store: SessionCSRFStore()}),
+	)
+
+	r.Get("/form", func(ctx gorouter.Context) {
+		ctx.Copy(strings.NewReader(GetCSRFToken(ctx)))
+	})
+	r.Post("/form", func(ctx gorouter.Context) {})
+
+	c := &sessionClient{r: r, cookies: make(map[string]*http.Cookie)}
+
+	token := c.do(http.MethodGet, "/form").Body.String()
+
+	if _, exists := c.cookies[defaultCSRFCookieName]; exists {
+		t.Errorf("expected no csrf cookie with session store\n")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/form", nil)
+	req.Header.Set(defaultCSRFHeaderName, token)
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusOK, rec.Code)
+	}
+}
@@ -0,0 +1,232 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	defaultBacklogTimeout   time.Duration = 5 * time.Second
+	defaultOverloadInterval time.Duration = 100 * time.Millisecond
+)
+
+var (
+	ErrInvalidThrottleLimit = errors.New("the limit of the throttle must be positive")
+)
+
+// ThrottleConfig is the configuration of the throttling middleware.
+type ThrottleConfig struct {
+	// The maximum count of the requests executed at the same time. Required.
+	Limit int
+
+	// The maximum count of the requests waiting for execution.
+	// The requests beyond it are rejected right away.
+	Backlog int
+
+	// The maximum time a request could wait in the backlog.
+	// By default it is 5 seconds.
+	BacklogTimeout time.Duration
+
+	// If it is set, then the requests are shed in a CoDel-style manner based
+	// on the observed latency – the time from getting a slot until the response
+	// is written: once every request completed during the OverloadInterval was
+	// slower than the TargetLatency, the process is considered overloaded, and
+	// the requests are not queued – they are rejected, unless a slot is free –,
+	// until a request completes within the target again.
+	TargetLatency time.Duration
+
+	// The interval of the overload detection. By default it is 100ms.
+	OverloadInterval time.Duration
+
+	// Whether the requests of each registered route are throttled
+	// separately, in case of the middleware is registered globally.
+	PerRoute bool
+
+	// If it is set, then the Retry-After header is sent on rejection.
+	RetryAfter time.Duration
+
+	// The handler called when the request is rejected.
+	// By default 503 is responded.
+	OnRejected gorouter.HandlerFunc
+}
+
+type throttler struct {
+	conf  *ThrottleConfig
+	slots chan struct{}
+
+	mu      sync.Mutex
+	waiting int
+	// Since when the completed requests have been slower than the
+	// target latency. It is zero, if the last one was within the target.
+	slowSince time.Time
+}
+
+// Throttle creates and returns a middleware, which limits the count of
+// the requests executed at the same time. The requests beyond the limit
+// wait in a backlog, while the requests beyond the backlog are rejected.
+func Throttle(conf ThrottleConfig) gorouter.Middleware {
+	if conf.Limit <= 0 {
+		panic(ErrInvalidThrottleLimit)
+	}
+
+	if conf.BacklogTimeout <= 0 {
+		conf.BacklogTimeout = defaultBacklogTimeout
+	}
+
+	if conf.OverloadInterval <= 0 {
+		conf.OverloadInterval = defaultOverloadInterval
+	}
+
+	if conf.OnRejected == nil {
+		conf.OnRejected = func(ctx gorouter.Context) {
+			ctx.StatusText(http.StatusServiceUnavailable)
+		}
+	}
+
+	var (
+		mu         sync.Mutex
+		throttlers = make(map[string]*throttler)
+		global     = newThrottler(&conf)
+	)
+
+	var getThrottler = func(ctx gorouter.Context) *throttler {
+		if !conf.PerRoute {
+			return global
+		}
+
+		key := ctx.GetRequestMethod() + " " + ctx.GetRegisteredUrl()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		t, ok := throttlers[key]
+		if !ok {
+			t = newThrottler(&conf)
+			throttlers[key] = t
+		}
+
+		return t
+	}
+
+	return gorouter.NewMiddleware(
+		func(ctx gorouter.Context) {
+			t := getThrottler(ctx)
+
+			if !t.acquire(ctx) {
+				if conf.RetryAfter > 0 {
+					ctx.GetResponseHeaders().Set(retryAfterHeaderKey, formatSeconds(conf.RetryAfter))
+				}
+
+				conf.OnRejected(ctx)
+
+				return
+			}
+
+			// The slot is released once the response is written,
+			// since the handler runs after the middleware returns.
+			start := time.Now()
+			ctx.Defer(func() {
+				now := time.Now()
+				t.release(now, now.Sub(start))
+			})
+
+			ctx.Next()
+		},
+		gorouter.MiddlewareWithType(gorouter.MiddlewarePreRunner),
+	)
+}
+
+func newThrottler(conf *ThrottleConfig) *throttler {
+	return &throttler{
+		conf:  conf,
+		slots: make(chan struct{}, conf.Limit),
+	}
+}
+
+// acquire takes a slot, waiting in the backlog if necessary.
+// It returns false if the request is rejected.
+func (t *throttler) acquire(ctx gorouter.Context) bool {
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	timeout, ok := t.enqueue(time.Now())
+	if !ok {
+		return false
+	}
+	defer t.dequeue()
+
+	var done <-chan struct{}
+	if r := ctx.GetRequest(); r != nil {
+		done = r.Context().Done()
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-done:
+		return false
+	}
+}
+
+// release frees the slot, and observes the latency of the request.
+func (t *throttler) release(now time.Time, latency time.Duration) {
+	<-t.slots
+
+	if t.conf.TargetLatency <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case latency <= t.conf.TargetLatency:
+		t.slowSince = time.Time{}
+	case t.slowSince.IsZero():
+		t.slowSince = now
+	}
+}
+
+// enqueue puts the request into the backlog, and returns how long it could wait.
+func (t *throttler) enqueue(now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.waiting >= t.conf.Backlog || t.isOverloaded(now) {
+		return 0, false
+	}
+
+	t.waiting++
+
+	return t.conf.BacklogTimeout, true
+}
+
+func (t *throttler) dequeue() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.waiting--
+}
+
+// isOverloaded returns whether the completed requests have been
+// slower than the target latency for the overload interval.
+// It must be called while the mutex is held.
+func (t *throttler) isOverloaded(now time.Time) bool {
+	if t.conf.TargetLatency <= 0 || t.slowSince.IsZero() {
+		return false
+	}
+
+	return now.Sub(t.slowSince) >= t.conf.OverloadInterval
+}
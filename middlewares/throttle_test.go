@@ -0,0 +1,202 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestThrottle(t *testing.T) {
+	var (
+		started = make(chan struct{}, 3)
+		unblock = make(chan struct{})
+
+		r = gorouter.New()
+	)
+
+	r.RegisterMiddlewares(Throttle(ThrottleConfig{
+		Limit:          1,
+		Backlog:        1,
+		BacklogTimeout: time.Second,
+		RetryAfter:     2 * time.Second,
+	}))
+
+	r.Get("/api/batch", func(ctx gorouter.Context) {
+		started <- struct{}{}
+		<-unblock
+		ctx.Status(http.StatusOK)
+	})
+
+	var (
+		wg    sync.WaitGroup
+		codes = make([]int, 2)
+	)
+
+	for i := range codes {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/batch", nil))
+			codes[i] = rec.Code
+		}(i)
+
+		// The first request must occupy the slot, before the second is sent.
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// Waiting for the second request to get into the backlog.
+	time.Sleep(50 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/batch", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After: %q; got: %q\n", "2", got)
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	for i, c := range codes {
+		if c != http.StatusOK {
+			t.Errorf("request %d: expected status code: %d; got: %d\n", i, http.StatusOK, c)
+		}
+	}
+}
+
+func TestThrottleBacklogTimeout(t *testing.T) {
+	type testCase struct {
+		name string
+		conf ThrottleConfig
+		// The time the completed requests were slower than the target.
+		slowFor time.Duration
+
+		expectedTimeout time.Duration
+		expectedOk      bool
+	}
+
+	tt := []testCase{
+		{
+			name:            "uses the backlog timeout by default",
+			conf:            ThrottleConfig{Limit: 1, Backlog: 2, BacklogTimeout: time.Second, OverloadInterval: time.Second},
+			expectedTimeout: time.Second,
+			expectedOk:      true,
+		},
+		{
+			name:            "rejects if the backlog is disabled",
+			conf:            ThrottleConfig{Limit: 1, Backlog: 0, BacklogTimeout: time.Second, OverloadInterval: time.Second},
+			expectedTimeout: 0,
+			expectedOk:      false,
+		},
+		{
+			name: "sheds the request once overloaded",
+			conf: ThrottleConfig{
+				Limit:            1,
+				Backlog:          2,
+				BacklogTimeout:   time.Second,
+				TargetLatency:    5 * time.Millisecond,
+				OverloadInterval: 100 * time.Millisecond,
+			},
+			slowFor:         200 * time.Millisecond,
+			expectedTimeout: 0,
+			expectedOk:      false,
+		},
+		{
+			name: "queues the request before overloaded",
+			conf: ThrottleConfig{
+				Limit:            1,
+				Backlog:          2,
+				BacklogTimeout:   time.Second,
+				TargetLatency:    5 * time.Millisecond,
+				OverloadInterval: 100 * time.Millisecond,
+			},
+			slowFor:         50 * time.Millisecond,
+			expectedTimeout: time.Second,
+			expectedOk:      true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				th  = newThrottler(&tc.conf)
+				now = time.Now()
+			)
+
+			if tc.slowFor > 0 {
+				th.slowSince = now.Add(-tc.slowFor)
+			}
+
+			timeout, ok := th.enqueue(now)
+
+			if ok != tc.expectedOk {
+				t.Fatalf("expected ok: %v; got: %v\n", tc.expectedOk, ok)
+			}
+
+			if timeout != tc.expectedTimeout {
+				t.Errorf("expected timeout: %v; got: %v\n", tc.expectedTimeout, timeout)
+			}
+		})
+	}
+}
+
+func TestThrottleLatency(t *testing.T) {
+	type testCase struct {
+		name      string
+		latencies []time.Duration
+
+		expectedOverloaded bool
+	}
+
+	tt := []testCase{
+		{
+			name:               "is overloaded if every request was slow for the interval",
+			latencies:          []time.Duration{time.Second, time.Second, time.Second},
+			expectedOverloaded: true,
+		},
+		{
+			name:               "is not overloaded after a fast request",
+			latencies:          []time.Duration{time.Second, time.Second, time.Millisecond},
+			expectedOverloaded: false,
+		},
+		{
+			name:               "is not overloaded if the slow requests are recent",
+			latencies:          []time.Duration{time.Millisecond, time.Millisecond, time.Second},
+			expectedOverloaded: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				th  = newThrottler(&ThrottleConfig{Limit: 1, TargetLatency: 100 * time.Millisecond, OverloadInterval: 100 * time.Millisecond})
+				now = time.Now()
+			)
+
+			// The requests complete every 60ms.
+			for _, latency := range tc.latencies {
+				now = now.Add(60 * time.Millisecond)
+
+				th.slots <- struct{}{}
+				th.release(now, latency)
+			}
+
+			if got := th.isOverloaded(now); got != tc.expectedOverloaded {
+				t.Errorf("expected overloaded: %v; got: %v\n", tc.expectedOverloaded, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+var (
+	ErrInvalidTimeout = errors.New("the timeout must be positive")
+)
+
+// TimeoutConfig is the configuration of the timeout middleware.
+type TimeoutConfig struct {
+	// The status code of the response, if the timeout is hit.
+	// By default it is 503, but eg. 504 could be used by gateways.
+	StatusCode int
+
+	// The handler writing the response, if the timeout is hit.
+	// By default the status text of the StatusCode is responded.
+	OnTimeout gorouter.HandlerFunc
+}
+
+// Timeout creates and returns a middleware, which bounds the execution
+// of the rest of the chain with the given timeout. The deadline is derived
+// on the context of the request, so the handlers should pass it – available
+// by ctx.GetRequestContext() – to the blocking calls. If the deadline is hit,
+// then the timeout response is written, while everything written by the
+// handler afterwards is discarded.
+//
+// The middlewares wrapping the response writer – eg. Compress – must be
+// registered after it, so their writes are guarded by the timeout as well.
+func Timeout(d time.Duration, conf ...TimeoutConfig) gorouter.Middleware {
+	if d <= 0 {
+		panic(ErrInvalidTimeout)
+	}
+
+	var c TimeoutConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.StatusCode == 0 {
+		c.StatusCode = http.StatusServiceUnavailable
+	}
+
+	if c.OnTimeout == nil {
+		c.OnTimeout = func(ctx gorouter.Context) {
+			ctx.StatusText(c.StatusCode)
+		}
+	}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		ctx.SetTimeout(d, c.OnTimeout)
+
+		ctx.Next()
+	})
+}
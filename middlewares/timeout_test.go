@@ -0,0 +1,111 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+func TestTimeout(t *testing.T) {
+	type testCase struct {
+		name  string
+		conf  []TimeoutConfig
+		delay time.Duration
+
+		expectedStatusCode int
+		expectedBody       string
+	}
+
+	tt := []testCase{
+		{
+			name:               "the handler finishes in time",
+			delay:              0,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "done",
+		},
+		{
+			name:               "responds with 503 by default",
+			delay:              200 * time.Millisecond,
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       http.StatusText(http.StatusServiceUnavailable),
+		},
+		{
+			name:               "responds with the configured status code",
+			conf:               []TimeoutConfig{{StatusCode: http.StatusGatewayTimeout}},
+			delay:              200 * time.Millisecond,
+			expectedStatusCode: http.StatusGatewayTimeout,
+			expectedBody:       http.StatusText(http.StatusGatewayTimeout),
+		},
+		{
+			name: "responds with the configured handler",
+			conf: []TimeoutConfig{{OnTimeout: func(ctx gorouter.Context) {
+				ctx.SendJson(http.StatusGatewayTimeout, map[string]string{"error": "timeout"})
+			}}},
+			delay:              200 * time.Millisecond,
+			expectedStatusCode: http.StatusGatewayTimeout,
+			expectedBody:       "{\"error\":\"timeout\"}\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gorouter.New()
+
+			r.RegisterMiddlewares(Timeout(50*time.Millisecond, tc.conf...))
+
+			r.Get("/api/report", func(ctx gorouter.Context) {
+				time.Sleep(tc.delay)
+
+				ctx.Copy(strings.NewReader("done"))
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/report", nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTimeoutWithCompress(t *testing.T) {
+	r := gorouter.New()
+
+	r.RegisterMiddlewares(
+		Timeout(50*time.Millisecond),
+		Compress(CompressConfig{MinSize: 1}),
+	)
+
+	r.Get("/api/report", func(ctx gorouter.Context) {
+		time.Sleep(100 * time.Millisecond)
+
+		ctx.AppendHttpHeader(contentTypeHeaderKey, "text/plain")
+		ctx.Copy(strings.NewReader(strings.Repeat("late", 1024)))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/report", nil)
+	req.Header.Set(acceptEncodingHeaderKey, EncodingGzip)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if got := rec.Header().Get(contentEncodingHeaderKey); got != "" {
+		t.Errorf("expected content encoding: %q; got: %q\n", "", got)
+	}
+
+	// Letting the handler finish, so the race detector could catch its writes.
+	time.Sleep(100 * time.Millisecond)
+}
@@ -0,0 +1,437 @@
+package middlewares
+
+import (
+	ctxpkg "context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	TraceparentHeaderKey string = "traceparent"
+	TracestateHeaderKey  string = "tracestate"
+
+	// The length of the traceparent of version 00.
+	traceparentLength int = 55
+
+	// The maximum length of the tracestate, which is propagated.
+	maxTracestateLength int = 512
+)
+
+var (
+	ErrInvalidTraceparent = errors.New("the traceparent is invalid")
+)
+
+type (
+	TraceID    [16]byte
+	SpanID     [8]byte
+	TraceFlags byte
+)
+
+// TraceFlagsSampled marks the traces, which are recorded by the caller.
+const TraceFlagsSampled TraceFlags = 0x01
+
+// String returns the lowercase hex encoding of the id.
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// IsValid returns whether the id is not all zeros.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+// String returns the lowercase hex encoding of the id.
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// IsValid returns whether the id is not all zeros.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+// SpanContext identifies a span across the process boundaries –
+// it is what the traceparent and the tracestate headers carry.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      TraceFlags
+	TraceState string
+
+	// Whether it has been extracted from an incoming request.
+	Remote bool
+}
+
+// IsValid returns whether both of the ids are valid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled returns whether the sampled flag is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&TraceFlagsSampled != 0
+}
+
+// Traceparent returns the value of the traceparent header of version 00.
+func (sc SpanContext) Traceparent() string {
+	var b strings.Builder
+	b.Grow(traceparentLength)
+
+	b.WriteString("00-")
+	b.WriteString(sc.TraceID.String())
+	b.WriteByte('-')
+	b.WriteString(sc.SpanID.String())
+	b.WriteByte('-')
+	b.WriteString(hex.EncodeToString([]byte{byte(sc.Flags)}))
+
+	return b.String()
+}
+
+// ParseTraceparent parses the value of the traceparent header – see
+// W3C Trace Context. The values of the future versions are accepted
+// as well, as long as they start with the fields of version 00.
+func ParseTraceparent(v string) (SpanContext, error) {
+	var sc SpanContext
+
+	if len(v) < traceparentLength || v[2] != '-' || v[35] != '-' || v[52] != '-' {
+		return sc, ErrInvalidTraceparent
+	}
+
+	version, ok := decodeLowerHex(v[0:2])
+	if !ok || version[0] == 0xff {
+		return sc, ErrInvalidTraceparent
+	}
+
+	if len(v) > traceparentLength && (version[0] == 0 || v[traceparentLength] != '-') {
+		return sc, ErrInvalidTraceparent
+	}
+
+	traceID, ok := decodeLowerHex(v[3:35])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	spanID, ok := decodeLowerHex(v[36:52])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	flags, ok := decodeLowerHex(v[53:55])
+	if !ok {
+		return sc, ErrInvalidTraceparent
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = TraceFlags(flags[0])
+
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	return sc, nil
+}
+
+// decodeLowerHex decodes the given hex, which
+// must consist of lowercase characters only.
+func decodeLowerHex(s string) ([]byte, bool) {
+	for _, c := range []byte(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, false
+		}
+	}
+
+	b, err := hex.DecodeString(s)
+
+	return b, err == nil
+}
+
+// ExtractTraceContext returns the span context of the traceparent
+// and the tracestate headers – if they are present and valid.
+func ExtractTraceContext(h http.Header) (SpanContext, bool) {
+	values := h.Values(TraceparentHeaderKey)
+	if len(values) != 1 {
+		return SpanContext{}, false
+	}
+
+	sc, err := ParseTraceparent(strings.TrimSpace(values[0]))
+	if err != nil {
+		return SpanContext{}, false
+	}
+
+	sc.Remote = true
+
+	// The tracestate could be split into multiple headers.
+	if ts := strings.Join(h.Values(TracestateHeaderKey), ","); len(ts) <= maxTracestateLength {
+		sc.TraceState = ts
+	}
+
+	return sc, true
+}
+
+// InjectTraceContext sets the traceparent and the tracestate
+// headers of the span of the given context – if there is any.
+func InjectTraceContext(c ctxpkg.Context, h http.Header) {
+	sc := SpanFromContext(c).SpanContext()
+	if !sc.IsValid() {
+		return
+	}
+
+	h.Set(TraceparentHeaderKey, sc.Traceparent())
+
+	if sc.TraceState != "" {
+		h.Set(TracestateHeaderKey, sc.TraceState)
+	} else {
+		h.Del(TracestateHeaderKey)
+	}
+}
+
+// PropagateTrace sets the trace context of the span of the given
+// Context on the given outgoing request, so the downstream
+// services continue the same trace.
+func PropagateTrace(ctx gorouter.Context, req *http.Request) {
+	InjectTraceContext(ctx.GetRequestContext(), req.Header)
+}
+
+type (
+	SpanKind   uint8
+	SpanStatus uint8
+)
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+const (
+	SpanStatusUnset SpanStatus = iota
+	SpanStatusOk
+	SpanStatusError
+)
+
+// Span is a single operation of a trace.
+type Span interface {
+	SpanContext() SpanContext
+	SetName(name string)
+	SetAttribute(key string, value any)
+	SetStatus(status SpanStatus, description string)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts the spans. The parent of the new span is the span of the given
+// context, or the remote span context of it – see ParentSpanContext. The adapters
+// of the tracing libraries – eg. OpenTelemetry – should implement it.
+type Tracer interface {
+	Start(c ctxpkg.Context, name string, kind SpanKind) (ctxpkg.Context, Span)
+}
+
+type traceContextKey uint8
+
+const (
+	spanContextKey traceContextKey = iota
+	remoteSpanContextKey
+	tracerContextKey
+)
+
+// ContextWithSpan returns a copy of the given context, which carries the given span.
+func ContextWithSpan(c ctxpkg.Context, span Span) ctxpkg.Context {
+	return ctxpkg.WithValue(c, spanContextKey, span)
+}
+
+// SpanFromContext returns the span of the given context. If there
+// is none, then a span, which does not record anything is returned.
+func SpanFromContext(c ctxpkg.Context) Span {
+	if span, ok := c.Value(spanContextKey).(Span); ok && span != nil {
+		return span
+	}
+	return noopSpan{}
+}
+
+// ContextWithRemoteSpanContext returns a copy of the given context, which
+// carries the given span context extracted from an incoming request.
+func ContextWithRemoteSpanContext(c ctxpkg.Context, sc SpanContext) ctxpkg.Context {
+	return ctxpkg.WithValue(c, remoteSpanContextKey, sc)
+}
+
+// ParentSpanContext returns the span context of the span of the
+// given context, or the remote span context – if there is any.
+func ParentSpanContext(c ctxpkg.Context) SpanContext {
+	if sc := SpanFromContext(c).SpanContext(); sc.IsValid() {
+		return sc
+	}
+
+	sc, _ := c.Value(remoteSpanContextKey).(SpanContext)
+
+	return sc
+}
+
+// StartSpan starts a child span of the span of the given context
+// with the tracer of the Tracing middleware, eg. in a handler:
+// c, span := middlewares.StartSpan(ctx.GetRequestContext(), "query users").
+func StartSpan(c ctxpkg.Context, name string) (ctxpkg.Context, Span) {
+	tracer, ok := c.Value(tracerContextKey).(Tracer)
+	if !ok || tracer == nil {
+		tracer = NoopTracer()
+	}
+
+	return tracer.Start(c, name, SpanKindInternal)
+}
+
+// GetSpan returns the span of the request started by the Tracing middleware.
+// If there is none, then a span, which does not record anything is returned.
+func GetSpan(ctx gorouter.Context) Span {
+	return SpanFromContext(ctx.GetRequestContext())
+}
+
+// NoopTracer returns a tracer, which does not record anything, but
+// the spans carry the parent span context, so the incoming trace
+// context is still propagated to the downstream services.
+func NoopTracer() Tracer {
+	return noopTracer{}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(c ctxpkg.Context, _ string, _ SpanKind) (ctxpkg.Context, Span) {
+	span := noopSpan{sc: ParentSpanContext(c)}
+
+	return ContextWithSpan(c, span), span
+}
+
+type noopSpan struct {
+	sc SpanContext
+}
+
+func (s noopSpan) SpanContext() SpanContext   { return s.sc }
+func (noopSpan) SetName(string)               {}
+func (noopSpan) SetAttribute(string, any)     {}
+func (noopSpan) SetStatus(SpanStatus, string) {}
+func (noopSpan) RecordError(error)            {}
+func (noopSpan) End()                         {}
+
+// TracingConfig is the configuration of the tracing middleware.
+type TracingConfig struct {
+	// The tracer of the spans. By default NoopTracer.
+	Tracer Tracer
+
+	// Whether the incoming trace context is ignored, eg. in case of a
+	// public facing service, where the clients must not join the traces.
+	IgnoreIncoming bool
+
+	// The name of the span of the request. By default the method
+	// and the registered url of the route, eg. GET /users/{id}.
+	SpanName func(ctx gorouter.Context) string
+
+	// Whether the trace context of the span of the request is sent back
+	// in the traceparent and tracestate headers of the response, eg. so
+	// the clients could report the id of the trace alongside the errors.
+	InjectResponse bool
+}
+
+// Tracing creates and returns a middleware, which continues the trace of
+// the traceparent and the tracestate headers – or starts a new one –, and
+// starts a span of the request. The span is available by GetSpan, and it
+// is carried by the context of the request, so the handlers could start
+// child spans by StartSpan. The status code of the response is recorded,
+// and the 5xx responses – and the panics of the chain – mark the span as failed.
+// With InjectResponse the trace context is sent back to the client as well.
+func Tracing(conf ...TracingConfig) gorouter.Middleware {
+	var c TracingConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	if c.Tracer == nil {
+		c.Tracer = NoopTracer()
+	}
+
+	if c.SpanName == nil {
+		c.SpanName = getSpanName
+	}
+
+	return gorouter.NewMiddleware(func(ctx gorouter.Context) {
+		reqCtx := ctxpkg.WithValue(ctx.GetRequestContext(), tracerContextKey, c.Tracer)
+
+		if !c.IgnoreIncoming {
+			if sc, ok := ExtractTraceContext(ctx.GetRequestHeaders()); ok {
+				reqCtx = ContextWithRemoteSpanContext(reqCtx, sc)
+			}
+		}
+
+		reqCtx, span := c.Tracer.Start(reqCtx, c.SpanName(ctx), SpanKindServer)
+
+		span.SetAttribute("http.request.method", ctx.GetRequestMethod())
+		span.SetAttribute("url.path", ctx.GetCleanedUrl())
+
+		if route := ctx.GetRegisteredUrl(); route != "" {
+			span.SetAttribute("http.route", route)
+		}
+
+		ctx.SetRequestContext(reqCtx)
+
+		if c.InjectResponse {
+			InjectTraceContext(reqCtx, ctx.GetResponseHeaders())
+		}
+
+		// The span is ended after the response is written,
+		// so the final status code is recorded.
+		ctx.Defer(func() {
+			info := ctx.GetInfo()
+
+			span.SetAttribute("http.response.status_code", info.StatusCode)
+			span.SetAttribute("http.response.body.size", info.WrittenBytes)
+
+			if val, ok := gorouter.GetPanic(ctx); ok {
+				err, isError := val.(error)
+				if !isError {
+					err = fmt.Errorf("panic: %v", val)
+				}
+
+				span.RecordError(err)
+				span.SetStatus(SpanStatusError, err.Error())
+			} else if info.StatusCode >= http.StatusInternalServerError {
+				span.SetStatus(SpanStatusError, http.StatusText(info.StatusCode))
+			}
+
+			span.End()
+		})
+
+		ctx.Next()
+	})
+}
+
+func getSpanName(ctx gorouter.Context) string {
+	// The non-standard methods are not part of the name,
+	// so the clients could not blow up the count of the names.
+	method := getMethodLabel(ctx.GetRequestMethod())
+
+	if route := ctx.GetRegisteredUrl(); route != "" {
+		return method + " " + route
+	}
+
+	return method
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
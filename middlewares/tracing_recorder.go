@@ -0,0 +1,159 @@
+package middlewares
+
+import (
+	ctxpkg "context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a span recorded by the SpanRecorder.
+type RecordedSpan struct {
+	Name        string
+	Kind        SpanKind
+	SpanContext SpanContext
+	Parent      SpanContext
+
+	Attributes        map[string]any
+	Status            SpanStatus
+	StatusDescription string
+	Errors            []error
+
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// SpanRecorder is a tracer, which keeps the ended spans in the memory,
+// so the instrumentation could be checked by the tests.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	ended []RecordedSpan
+}
+
+var _ Tracer = (*SpanRecorder)(nil)
+
+// NewSpanRecorder creates and returns a new, empty span recorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start starts a new span. It continues the trace of the parent
+// span context – if there is any –, otherwise starts a new, sampled one.
+func (sr *SpanRecorder) Start(c ctxpkg.Context, name string, kind SpanKind) (ctxpkg.Context, Span) {
+	var (
+		parent = ParentSpanContext(c)
+
+		sc = SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     newSpanID(),
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		}
+	)
+
+	if !parent.IsValid() {
+		sc.TraceID = newTraceID()
+		sc.Flags = TraceFlagsSampled
+	}
+
+	span := &recordingSpan{
+		recorder: sr,
+		span: RecordedSpan{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parent,
+			Attributes:  make(map[string]any),
+			StartTime:   time.Now(),
+		},
+	}
+
+	return ContextWithSpan(c, span), span
+}
+
+// Ended returns the ended spans in order of their ending.
+func (sr *SpanRecorder) Ended() []RecordedSpan {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	return slices.Clone(sr.ended)
+}
+
+// Reset deletes the recorded spans.
+func (sr *SpanRecorder) Reset() {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	sr.ended = nil
+}
+
+type recordingSpan struct {
+	recorder *SpanRecorder
+
+	mu      sync.Mutex
+	span    RecordedSpan
+	isEnded bool
+}
+
+func (s *recordingSpan) SpanContext() SpanContext {
+	return s.span.SpanContext
+}
+
+func (s *recordingSpan) SetName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Name = name
+}
+
+func (s *recordingSpan) SetAttribute(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Attributes[key] = value
+}
+
+func (s *recordingSpan) SetStatus(status SpanStatus, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Status = status
+	s.span.StatusDescription = description
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.span.Errors = append(s.span.Errors, err)
+}
+
+// End records the span. The subsequent calls are ignored.
+func (s *recordingSpan) End() {
+	s.mu.Lock()
+
+	if s.isEnded {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.isEnded = true
+	s.span.EndTime = time.Now()
+
+	span := s.span
+	span.Attributes = maps.Clone(s.span.Attributes)
+	span.Errors = slices.Clone(s.span.Errors)
+
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.recorder.ended = append(s.recorder.ended, span)
+}
@@ -0,0 +1,312 @@
+package middlewares
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/balazskvancz/gorouter"
+)
+
+const (
+	testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	testTraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID      = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	type testCase struct {
+		name  string
+		input string
+
+		expectedError   error
+		expectedSampled bool
+	}
+
+	tt := []testCase{
+		{
+			name:            "parses the valid traceparent",
+			input:           testTraceparent,
+			expectedSampled: true,
+		},
+		{
+			name:            "parses the not sampled traceparent",
+			input:           "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expectedSampled: false,
+		},
+		{
+			name:            "accepts the future version with additional fields",
+			input:           "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			expectedSampled: true,
+		},
+		{
+			name:          "rejects the version 00 with additional fields",
+			input:         testTraceparent + "-extra",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "rejects the version ff",
+			input:         "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "rejects the uppercase hex",
+			input:         "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "rejects the all zero trace id",
+			input:         "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "rejects the all zero span id",
+			input:         "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+		{
+			name:          "rejects the malformed traceparent",
+			input:         "00-4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7-01",
+			expectedError: ErrInvalidTraceparent,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tc.input)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got: %v\n", tc.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := sc.TraceID.String(); got != testTraceID {
+				t.Errorf("expected trace id: %s; got: %s\n", testTraceID, got)
+			}
+
+			if got := sc.SpanID.String(); got != testSpanID {
+				t.Errorf("expected span id: %s; got: %s\n", testSpanID, got)
+			}
+
+			if got := sc.IsSampled(); got != tc.expectedSampled {
+				t.Errorf("expected sampled: %v; got: %v\n", tc.expectedSampled, got)
+			}
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	type testCase struct {
+		name        string
+		conf        TracingConfig
+		url         string
+		traceparent string
+		tracestate  string
+
+		expectedName      string
+		expectedStatus    SpanStatus
+		expectedContinued bool
+		expectedError     string
+		expectedInjected  bool
+	}
+
+	tt := []testCase{
+		{
+			name:              "continues the incoming trace",
+			url:               "/users/1",
+			traceparent:       testTraceparent,
+			tracestate:        "vendor=abc",
+			expectedName:      "GET /users/{id}",
+			expectedStatus:    SpanStatusUnset,
+			expectedContinued: true,
+		},
+		{
+			name:              "starts a new trace without incoming trace context",
+			url:               "/users/1",
+			expectedName:      "GET /users/{id}",
+			expectedStatus:    SpanStatusUnset,
+			expectedContinued: false,
+		},
+		{
+			name:              "ignores the incoming trace context",
+			conf:              TracingConfig{IgnoreIncoming: true},
+			url:               "/users/1",
+			traceparent:       testTraceparent,
+			expectedName:      "GET /users/{id}",
+			expectedStatus:    SpanStatusUnset,
+			expectedContinued: false,
+		},
+		{
+			name:              "marks the span of the 5xx response as failed",
+			url:               "/fail",
+			traceparent:       testTraceparent,
+			expectedName:      "GET /fail",
+			expectedStatus:    SpanStatusError,
+			expectedContinued: true,
+			expectedError:     "the database is unavailable",
+		},
+		{
+			name:              "records the panic of the handler",
+			url:               "/panic",
+			expectedName:      "GET /panic",
+			expectedStatus:    SpanStatusError,
+			expectedContinued: false,
+			expectedError:     "panic: boom",
+		},
+		{
+			name:              "injects the trace context into the response",
+			conf:              TracingConfig{InjectResponse: true},
+			url:               "/users/1",
+			traceparent:       testTraceparent,
+			expectedName:      "GET /users/{id}",
+			expectedStatus:    SpanStatusUnset,
+			expectedContinued: true,
+			expectedInjected:  true,
+		},
+		{
+			name:              "names the span of the unmatched request by the method",
+			url:               "/unknown",
+			expectedName:      "GET",
+			expectedStatus:    SpanStatusUnset,
+			expectedContinued: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				recorder = NewSpanRecorder()
+				r        = gorouter.New(gorouter.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+				outgoing *http.Request
+			)
+
+			tc.conf.Tracer = recorder
+			r.RegisterMiddlewares(Tracing(tc.conf))
+
+			r.Get("/users/{id}", func(ctx gorouter.Context) {
+				c, span := StartSpan(ctx.GetRequestContext(), "query user")
+				defer span.End()
+
+				outgoing = httptest.NewRequestWithContext(c, http.MethodGet, "/downstream", nil)
+				InjectTraceContext(c, outgoing.Header)
+
+				ctx.Status(http.StatusOK)
+			})
+
+			r.Get("/fail", func(ctx gorouter.Context) {
+				GetSpan(ctx).RecordError(errors.New("the database is unavailable"))
+				ctx.Status(http.StatusInternalServerError)
+			})
+
+			r.Get("/panic", func(ctx gorouter.Context) {
+				panic("boom")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.traceparent != "" {
+				req.Header.Set(TraceparentHeaderKey, tc.traceparent)
+			}
+			if tc.tracestate != "" {
+				req.Header.Set(TracestateHeaderKey, tc.tracestate)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			spans := recorder.Ended()
+			if len(spans) == 0 {
+				t.Fatalf("expected spans; got none\n")
+			}
+
+			server := spans[len(spans)-1]
+
+			if server.Name != tc.expectedName {
+				t.Errorf("expected name: %s; got: %s\n", tc.expectedName, server.Name)
+			}
+
+			if server.Kind != SpanKindServer {
+				t.Errorf("expected kind: %v; got: %v\n", SpanKindServer, server.Kind)
+			}
+
+			if server.Status != tc.expectedStatus {
+				t.Errorf("expected status: %v; got: %v\n", tc.expectedStatus, server.Status)
+			}
+
+			if continued := server.SpanContext.TraceID.String() == testTraceID; continued != tc.expectedContinued {
+				t.Errorf("expected continued: %v; got: %v\n", tc.expectedContinued, continued)
+			}
+
+			if tc.expectedContinued && server.Parent.SpanID.String() != testSpanID {
+				t.Errorf("expected parent span id: %s; got: %s\n", testSpanID, server.Parent.SpanID)
+			}
+
+			if server.Attributes["http.response.status_code"] == nil {
+				t.Errorf("expected status code attribute; got: %v\n", server.Attributes)
+			}
+
+			if tc.expectedError != "" && (len(server.Errors) != 1 || server.Errors[0].Error() != tc.expectedError) {
+				t.Errorf("expected recorded error: %s; got: %v\n", tc.expectedError, server.Errors)
+			}
+
+			var expectedTraceparent string
+			if tc.expectedInjected {
+				expectedTraceparent = server.SpanContext.Traceparent()
+			}
+
+			if got := rec.Header().Get(TraceparentHeaderKey); got != expectedTraceparent {
+				t.Errorf("expected response traceparent: %q; got: %q\n", expectedTraceparent, got)
+			}
+
+			if outgoing == nil {
+				return
+			}
+
+			if len(spans) != 2 {
+				t.Fatalf("expected spans: 2; got: %d\n", len(spans))
+			}
+
+			child := spans[0]
+
+			if child.Parent.SpanID != server.SpanContext.SpanID || child.SpanContext.TraceID != server.SpanContext.TraceID {
+				t.Errorf("expected child of: %s; got: %s\n", server.SpanContext.Traceparent(), child.Parent.Traceparent())
+			}
+
+			if expected, got := child.SpanContext.Traceparent(), outgoing.Header.Get(TraceparentHeaderKey); got != expected {
+				t.Errorf("expected propagated traceparent: %s; got: %s\n", expected, got)
+			}
+
+			if got := outgoing.Header.Get(TracestateHeaderKey); got != tc.tracestate && !tc.conf.IgnoreIncoming {
+				t.Errorf("expected propagated tracestate: %s; got: %s\n", tc.tracestate, got)
+			}
+		})
+	}
+}
+
+func TestNoopTracerPropagation(t *testing.T) {
+	var (
+		r = gorouter.New()
+
+		outgoing = httptest.NewRequest(http.MethodGet, "/downstream", nil)
+	)
+
+	r.RegisterMiddlewares(Tracing())
+
+	r.Get("/users/{id}", func(ctx gorouter.Context) {
+		PropagateTrace(ctx, outgoing)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(TraceparentHeaderKey, testTraceparent)
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := outgoing.Header.Get(TraceparentHeaderKey); got != testTraceparent {
+		t.Errorf("expected traceparent: %s; got: %s\n", testTraceparent, got)
+	}
+}
@@ -0,0 +1,174 @@
+package gorouter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	paramSourcePath  string = "path"
+	paramSourceQuery string = "query"
+
+	querySliceSeparator string = ","
+)
+
+var (
+	ErrParamNotFound = errors.New("param not found")
+)
+
+// ParamError is returned by the typed path and query param
+// accessors, if the param is missing or could not be parsed.
+type ParamError struct {
+	// The source of the param: path or query.
+	Source string
+	Key    string
+	Value  string
+	// The name of the type, which the value was parsed to.
+	Type string
+	Err  error
+}
+
+func (e *ParamError) Error() string {
+	if errors.Is(e.Err, ErrParamNotFound) {
+		return fmt.Sprintf("%s param %q: %v", e.Source, e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s param %q: cannot parse %q as %s: %v", e.Source, e.Key, e.Value, e.Type, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// QuerySliceStyle determines how multiple values are passed in a query param.
+type QuerySliceStyle uint8
+
+const (
+	// The key is repeated for each value, eg. ?id=1&id=2
+	QuerySliceRepeated QuerySliceStyle = iota
+	// The values are separated by comma, eg. ?id=1,2
+	QuerySliceComma
+)
+
+// QueryInt returns the parsed int value of the query param identified by the given key.
+// If the param is missing, then the default is returned – if provided.
+func (ctx *context) QueryInt(key string, def ...int) (int, error) {
+	return parseQueryParam(ctx, key, "int", strconv.Atoi, def)
+}
+
+// QueryInt64 returns the parsed int64 value of the query param identified by the given key.
+// If the param is missing, then the default is returned – if provided.
+func (ctx *context) QueryInt64(key string, def ...int64) (int64, error) {
+	return parseQueryParam(ctx, key, "int64", func(v string) (int64, error) {
+		return strconv.ParseInt(v, 10, 64)
+	}, def)
+}
+
+// QueryFloat64 returns the parsed float64 value of the query param identified by the given key.
+// If the param is missing, then the default is returned – if provided.
+func (ctx *context) QueryFloat64(key string, def ...float64) (float64, error) {
+	return parseQueryParam(ctx, key, "float64", func(v string) (float64, error) {
+		return strconv.ParseFloat(v, 64)
+	}, def)
+}
+
+// QueryBool returns the parsed bool value of the query param identified by the given key.
+// Beside the values accepted by strconv.ParseBool, on/off and yes/no are accepted as well.
+// If the param is missing, then the default is returned – if provided.
+func (ctx *context) QueryBool(key string, def ...bool) (bool, error) {
+	return parseQueryParam(ctx, key, "bool", parseBool, def)
+}
+
+// QueryTime returns the value of the query param identified by the given key parsed with the layout.
+// If the param is missing, then the default is returned – if provided.
+func (ctx *context) QueryTime(key string, layout string, def ...time.Time) (time.Time, error) {
+	return parseQueryParam(ctx, key, "time", func(v string) (time.Time, error) {
+		return time.Parse(layout, v)
+	}, def)
+}
+
+// QueryDuration returns the parsed time.Duration value of the query param identified by the given key.
+// If the param is missing, then the default is returned – if provided.
+func (ctx *context) QueryDuration(key string, def ...time.Duration) (time.Duration, error) {
+	return parseQueryParam(ctx, key, "duration", time.ParseDuration, def)
+}
+
+// QuerySlice returns all the non-empty values of the query param
+// identified by the given key, according to the given style.
+func (ctx *context) QuerySlice(key string, style QuerySliceStyle) []string {
+	var (
+		values = ctx.GetQueryParams()[key]
+		res    = make([]string, 0, len(values))
+	)
+
+	for _, v := range values {
+		if style != QuerySliceComma {
+			if v != "" {
+				res = append(res, v)
+			}
+
+			continue
+		}
+
+		for _, e := range strings.Split(v, querySliceSeparator) {
+			if e = strings.TrimSpace(e); e != "" {
+				res = append(res, e)
+			}
+		}
+	}
+
+	return res
+}
+
+func parsePathParam[T any](ctx *context, key string, typeName string, parse func(string) (T, error)) (T, error) {
+	v, ok := ctx.GetParams()[key]
+	if !ok {
+		var zero T
+		return zero, &ParamError{Source: paramSourcePath, Key: key, Type: typeName, Err: ErrParamNotFound}
+	}
+
+	return parseParam(paramSourcePath, key, v, typeName, parse)
+}
+
+func parseQueryParam[T any](ctx *context, key string, typeName string, parse func(string) (T, error), def []T) (T, error) {
+	v := ctx.GetQueryParam(key)
+	if v == "" {
+		if len(def) > 0 {
+			return def[0], nil
+		}
+
+		var zero T
+		return zero, &ParamError{Source: paramSourceQuery, Key: key, Type: typeName, Err: ErrParamNotFound}
+	}
+
+	return parseParam(paramSourceQuery, key, v, typeName, parse)
+}
+
+func parseParam[T any](source string, key string, value string, typeName string, parse func(string) (T, error)) (T, error) {
+	parsed, err := parse(value)
+	if err != nil {
+		// The strconv errors already contain the value.
+		var numErr *strconv.NumError
+		if errors.As(err, &numErr) {
+			err = numErr.Err
+		}
+
+		var zero T
+		return zero, &ParamError{Source: source, Key: key, Value: value, Type: typeName, Err: err}
+	}
+
+	return parsed, nil
+}
+
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+
+	return strconv.ParseBool(v)
+}
@@ -0,0 +1,49 @@
+package gorouter
+
+import "slices"
+
+const (
+	// PrincipalKey is the key of the authenticated principal bound to the Context.
+	PrincipalKey ContextKey = "__principal__"
+)
+
+// Principal is the authenticated subject of a request.
+type Principal struct {
+	// The identifier of the subject, eg. the id or the name of the user.
+	Subject string
+
+	// The scheme of the authentication, eg. Basic or Bearer.
+	Scheme string
+
+	Roles       []string
+	Permissions []string
+
+	// Any additional attributes of the subject, eg. the claims of a token.
+	Claims map[string]any
+}
+
+// HasRole returns whether the principal has the given role.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Roles, role)
+}
+
+// HasPermission returns whether the principal has the given permission.
+func (p *Principal) HasPermission(permission string) bool {
+	if p == nil {
+		return false
+	}
+	return slices.Contains(p.Permissions, permission)
+}
+
+// GetPrincipal returns the authenticated principal bound to the given
+// Context by an authentication middleware – if there is any.
+func GetPrincipal(ctx Context) (*Principal, bool) {
+	p, ok := ctx.GetBindedValue(PrincipalKey).(*Principal)
+	if !ok || p == nil {
+		return nil, false
+	}
+	return p, true
+}
@@ -0,0 +1,209 @@
+package gorouter
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+)
+
+const (
+	// The default maximum size of the captured stack traces.
+	defaultStackSize int = 64 << 10
+
+	problemJsonContentType string = "application/problem+json"
+
+	// PanicKey is the key of the recovered value of the panic bound to the Context.
+	PanicKey ContextKey = "__panic__"
+)
+
+// The headers describing the content, which are removed with the partial
+// response of the panicking handler, if the headers before the chain
+// could not be restored.
+var contentHeaderKeys = []string{
+	contentTypeHeaderKey,
+	"Content-Length",
+	"Content-Disposition",
+	"Content-Range",
+	"ETag",
+	"Last-Modified",
+}
+
+// PanicReporter reports the panics recovered by the router,
+// eg. to an error tracking service.
+type PanicReporter interface {
+	ReportPanic(ctx Context, val any, stack []byte)
+}
+
+// PanicReporterFunc is an adapter to use an ordinary function as PanicReporter.
+type PanicReporterFunc func(ctx Context, val any, stack []byte)
+
+func (f PanicReporterFunc) ReportPanic(ctx Context, val any, stack []byte) {
+	f(ctx, val, stack)
+}
+
+// RecoveryConfig is the configuration of the panic recovery of the router.
+type RecoveryConfig struct {
+	// Disabled turns off the recovery – unless a handler is set
+	// by WithPanicHandler –, so the panics reach the net/http.
+	Disabled bool
+
+	// ProblemDetails makes the default response an RFC 9457
+	// application/problem+json document instead of plain text.
+	ProblemDetails bool
+
+	// Reporter is called with every recovered panic.
+	Reporter PanicReporter
+
+	// StackSize is the maximum size of the captured stack trace,
+	// by default 64KB.
+	StackSize int
+}
+
+// ProblemDetails is the RFC 9457 problem document of the error responses.
+type ProblemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// WithRecovery allows to configure the panic recovery of the router. By
+// default every panic is recovered: the stack trace is logged, the partial
+// response of the handler is discarded and 500 is sent instead.
+func WithRecovery(conf RecoveryConfig) routerOptionFunc {
+	return func(r *router) {
+		if conf.StackSize <= 0 {
+			conf.StackSize = defaultStackSize
+		}
+
+		r.recovery = conf
+	}
+}
+
+// boundedPanic carries the recovered value of the panicking chain of a
+// timed route – with the stack trace of its goroutine – to the goroutine
+// of Serve, since the stack of the latter does not include the handler.
+type boundedPanic struct {
+	val   any
+	stack []byte
+}
+
+// unwrapPanic returns the original value and the stack trace – if it has
+// been already captured – of the given recovered value.
+func unwrapPanic(val any) (any, []byte) {
+	if bp, ok := val.(*boundedPanic); ok {
+		return bp.val, bp.stack
+	}
+
+	return val, nil
+}
+
+// recoverPanic handles the given recovered value of the panicking chain.
+// The stack trace is captured, unless it is given. It returns false,
+// if the panic must be passed on to the net/http.
+func (r *router) recoverPanic(ctx Context, val any, stack []byte) bool {
+	// The net/http aborts the response silently by this value.
+	if err, ok := val.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+		return false
+	}
+
+	// The deferred functions – eg. of the tracing – could report it as well.
+	ctx.BindValue(PanicKey, val)
+
+	if r.recovery.Disabled && r.panicHandler == nil {
+		return false
+	}
+
+	if stack == nil {
+		stack = captureStack(r.recovery.StackSize)
+	}
+
+	ctx.GetLogger().Error("panic recovered", "panic", val, "url", ctx.GetUrl(), "stack", string(stack))
+
+	if r.recovery.Reporter != nil {
+		r.reportPanic(ctx, val, stack)
+	}
+
+	// The status code and the beginning of the body have been already sent,
+	// so the connection must be aborted to signal the broken response. The
+	// panic has been already logged, so the net/http should not log it again.
+	if c, ok := ctx.(*context); ok {
+		if c.writer.isStreaming {
+			panic(http.ErrAbortHandler)
+		}
+
+		// Eg. the cookies and the redirects of the chain must not be sent.
+		c.writer.discard()
+	} else {
+		for _, key := range contentHeaderKeys {
+			ctx.GetResponseHeaders().Del(key)
+		}
+	}
+
+	if r.panicHandler != nil {
+		r.panicHandler(ctx, val)
+
+		return true
+	}
+
+	if r.recovery.ProblemDetails {
+		writeProblem(ctx, http.StatusInternalServerError)
+
+		return true
+	}
+
+	ctx.StatusText(http.StatusInternalServerError)
+
+	return true
+}
+
+// GetPanic returns the recovered value of the panic of the chain
+// of the given Context – if there was any. It is available for the
+// functions registered by Defer, which are called after the recovery.
+func GetPanic(ctx Context) (any, bool) {
+	val := ctx.GetBindedValue(PanicKey)
+	return val, val != nil
+}
+
+// reportPanic calls the reporter, which must not break the recovery.
+func (r *router) reportPanic(ctx Context, val any, stack []byte) {
+	defer func() {
+		if rval := recover(); rval != nil {
+			ctx.GetLogger().Error("panic reporter failed", "panic", rval)
+		}
+	}()
+
+	r.recovery.Reporter.ReportPanic(ctx, val, stack)
+}
+
+// captureStack returns the stack trace of the current goroutine.
+func captureStack(size int) []byte {
+	if size <= 0 {
+		size = defaultStackSize
+	}
+
+	buf := make([]byte, size)
+	n := runtime.Stack(buf, false)
+
+	return buf[:n]
+}
+
+// writeProblem writes the problem document of the given status code.
+func writeProblem(ctx Context, statusCode int) {
+	ctx.Render(statusCode, &problemResponse{JsonResponse{Data: &ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(statusCode),
+		Status:    statusCode,
+		Instance:  ctx.GetCleanedUrl(),
+		RequestID: GetRequestID(ctx),
+	}}})
+}
+
+type problemResponse struct {
+	JsonResponse
+}
+
+func (pr *problemResponse) ContentType() string {
+	return problemJsonContentType
+}
@@ -0,0 +1,275 @@
+package gorouter
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecovery(t *testing.T) {
+	type testCase struct {
+		name string
+		opts []routerOptionFunc
+
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        string
+	}
+
+	tt := []testCase{
+		{
+			name:                "recovers by default with plain text",
+			expectedStatusCode:  http.StatusInternalServerError,
+			expectedContentType: "text/plain",
+			expectedBody:        http.StatusText(http.StatusInternalServerError),
+		},
+		{
+			name:                "recovers with problem details",
+			opts:                []routerOptionFunc{WithRecovery(RecoveryConfig{ProblemDetails: true})},
+			expectedStatusCode:  http.StatusInternalServerError,
+			expectedContentType: problemJsonContentType,
+			expectedBody:        `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/api/panic","requestId":"req-1"}` + "\n",
+		},
+		{
+			name: "the panic handler writes the response",
+			opts: []routerOptionFunc{
+				WithRecovery(RecoveryConfig{Disabled: true}),
+				WithPanicHandler(func(ctx Context, val interface{}) {
+					ctx.SendJson(http.StatusServiceUnavailable, map[string]any{"panic": val})
+				}),
+			},
+			expectedStatusCode:  http.StatusServiceUnavailable,
+			expectedContentType: "application/json",
+			expectedBody:        `{"panic":"boom"}` + "\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				logs           bytes.Buffer
+				deferredStatus int
+
+				r = New(append(tc.opts, WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))...)
+			)
+
+			r.RegisterMiddlewares(NewMiddleware(func(ctx Context) {
+				ctx.BindValue(RequestIDKey, "req-1")
+				ctx.GetResponseHeaders().Set("X-Request-ID", "req-1")
+				ctx.Defer(func() { deferredStatus = ctx.GetInfo().StatusCode })
+
+				ctx.Next()
+			}))
+
+			r.Get("/api/panic", func(ctx Context) {
+				ctx.GetResponseHeaders().Set("Content-Disposition", "attachment")
+				ctx.GetResponseHeaders().Set("Location", "/api/users/1")
+				ctx.BeforeWrite(func() { ctx.GetResponseHeaders().Set("Set-Cookie", "session=1") })
+				ctx.Render(http.StatusOK, &HtmlResponse{Data: []byte("<p>partial")})
+
+				panic("boom")
+			})
+
+			rec := httptest.NewRecorder()
+			rec.Header().Set("X-Served-By", "edge")
+
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if got := rec.Header().Get(contentTypeHeaderKey); got != tc.expectedContentType {
+				t.Errorf("expected content type: %q; got: %q\n", tc.expectedContentType, got)
+			}
+
+			if rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+
+			for _, key := range []string{"Content-Disposition", "Location", "Set-Cookie", "X-Request-ID"} {
+				if got := rec.Header().Get(key); got != "" {
+					t.Errorf("expected %s to be removed; got: %q\n", key, got)
+				}
+			}
+
+			if got := rec.Header().Get("X-Served-By"); got != "edge" {
+				t.Errorf("expected header before the chain: %q; got: %q\n", "edge", got)
+			}
+
+			if deferredStatus != tc.expectedStatusCode {
+				t.Errorf("expected deferred status code: %d; got: %d\n", tc.expectedStatusCode, deferredStatus)
+			}
+
+			for _, s := range []string{`msg="panic recovered"`, "panic=boom", "request_id=req-1", "recovery_test.go"} {
+				if !strings.Contains(logs.String(), s) {
+					t.Errorf("expected log to contain: %q; got: %q\n", s, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPanicReporter(t *testing.T) {
+	var (
+		reported []any
+		stacks   [][]byte
+
+		r = New(
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithRecovery(RecoveryConfig{
+				Reporter: PanicReporterFunc(func(ctx Context, val any, stack []byte) {
+					reported = append(reported, val)
+					stacks = append(stacks, stack)
+
+					panic("reporter is broken")
+				}),
+			}),
+		)
+	)
+
+	r.Get("/api/panic", func(ctx Context) {
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusInternalServerError, rec.Code)
+	}
+
+	if len(reported) != 1 || reported[0] != "boom" {
+		t.Fatalf("expected reported panics: %v; got: %v\n", []any{"boom"}, reported)
+	}
+
+	if !bytes.Contains(stacks[0], []byte("recovery_test.go")) {
+		t.Errorf("expected stack trace of the handler; got: %s\n", stacks[0])
+	}
+}
+
+// panickingHandler is a named handler, so it could be found in the stack traces.
+func panickingHandler(ctx Context) {
+	panic("boom")
+}
+
+func TestTimedRouteRecovery(t *testing.T) {
+	var (
+		reported any
+		stack    []byte
+
+		r = New(
+			WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+			WithRecovery(RecoveryConfig{
+				Reporter: PanicReporterFunc(func(ctx Context, val any, s []byte) {
+					reported, stack = val, s
+				}),
+			}),
+		)
+	)
+
+	r.Get("/api/panic", panickingHandler).Timeout(time.Second)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusInternalServerError, rec.Code)
+	}
+
+	if reported != "boom" {
+		t.Errorf("expected reported panic: %v; got: %v\n", "boom", reported)
+	}
+
+	if !bytes.Contains(stack, []byte("panickingHandler")) {
+		t.Errorf("expected stack trace of the handler; got: %s\n", stack)
+	}
+}
+
+func TestRecoveryRepanics(t *testing.T) {
+	type testCase struct {
+		name    string
+		opts    []routerOptionFunc
+		handler HandlerFunc
+		timeout time.Duration
+
+		expectedPanic any
+	}
+
+	tt := []testCase{
+		{
+			name:          "passes on the abort of the handler",
+			handler:       func(ctx Context) { panic(http.ErrAbortHandler) },
+			expectedPanic: http.ErrAbortHandler,
+		},
+		{
+			name:          "passes on the panic if the recovery is disabled",
+			opts:          []routerOptionFunc{WithRecovery(RecoveryConfig{Disabled: true})},
+			handler:       func(ctx Context) { panic("boom") },
+			expectedPanic: "boom",
+		},
+		{
+			name:          "passes on the original panic of the timed route",
+			opts:          []routerOptionFunc{WithRecovery(RecoveryConfig{Disabled: true})},
+			handler:       func(ctx Context) { panic("boom") },
+			timeout:       time.Second,
+			expectedPanic: "boom",
+		},
+		{
+			name:          "passes on the abort of the timed route",
+			handler:       func(ctx Context) { panic(http.ErrAbortHandler) },
+			timeout:       time.Second,
+			expectedPanic: http.ErrAbortHandler,
+		},
+		{
+			name: "aborts the already streamed response",
+			handler: func(ctx Context) {
+				ctx.Copy(strings.NewReader("partial"))
+				ctx.Flush()
+
+				panic("boom")
+			},
+			expectedPanic: http.ErrAbortHandler,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				deferred bool
+
+				r = New(append(tc.opts, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))...)
+			)
+
+			route := r.Get("/api/panic", func(ctx Context) {
+				ctx.Defer(func() { deferred = true })
+				tc.handler(ctx)
+			})
+
+			if tc.timeout > 0 {
+				route.Timeout(tc.timeout)
+			}
+
+			var val any
+
+			func() {
+				defer func() { val = recover() }()
+
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+			}()
+
+			if val != tc.expectedPanic {
+				t.Errorf("expected panic: %v; got: %v\n", tc.expectedPanic, val)
+			}
+
+			if !deferred {
+				t.Errorf("expected the deferred functions to be called\n")
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package gorouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidRedirectCode = errors.New("the status code of a redirect must be 3xx")
+	ErrInvalidRedirectUrl  = errors.New("the redirect url is invalid")
+	ErrRedirectNotAllowed  = errors.New("the redirect to the given host is not allowed")
+	ErrRouteNotFound       = errors.New("there is no route registered with the given name")
+	ErrMissingRouteParam   = errors.New("missing route param")
+	ErrNoRouter            = errors.New("the context does not belong to a router")
+)
+
+// redirectPolicy determines where the redirects are allowed to point.
+type redirectPolicy struct {
+	// The allowed hosts for absolute redirects. If it is empty,
+	// every host is allowed. An entry starting with *. allows
+	// all the subdomains of the given domain.
+	allowedHosts []string
+
+	// If it is set, then the redirects pointing to a not
+	// allowed host are rewritten to this url, instead of rejection.
+	fallbackUrl string
+}
+
+// Redirect redirects the request to the given url with the given 3xx status code.
+// The url could be relative or absolute, however absolute urls are checked against
+// the allowed redirect hosts of the router. Without any allowed hosts every host is
+// allowed, so it is safe to use with user input only if WithAllowedRedirectHosts is set.
+func (ctx *context) Redirect(statusCode int, location string) error {
+	if statusCode < http.StatusMultipleChoices || statusCode > http.StatusPermanentRedirect {
+		return ErrInvalidRedirectCode
+	}
+
+	var policy redirectPolicy
+	if ctx.router != nil {
+		policy = ctx.router.redirectPolicy
+	}
+
+	location, err := policy.check(location, ctx.getHost())
+	if err != nil {
+		return err
+	}
+
+	ctx.writer.w.Header().Set(locationHeaderKey, location)
+	ctx.Status(statusCode)
+
+	return nil
+}
+
+// RedirectToRoute redirects the request with 302 to the url of the
+// route registered with the given name, filled with the given params.
+func (ctx *context) RedirectToRoute(name string, params map[string]string) error {
+	if ctx.router == nil {
+		return ErrNoRouter
+	}
+
+	location, err := ctx.router.GetRouteUrl(name, params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Redirect(http.StatusFound, location)
+}
+
+func (ctx *context) getHost() string {
+	if r := ctx.GetRequest(); r != nil {
+		return r.Host
+	}
+	return ""
+}
+
+// check returns the url where the redirect should point,
+// or an error if the redirect is not allowed at all.
+func (p *redirectPolicy) check(location string, requestHost string) (string, error) {
+	// Browsers strip the leading and trailing whitespaces
+	// and treat backslashes as slashes, so /\evil.com
+	// would be the same as //evil.com.
+	location = strings.TrimFunc(location, func(r rune) bool { return r <= ' ' })
+	location = strings.ReplaceAll(location, "\\", slash)
+
+	if location == "" {
+		return "", ErrInvalidRedirectUrl
+	}
+
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidRedirectUrl, err)
+	}
+
+	// Relative urls always point to the same host – unless they
+	// start with more slashes, eg. ///evil.com or /%5C/evil.com,
+	// which the browsers treat as the host.
+	if u.Scheme == "" && u.Host == "" {
+		if hasAuthorityPrefix(location) {
+			return "", ErrInvalidRedirectUrl
+		}
+
+		return location, nil
+	}
+
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return "", ErrInvalidRedirectUrl
+	}
+
+	if p.isHostAllowed(u.Hostname(), requestHost) {
+		return location, nil
+	}
+
+	if p.fallbackUrl != "" {
+		return p.fallbackUrl, nil
+	}
+
+	return "", ErrRedirectNotAllowed
+}
+
+// hasAuthorityPrefix reports whether the given relative url
+// starts with two or more slashes or backslashes after unescaping.
+func hasAuthorityPrefix(location string) bool {
+	if unescaped, err := url.PathUnescape(location); err == nil {
+		location = unescaped
+	}
+
+	location = strings.ReplaceAll(location, "\\", slash)
+
+	return strings.HasPrefix(location, slash+slash)
+}
+
+func (p *redirectPolicy) isHostAllowed(host string, requestHost string) bool {
+	if len(p.allowedHosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+
+	// The host of the request itself is always allowed.
+	if reqHost := (&url.URL{Host: requestHost}).Hostname(); strings.EqualFold(host, reqHost) {
+		return true
+	}
+
+	for _, allowed := range p.allowedHosts {
+		allowed = strings.ToLower(allowed)
+
+		if domain, isWildcard := strings.CutPrefix(allowed, "*."); isWildcard {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+
+			continue
+		}
+
+		if host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetRouteUrl returns the url of the route registered with
+// the given name, where the params are replaced by the given values.
+func (r *router) GetRouteUrl(name string, params map[string]string) (string, error) {
+	route, ok := r.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+
+	return buildUrl(route.GetUrl(), params)
+}
+
+func buildUrl(pattern string, params map[string]string) (string, error) {
+	segments := strings.Split(pattern, slash)
+
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, paramStart) || !strings.HasSuffix(segment, paramEnd) {
+			continue
+		}
+
+		var (
+			key                = segment[1 : len(segment)-1]
+			catchAllKey, isAll = strings.CutSuffix(key, catchAllSuffix)
+		)
+
+		if isAll {
+			key = catchAllKey
+		}
+
+		value, ok := params[key]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrMissingRouteParam, key)
+		}
+
+		if !isAll {
+			segments[i] = url.PathEscape(value)
+
+			continue
+		}
+
+		// In case of catch-all, the slashes must be kept.
+		parts := strings.Split(value, slash)
+		for j, p := range parts {
+			parts[j] = url.PathEscape(p)
+		}
+
+		segments[i] = strings.Join(parts, slash)
+	}
+
+	return strings.Join(segments, slash), nil
+}
@@ -0,0 +1,253 @@
+package gorouter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	type testCase struct {
+		name       string
+		opts       []routerOptionFunc
+		statusCode int
+		location   string
+
+		expectedLocation string
+		err              error
+	}
+
+	var allowed = []routerOptionFunc{WithAllowedRedirectHosts("accounts.example.com", "*.trusted.com")}
+
+	tt := []testCase{
+		{
+			name:             "redirects to relative url",
+			statusCode:       http.StatusFound,
+			location:         "/dashboard?tab=1",
+			expectedLocation: "/dashboard?tab=1",
+		},
+		{
+			name:       "returns error for non 3xx status code",
+			statusCode: http.StatusOK,
+			location:   "/dashboard",
+			err:        ErrInvalidRedirectCode,
+		},
+		{
+			name:             "redirects to any host if there are no allowed hosts",
+			statusCode:       http.StatusSeeOther,
+			location:         "https://evil.com",
+			expectedLocation: "https://evil.com",
+		},
+		{
+			name:       "rejects javascript scheme",
+			statusCode: http.StatusFound,
+			location:   "javascript:alert(1)",
+			err:        ErrInvalidRedirectUrl,
+		},
+		{
+			name:       "rejects control characters",
+			statusCode: http.StatusFound,
+			location:   "/\t/evil.com",
+			err:        ErrInvalidRedirectUrl,
+		},
+		{
+			name:             "redirects to the host of the request",
+			opts:             allowed,
+			statusCode:       http.StatusFound,
+			location:         "http://example.com/home",
+			expectedLocation: "http://example.com/home",
+		},
+		{
+			name:             "redirects to an allowed host",
+			opts:             allowed,
+			statusCode:       http.StatusFound,
+			location:         "https://accounts.example.com/login",
+			expectedLocation: "https://accounts.example.com/login",
+		},
+		{
+			name:             "redirects to a subdomain of an allowed wildcard host",
+			opts:             allowed,
+			statusCode:       http.StatusFound,
+			location:         "https://app.trusted.com",
+			expectedLocation: "https://app.trusted.com",
+		},
+		{
+			name:       "rejects the domain of the wildcard itself",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   "https://trusted.com",
+			err:        ErrRedirectNotAllowed,
+		},
+		{
+			name:       "rejects a foreign host",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   "https://evil.com/login",
+			err:        ErrRedirectNotAllowed,
+		},
+		{
+			name:       "rejects a protocol-relative foreign host",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   "//evil.com",
+			err:        ErrRedirectNotAllowed,
+		},
+		{
+			name:       "rejects a foreign host disguised with backslash",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   " /\\evil.com",
+			err:        ErrRedirectNotAllowed,
+		},
+		{
+			name:       "rejects a relative url starting with three slashes",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   "///evil.com",
+			err:        ErrInvalidRedirectUrl,
+		},
+		{
+			name:       "rejects a relative url starting with an escaped backslash",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   "/%5C/evil.com",
+			err:        ErrInvalidRedirectUrl,
+		},
+		{
+			name:       "rejects a relative url starting with an escaped slash",
+			opts:       allowed,
+			statusCode: http.StatusFound,
+			location:   "/%2F/evil.com",
+			err:        ErrInvalidRedirectUrl,
+		},
+		{
+			name:       "rejects a relative url starting with slashes without allowed hosts",
+			statusCode: http.StatusFound,
+			location:   "\\\\\\evil.com",
+			err:        ErrInvalidRedirectUrl,
+		},
+		{
+			name:             "rewrites a foreign host to the fallback",
+			opts:             append([]routerOptionFunc{WithRedirectFallback("/")}, allowed...),
+			statusCode:       http.StatusFound,
+			location:         "https://evil.com/login",
+			expectedLocation: "/",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				r   = New(tc.opts...)
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodGet, "/login", nil)
+
+				err error
+			)
+
+			r.Get("/login", func(ctx Context) {
+				err = ctx.Redirect(tc.statusCode, tc.location)
+			})
+
+			r.ServeHTTP(rec, req)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+
+			if got := rec.Header().Get("Location"); got != tc.expectedLocation {
+				t.Errorf("expected location: %q; got location: %q\n", tc.expectedLocation, got)
+			}
+
+			if tc.err == nil && rec.Code != tc.statusCode {
+				t.Errorf("expected statusCode: %d; got: %d\n", tc.statusCode, rec.Code)
+			}
+		})
+	}
+}
+
+func TestGetRouteUrl(t *testing.T) {
+	r := New()
+
+	r.Get("/orders/{id}", func(_ Context) {}).Name("orders.show")
+	r.Get("/files/{path...}", func(_ Context) {}).Name("files")
+	r.Get("/", func(_ Context) {}).Name("home")
+
+	type testCase struct {
+		name      string
+		routeName string
+		params    map[string]string
+
+		expected string
+		err      error
+	}
+
+	tt := []testCase{
+		{
+			name:      "returns the url without params",
+			routeName: "home",
+			expected:  "/",
+		},
+		{
+			name:      "returns the url with escaped params",
+			routeName: "orders.show",
+			params:    map[string]string{"id": "a/b c"},
+			expected:  "/orders/a%2Fb%20c",
+		},
+		{
+			name:      "returns the url with catch-all param",
+			routeName: "files",
+			params:    map[string]string{"path": "docs/read me.txt"},
+			expected:  "/files/docs/read%20me.txt",
+		},
+		{
+			name:      "returns error if a param is missing",
+			routeName: "orders.show",
+			err:       ErrMissingRouteParam,
+		},
+		{
+			name:      "returns error if there is no such route",
+			routeName: "missing",
+			err:       ErrRouteNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			url, err := r.GetRouteUrl(tc.routeName, tc.params)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.err, err)
+			}
+
+			if url != tc.expected {
+				t.Errorf("expected url: %s; got url: %s\n", tc.expected, url)
+			}
+		})
+	}
+}
+
+func TestRedirectToRoute(t *testing.T) {
+	var (
+		r   = New()
+		rec = httptest.NewRecorder()
+	)
+
+	r.Get("/orders/{id}", func(_ Context) {}).Name("orders.show")
+	r.Post("/orders", func(ctx Context) {
+		if err := ctx.RedirectToRoute("orders.show", map[string]string{"id": "42"}); err != nil {
+			t.Error(err)
+		}
+	})
+
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Errorf("expected statusCode: %d; got: %d\n", http.StatusFound, rec.Code)
+	}
+
+	if got := rec.Header().Get("Location"); got != "/orders/42" {
+		t.Errorf("expected location: %s; got location: %s\n", "/orders/42", got)
+	}
+}
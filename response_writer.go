@@ -29,6 +29,22 @@ func (dr *DefaultResponse) ContentType() string {
 	return "text/plain"
 }
 
+type HtmlResponse struct {
+	Data any
+}
+
+func (hr *HtmlResponse) Encode(w io.Writer) (int, error) {
+	b, ok := hr.Data.([]byte)
+	if !ok {
+		return 0, errors.New("cant assert to []byte")
+	}
+	return w.Write(b)
+}
+
+func (hr *HtmlResponse) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
 type JsonResponse struct {
 	Data any
 }
@@ -58,6 +74,7 @@ func (js *JsonResponse) ContentType() string {
 
 var (
 	_ (Response) = (*DefaultResponse)(nil)
+	_ (Response) = (*HtmlResponse)(nil)
 	_ (Response) = (*JsonResponse)(nil)
 )
 
@@ -67,6 +84,19 @@ type responseWriter struct {
 	buff              *bytes.Buffer
 	writtenBytes      int
 
+	// Whether the status code and the headers are already written
+	// to the underlying writer. In streaming mode every write goes
+	// directly to the underlying writer instead of the buffer.
+	isStreaming bool
+
+	// The functions to call right before the status code
+	// and the headers are written to the underlying writer.
+	beforeWrite []func()
+
+	// The headers of the response before the chain was executed,
+	// which are restored, if the response of the chain is discarded.
+	headers http.Header
+
 	w http.ResponseWriter
 }
 
@@ -75,17 +105,75 @@ func (rw *responseWriter) Empty() {
 	rw.statusCode = 0
 	rw.w = nil
 	rw.writtenBytes = 0
+	rw.isStreaming = false
+	rw.beforeWrite = rw.beforeWrite[:0]
+	rw.headers = nil
+}
+
+// snapshot saves the current headers of the response,
+// so they could be restored by discard.
+func (rw *responseWriter) snapshot() {
+	if rw.w == nil {
+		return
+	}
+	rw.headers = rw.w.Header().Clone()
+}
+
+// reset drops the buffered content, the status code and the headers
+// describing the content, so a different response could be written instead.
+func (rw *responseWriter) reset() {
+	rw.buff.Reset()
+	rw.statusCode = 0
+	rw.writtenBytes = 0
+
+	if rw.w == nil {
+		return
+	}
+
+	for _, key := range contentHeaderKeys {
+		rw.w.Header().Del(key)
+	}
+}
+
+// discard drops the buffered content, the status code and the headers
+// set since the snapshot – with the functions registered by BeforeWrite,
+// eg. the ones setting cookies –, so a different response could be
+// written instead.
+func (rw *responseWriter) discard() {
+	rw.buff.Reset()
+	rw.statusCode = 0
+	rw.writtenBytes = 0
+	rw.beforeWrite = rw.beforeWrite[:0]
+
+	if rw.w == nil {
+		return
+	}
+
+	header := rw.w.Header()
+	clear(header)
+
+	for key, values := range rw.headers {
+		header[key] = values
+	}
+}
+
+// target returns the writer, where the body should be written to.
+func (rw *responseWriter) target() io.Writer {
+	if rw.isStreaming {
+		return rw.w
+	}
+	return rw.buff
 }
 
 func (rw *responseWriter) write(b []byte) (int, error) {
-	n, err := rw.buff.Write(b)
+	n, err := rw.target().Write(b)
 	rw.writtenBytes += n
 	return n, err
 }
 
 func (rw *responseWriter) render(r Response) (int, error) {
 	rw.addHeader(contentTypeHeaderKey, r.ContentType())
-	n, err := r.Encode(rw.buff)
+	n, err := r.Encode(rw.target())
 	rw.writtenBytes += n
 	return n, err
 }
@@ -94,6 +182,17 @@ func (rw *responseWriter) setStatus(statusCode int) {
 	rw.statusCode = statusCode
 }
 
+// getStatusCode returns the status code which is – or will be – written.
+func (rw *responseWriter) getStatusCode() int {
+	if rw.statusCode > 0 {
+		return rw.statusCode
+	}
+	if rw.defaultStatusCode > 0 {
+		return rw.defaultStatusCode
+	}
+	return defaultStatusCode
+}
+
 func (rw *responseWriter) addHeader(key, value string) {
 	rw.w.Header().Add(key, value)
 }
@@ -102,20 +201,79 @@ func (rw *responseWriter) copy(r io.Reader) error {
 	if rw == nil {
 		return errors.New("response writer is <nil>")
 	}
-	n, err := io.Copy(rw.buff, r)
+	n, err := io.Copy(rw.target(), r)
 	rw.writtenBytes += int(n)
 	return err
 }
 
-func (rw *responseWriter) flush() {
-	statusCode := defaultStatusCode
-	if rw.defaultStatusCode > 0 {
-		statusCode = rw.defaultStatusCode
+// stream switches the writer to streaming mode by writing the status code,
+// the headers and the buffered content to the underlying writer.
+func (rw *responseWriter) stream() {
+	if rw.isStreaming {
+		return
 	}
-	if rw.statusCode > 0 {
-		statusCode = rw.statusCode
+
+	// The functions are called before the switch, so they could still
+	// replace the buffered response. They are called only once, even if
+	// one of them flushes the response.
+	hooks := rw.beforeWrite
+	rw.beforeWrite = nil
+
+	for _, fn := range hooks {
+		fn()
 	}
 
-	rw.w.WriteHeader(statusCode)
+	rw.beforeWrite = hooks[:0]
+
+	if rw.isStreaming {
+		return
+	}
+	rw.isStreaming = true
+
+	rw.w.WriteHeader(rw.getStatusCode())
 	rw.buff.WriteTo(rw.w)
 }
+
+// flush writes everything to the underlying writer, then flushes it to the
+// connection – if it is supported. Every subsequent write is streamed.
+func (rw *responseWriter) flush() {
+	rw.stream()
+
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// end writes the response to the underlying writer, unless it has been
+// already streamed. It leaves the flushing to the net/http, so the
+// Content-Length of small responses could be still determined.
+func (rw *responseWriter) end() {
+	rw.stream()
+}
+
+// contentWriter adapts the responseWriter to the http.ResponseWriter interface,
+// so the content serving of net/http could be used with the context.
+type contentWriter struct {
+	rw *responseWriter
+
+	// Whether the response should be streamed
+	// right after the status code is written.
+	isStreaming bool
+}
+
+var _ http.ResponseWriter = (*contentWriter)(nil)
+
+func (cw *contentWriter) Header() http.Header {
+	return cw.rw.w.Header()
+}
+
+func (cw *contentWriter) WriteHeader(statusCode int) {
+	cw.rw.setStatus(statusCode)
+	if cw.isStreaming {
+		cw.rw.stream()
+	}
+}
+
+func (cw *contentWriter) Write(b []byte) (int, error) {
+	return cw.rw.write(b)
+}
@@ -2,6 +2,7 @@ package gorouter
 
 import (
 	"strings"
+	"time"
 )
 
 var (
@@ -19,6 +20,11 @@ func removeQueryPart(url string) string {
 }
 
 type route struct {
+	router *router
+
+	name        string
+	policy      Policy
+	timeout     time.Duration
 	fullUrl     string
 	handler     HandlerFunc
 	middlewares map[MiddlewareType]Middlewares
@@ -32,6 +38,13 @@ type Route interface {
 	Handler
 	ExecuteChainer
 	RegisterMiddlewares(mws ...Middleware) Route
+	Name(name string) Route
+	Require(permissions ...string) Route
+	RequirePolicy(policy Policy) Route
+	Timeout(d time.Duration) Route
+	GetName() string
+	GetPolicy() Policy
+	GetTimeout() time.Duration
 	GetUrl() string
 }
 
@@ -39,6 +52,7 @@ var _ Route = (*route)(nil)
 
 func newRoute(url string, fn HandlerFunc, r *router) Route {
 	return &route{
+		router:      r,
 		fullUrl:     url,
 		handler:     fn,
 		middlewares: make(map[MiddlewareType]Middlewares),
@@ -70,6 +84,90 @@ func (r *route) RegisterMiddlewares(mws ...Middleware) Route {
 	return r
 }
 
+// Name names the route, so its url could be built by
+// the router, then returns the route pointer.
+func (r *route) Name(name string) Route {
+	if r == nil {
+		return nil
+	}
+
+	r.name = name
+
+	if r.router != nil {
+		r.router.registerNamedRoute(name, r)
+	}
+
+	return r
+}
+
+// Require requires all the given permissions from the principal
+// of the request to access the route, then returns the route pointer.
+func (r *route) Require(permissions ...string) Route {
+	policies := make([]Policy, 0, len(permissions))
+	for _, p := range permissions {
+		policies = append(policies, Permission(p))
+	}
+
+	if len(policies) == 1 {
+		return r.RequirePolicy(policies[0])
+	}
+
+	return r.RequirePolicy(AllOf(policies...))
+}
+
+// RequirePolicy requires the given policy to be fulfilled by the principal
+// of the request to access the route, then returns the route pointer.
+// If it is called multiple times, then all the policies are required.
+func (r *route) RequirePolicy(policy Policy) Route {
+	if r == nil {
+		return nil
+	}
+
+	if r.policy == nil {
+		r.policy = policy
+	} else {
+		r.policy = AllOf(r.policy, policy)
+	}
+
+	return r
+}
+
+// GetPolicy returns the policy of the route.
+func (r *route) GetPolicy() Policy {
+	if r == nil {
+		return nil
+	}
+	return r.policy
+}
+
+// Timeout bounds the execution of the route with the given
+// timeout – see Context.SetTimeout –, then returns the route pointer.
+func (r *route) Timeout(d time.Duration) Route {
+	if r == nil {
+		return nil
+	}
+
+	r.timeout = d
+
+	return r
+}
+
+// GetTimeout returns the timeout of the route.
+func (r *route) GetTimeout() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.timeout
+}
+
+// GetName returns the name of the route.
+func (r *route) GetName() string {
+	if r == nil {
+		return ""
+	}
+	return r.name
+}
+
 func (r *route) GetUrl() string {
 	if r == nil {
 		return ""
@@ -96,13 +194,26 @@ func (r *route) ExecuteChain(ctx Context, lastIndex uint8) {
 		last = currentIndex
 	}
 
-	if needToExecuteHandler {
-		r.Handle(ctx)
+	var execute = func() {
+		// The authorization is done after the middlewares
+		// of the route, so they could authenticate the request.
+		if needToExecuteHandler && r.router.authorize(ctx, r.policy) {
+			r.Handle(ctx)
+		}
+
+		for _, e := range r.middlewares[MiddlewarePostRunner] {
+			e.Handle(ctx)
+		}
 	}
 
-	for _, e := range r.middlewares[MiddlewarePostRunner] {
-		e.Handle(ctx)
+	// The timeout could be set by the middlewares of the route as well.
+	if c, ok := ctx.(*context); ok {
+		c.executeBounded(execute)
+
+		return
 	}
+
+	execute()
 }
 
 func (r *route) Handle(ctx Context) {
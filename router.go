@@ -2,13 +2,20 @@ package gorouter
 
 import (
 	ctxpkg "context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"text/tabwriter"
+	"time"
 )
 
 var logo string = `
@@ -42,6 +49,25 @@ type Router interface {
 	Trace(url string, handler HandlerFunc) Route
 	Patch(url string, handler HandlerFunc) Route
 	Connect(url string, handler HandlerFunc) Route
+
+	// Serving the files of a filesystem under the given prefix.
+	Static(prefix string, fsys fs.FS, conf StaticConfig)
+
+	// Building the url of a named route.
+	GetRouteUrl(name string, params map[string]string) (string, error)
+
+	// Listing all the registered routes.
+	Routes() []RouteInfo
+
+	// Registering the health, readiness and liveness endpoints.
+	Health(conf HealthConfig) *Health
+
+	// Registering the runtime debug endpoints, eg. pprof.
+	Debug(prefix string, auth Middleware)
+
+	// The logger of the router, and changing the level of it at runtime.
+	GetLogger() *slog.Logger
+	SetLogLevel(level slog.Level)
 }
 
 type (
@@ -61,6 +87,8 @@ const (
 
 	defaultAddress    int    = 8000
 	defaultServerName string = "goRouter"
+
+	allowHeaderKey string = "Allow"
 )
 
 type routerInfo struct {
@@ -114,11 +142,43 @@ type router struct {
 
 	// Custom handler function for panics.
 	panicHandler PanicHandlerFunc
+	recovery     RecoveryConfig
 
 	// A handler when the method tree is empty.
 	emptyTreeHandler HandlerFunc
 
-	logger Logger
+	// The keys for signing and encrypting cookies.
+	keyRing *KeyRing
+
+	// The routes registered with a name.
+	namedRoutes map[string]Route
+
+	// The policy of the redirects done by the contexts.
+	redirectPolicy redirectPolicy
+
+	// The authorizer of the route policies.
+	authorizer Authorizer
+
+	// Custom handler for HTTP 403, when the authorization fails.
+	forbiddenHandler HandlerFunc
+
+	// The duration between the failing readiness and
+	// the shutdown of the server, see WithDrainPeriod.
+	drainPeriod time.Duration
+
+	// Whether the shutdown of the server has begun.
+	isShuttingDown atomic.Bool
+
+	// The address of the separate admin listener, and the router
+	// of it, which serves the debug endpoints – if there is any.
+	adminAddress int
+	adminRouter  *router
+
+	// The logger given by WithLogger – if there is any –, the logger
+	// built from it and the minimum level of the logs.
+	customLogger Logger
+	logger       *slog.Logger
+	logLevel     *slog.LevelVar
 }
 
 // WithAddress allows to configure address of the router
@@ -176,7 +236,8 @@ func WithOptionsHandler(h HandlerFunc) routerOptionFunc {
 }
 
 // WithPanicHandler allows to configure a recover function
-// which is called if a panic happens somewhere.
+// which is called if a panic happens somewhere. It writes the
+// response instead of the default one of the recovery.
 func WithPanicHandler(h PanicHandlerFunc) routerOptionFunc {
 	return func(r *router) {
 		r.panicHandler = h
@@ -197,6 +258,73 @@ func WithEmptyTreeHandler(handler HandlerFunc) routerOptionFunc {
 	}
 }
 
+// WithKeyRing allows to configure the key ring, which is
+// used for signing and encrypting cookies.
+func WithKeyRing(kr *KeyRing) routerOptionFunc {
+	return func(r *router) {
+		r.keyRing = kr
+	}
+}
+
+// WithAllowedRedirectHosts allows to configure the hosts, where the absolute
+// redirects could point to. The host of the request is always allowed.
+// A host starting with *. allows all the subdomains of the given domain.
+func WithAllowedRedirectHosts(hosts ...string) routerOptionFunc {
+	return func(r *router) {
+		r.redirectPolicy.allowedHosts = append(r.redirectPolicy.allowedHosts, hosts...)
+	}
+}
+
+// WithRedirectFallback allows to configure the url, which the redirects
+// pointing to a not allowed host are rewritten to, instead of rejection.
+func WithRedirectFallback(url string) routerOptionFunc {
+	return func(r *router) {
+		r.redirectPolicy.fallbackUrl = url
+	}
+}
+
+// WithAuthorizer allows to configure the authorizer, which
+// authorizes the requests against the policies of the routes.
+func WithAuthorizer(a Authorizer) routerOptionFunc {
+	return func(r *router) {
+		if a != nil {
+			r.authorizer = a
+		}
+	}
+}
+
+// WithForbiddenHandler allows to configure the 403 handler of the router.
+func WithForbiddenHandler(h HandlerFunc) routerOptionFunc {
+	return func(r *router) {
+		if h != nil {
+			r.forbiddenHandler = h
+		}
+	}
+}
+
+// WithDrainPeriod allows to configure the duration, which the router waits
+// for between the beginning of the shutdown and the shutdown of the server.
+// Meanwhile the readiness endpoint is failing, so the load balancers stop
+// sending new requests, while the incoming ones are still served.
+func WithDrainPeriod(d time.Duration) routerOptionFunc {
+	return func(r *router) {
+		if d > 0 {
+			r.drainPeriod = d
+		}
+	}
+}
+
+// WithAdminAddress allows to configure the address of a separate admin
+// listener, which serves the debug endpoints instead of the main one,
+// so they could be kept private, eg. not exposed by the load balancer.
+func WithAdminAddress(address int) routerOptionFunc {
+	return func(r *router) {
+		if address > 0 {
+			r.adminAddress = address
+		}
+	}
+}
+
 // New returns a new Router instance decorated
 // by the given optionFuncs.
 func New(opts ...routerOptionFunc) Router {
@@ -215,28 +343,37 @@ func New(opts ...routerOptionFunc) Router {
 
 		middlewares:  make(middlewareRegistry, 0),
 		endpointTree: newNode(),
+		namedRoutes:  make(map[string]Route),
 
 		notFoundHandler:  defaultNotFoundHandler,
 		emptyTreeHandler: defaultEmptyTreeHandler,
+		forbiddenHandler: defaultForbiddenHandler,
+		authorizer:       defaultAuthorizer{},
 		optionsHandler:   nil,
 		panicHandler:     nil,
+		recovery:         RecoveryConfig{StackSize: defaultStackSize},
 	}
 
+	r.logLevel = new(slog.LevelVar)
+	r.logLevel.Set(defaultLogLevel)
+
 	for _, o := range opts {
 		o(r)
 	}
 
-	logger := newLogger(r.routerInfo.serverName)
-
-	r.logger = logger
+	r.logger = newLogger(r.routerInfo.serverName, r.customLogger, r.logLevel)
 
 	r.contextPool = sync.Pool{
 		New: func() any {
-			return NewContext(ContextConfig{
+			ctx := NewContext(ContextConfig{
 				ContextIdChannel:          ctxIdChannel,
 				DefaultResponseStatusCode: r.routerInfo.defaultResponseStatusCode,
 				MaxIncomingBodySize:       r.maxFormSize,
+				KeyRing:                   r.keyRing,
 			})
+			ctx.router = r
+
+			return ctx
 		},
 	}
 
@@ -279,18 +416,46 @@ func (r *router) ListenWithContext(ctx ctxpkg.Context) {
 	}
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
-			r.logger.Error(err.Error())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.logger.Error("server stopped", "address", addr, "error", err)
 		}
 	}()
 
+	var adminServer *http.Server
+
+	if r.adminRouter != nil {
+		adminServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", r.adminAddress),
+			Handler: r.adminRouter,
+		}
+
+		go func() {
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				r.logger.Error("admin server stopped", "address", adminServer.Addr, "error", err)
+			}
+		}()
+	}
+
 	signalChannel := make(chan os.Signal, 1)
 
 	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
 
 	var shutdown = func() {
+		r.isShuttingDown.Store(true)
+
+		if r.drainPeriod > 0 {
+			r.logger.Info("draining before shutdown", "period", r.drainPeriod)
+			time.Sleep(r.drainPeriod)
+		}
+
 		if err := server.Shutdown(ctxpkg.Background()); err != nil {
-			r.logger.Error(err.Error())
+			r.logger.Error("failed to shut down the server", "error", err)
+		}
+
+		if adminServer != nil {
+			if err := adminServer.Shutdown(ctxpkg.Background()); err != nil {
+				r.logger.Error("failed to shut down the admin server", "error", err)
+			}
 		}
 	}
 
@@ -349,47 +514,69 @@ func (r *router) Connect(url string, handler HandlerFunc) Route {
 
 // Serve seaches for the right handler – and middleware – based upon the given context.
 func (r *router) Serve(ctx Context) {
-	if r.panicHandler != nil {
-		defer func() {
-			if val := recover(); val != nil {
-				r.panicHandler(ctx, val)
-			}
-		}()
+	// The headers set by the chain are dropped, if it panics.
+	if c, ok := ctx.(*context); ok {
+		c.writer.snapshot()
 	}
 
-	defer func() {
-		ctx.Flush()
-	}()
+	// The deferred functions of the context are called at the
+	// very end, after the panic – if there was any – is handled.
+	defer runDeferred(ctx)
 
-	method := ctx.GetRequestMethod()
-	if method == http.MethodOptions {
-		if r.optionsHandler != nil {
-			r.optionsHandler(ctx)
+	// The panic must be handled before the response is written,
+	// so the partial response of the handler could be replaced.
+	defer func() {
+		if val := recover(); val != nil {
+			val, stack := unwrapPanic(val)
 
-			return
+			if !r.recoverPanic(ctx, val, stack) {
+				panic(val)
+			}
 		}
-	}
 
-	var route ExecuteChainer = nil
+		endResponse(ctx)
+	}()
+
+	var (
+		method = ctx.GetRequestMethod()
+
+		route ExecuteChainer = nil
+	)
 
 	foundRoute, params, err := r.endpointTree.find(method, ctx.GetCleanedUrl())
-	if err != nil {
-		fmt.Println(err)
-		return // TODO: what to do with it?
-	}
 
-	if foundRoute == nil {
+	switch {
+	// The global middlewares – eg. CORS – must
+	// run before the custom OPTIONS handler as well.
+	case method == http.MethodOptions && r.optionsHandler != nil:
+		route = &generalChainer{handler: r.optionsHandler}
+
+	case err != nil:
+		route = r.getEmptyTreeHandler()
+
+	case foundRoute == nil:
+		// The url could be registered, but not with the requested method.
+		if allowed := r.endpointTree.getAllowedMethods(ctx.GetCleanedUrl()); len(allowed) > 0 {
+			ctx.GetResponseHeaders().Set(allowHeaderKey, strings.Join(allowed, ", "))
+			route = r.getEmptyTreeHandler()
+
+			break
+		}
+
 		route = r.getNotFoundHandler()
-	} else {
+
+	default:
 		route = foundRoute
+
+		ctx.BindValue(reqisteredUrlKey, foundRoute.GetUrl())
+		ctx.SetTimeout(foundRoute.GetTimeout(), nil)
 	}
 
 	ctx.BindValue(routeParamsKey, params)
-	ctx.BindValue(reqisteredUrlKey, foundRoute.GetUrl())
 
 	var (
-		lastIndex            uint8 = 0
-		needToExecuteHandler       = true
+		lastIndex            = ctx.GetCurrentIndex()
+		needToExecuteHandler = true
 	)
 
 	var exucuteMiddlewareChain = func(mwType MiddlewareType) {
@@ -411,14 +598,31 @@ func (r *router) Serve(ctx Context) {
 
 	exucuteMiddlewareChain(MiddlewarePreRunner)
 
-	// At this point the route should be a
-	// non-nil value, however a last nil check
-	// must be carried out.
-	if needToExecuteHandler && route != nil {
-		route.ExecuteChain(ctx, lastIndex)
+	var execute = func() {
+		// At this point the route should be a
+		// non-nil value, however a last nil check
+		// must be carried out.
+		if needToExecuteHandler && route != nil {
+			route.ExecuteChain(ctx, lastIndex)
+		}
+
+		// If the route has been timed out, then the
+		// context is owned by the goroutine of the handler.
+		if isTimedOut(ctx) {
+			return
+		}
+
+		exucuteMiddlewareChain(MiddlewarePostRunner)
 	}
 
-	exucuteMiddlewareChain(MiddlewarePostRunner)
+	// If a timeout was set, then the rest of the chain is bounded by it.
+	if c, ok := ctx.(*context); ok {
+		c.executeBounded(execute)
+
+		return
+	}
+
+	execute()
 }
 
 // ServeHTTP is the main entrypoint for every incoming HTTP requests.
@@ -432,6 +636,16 @@ func (router *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	router.Serve(ctx)
 
+	// The context holds only a weak pointer to the request,
+	// so it must be kept alive until the serving is finished.
+	runtime.KeepAlive(r)
+
+	// The timed out context is still used by the handler,
+	// so it is left to the GC instead of the pool.
+	if ctx.isTimedOut() {
+		return
+	}
+
 	// Release every pointer then put it back to the pool.
 	// If we didnt release the all the pointers, then the GC
 	// cant free the pointer until we call ctx.reset on
@@ -472,6 +686,44 @@ func (router *router) getNotFoundHandler() ExecuteChainer {
 	return &generalChainer{handler: defaultNotFoundHandler}
 }
 
+func (router *router) getEmptyTreeHandler() ExecuteChainer {
+	if router.emptyTreeHandler != nil {
+		return &generalChainer{handler: router.emptyTreeHandler}
+	}
+
+	return &generalChainer{handler: defaultEmptyTreeHandler}
+}
+
+// endResponse writes the response of the context, unless it has been already streamed.
+func endResponse(ctx Context) {
+	if c, ok := ctx.(*context); ok {
+		if c.isTimedOut() {
+			return
+		}
+
+		c.writer.end()
+
+		return
+	}
+
+	ctx.Flush()
+}
+
+// runDeferred calls the deferred functions of the context.
+func runDeferred(ctx Context) {
+	// The deferred functions of the timed out context
+	// are called by the goroutine of the handler.
+	if c, ok := ctx.(*context); ok && !c.isTimedOut() {
+		c.runDeferred()
+	}
+}
+
+// isTimedOut returns whether the execution of the given context has been timed out.
+func isTimedOut(ctx Context) bool {
+	c, ok := ctx.(*context)
+	return ok && c.isTimedOut()
+}
+
 func getContextIdChan() contextIdChan {
 	ch := make(chan uint64)
 	go func() {
@@ -488,13 +740,24 @@ func (r *router) addRoute(method string, url string, handler HandlerFunc) Route
 	route := newRoute(url, handler, r)
 
 	if err := r.endpointTree.insert(method, url, route); err != nil {
-		r.logger.Error(err.Error())
+		r.logger.Error("failed to register route", "method", method, "url", url, "error", err)
 		return nil
 	}
 
+	r.logger.Debug("route registered", "method", method, "url", url)
+
 	return route
 }
 
+func (r *router) registerNamedRoute(name string, route Route) {
+	if _, exists := r.namedRoutes[name]; exists {
+		r.logger.Error("route name is already registered", "name", name)
+		return
+	}
+
+	r.namedRoutes[name] = route
+}
+
 func defaultNotFoundHandler(ctx Context) {
 	ctx.Status(http.StatusNotFound)
 }
@@ -0,0 +1,332 @@
+package gorouter
+
+import (
+	ctxpkg "context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	type testCase struct {
+		name    string
+		opts    []routerOptionFunc
+		method  string
+		url     string
+		stopper bool
+
+		expectedStatusCode    int
+		expectedHandlerCalled bool
+		expectedAllowHeader   string
+	}
+
+	tt := []testCase{
+		{
+			name:                  "executes the handler of the found route",
+			method:                http.MethodGet,
+			url:                   "/api/users",
+			expectedStatusCode:    http.StatusOK,
+			expectedHandlerCalled: true,
+		},
+		{
+			name:                  "the first global middleware can stop the chain",
+			method:                http.MethodGet,
+			url:                   "/api/users",
+			stopper:               true,
+			expectedStatusCode:    http.StatusUnauthorized,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "responds with 404 for not registered url",
+			method:                http.MethodGet,
+			url:                   "/api/products",
+			expectedStatusCode:    http.StatusNotFound,
+			expectedHandlerCalled: false,
+		},
+		{
+			name:                  "responds with 405 for not registered method",
+			method:                http.MethodDelete,
+			url:                   "/api/users",
+			expectedStatusCode:    http.StatusMethodNotAllowed,
+			expectedHandlerCalled: false,
+			expectedAllowHeader:   "GET, POST",
+		},
+		{
+			name:   "the global middlewares run before the options handler",
+			opts:   []routerOptionFunc{WithOptionsHandler(func(ctx Context) { ctx.Status(http.StatusOK) })},
+			method: http.MethodOptions,
+			url:    "/api/users",
+
+			stopper:               true,
+			expectedStatusCode:    http.StatusUnauthorized,
+			expectedHandlerCalled: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				handlerCalled bool
+
+				r = New(tc.opts...)
+			)
+
+			if tc.stopper {
+				r.RegisterMiddlewares(NewMiddleware(func(ctx Context) {
+					ctx.Status(http.StatusUnauthorized)
+				}, MiddlewareWithType(MiddlewarePreRunner)))
+			}
+
+			r.Get("/api/users", func(ctx Context) {
+				handlerCalled = true
+				ctx.Status(http.StatusOK)
+			})
+
+			r.Post("/api/users", func(ctx Context) {})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.url, nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if got := rec.Header().Get(allowHeaderKey); got != tc.expectedAllowHeader {
+				t.Errorf("expected allow header: %q; got: %q\n", tc.expectedAllowHeader, got)
+			}
+
+			if handlerCalled != tc.expectedHandlerCalled {
+				t.Errorf("expected handler called: %v; got: %v\n", tc.expectedHandlerCalled, handlerCalled)
+			}
+		})
+	}
+}
+
+func TestDefer(t *testing.T) {
+	var (
+		calls []string
+
+		r = New(WithPanicHandler(func(ctx Context, _ interface{}) {
+			calls = append(calls, "panic handler")
+			ctx.Status(http.StatusInternalServerError)
+		}))
+	)
+
+	r.Get("/api/users", func(ctx Context) {
+		ctx.Defer(func() { calls = append(calls, "first") })
+		ctx.Defer(func() { calls = append(calls, "second") })
+
+		ctx.Copy(strings.NewReader("ok"))
+	})
+
+	r.Get("/api/panic", func(ctx Context) {
+		ctx.Defer(func() { calls = append(calls, "deferred") })
+
+		panic("boom")
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+
+	if rec.Body.String() != "ok" {
+		t.Errorf("expected body: %q; got: %q\n", "ok", rec.Body.String())
+	}
+
+	if expected := "second,first"; strings.Join(calls, ",") != expected {
+		t.Errorf("expected calls: %s; got: %s\n", expected, strings.Join(calls, ","))
+	}
+
+	calls = nil
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+
+	if expected := "panic handler,deferred"; strings.Join(calls, ",") != expected {
+		t.Errorf("expected calls: %s; got: %s\n", expected, strings.Join(calls, ","))
+	}
+}
+
+func TestNestedTimeout(t *testing.T) {
+	type testCase struct {
+		name             string
+		globalTimeout    time.Duration
+		routeTimeout     time.Duration
+		handlerTimeout   time.Duration
+		globalOnTimeout  HandlerFunc
+		handlerOnTimeout HandlerFunc
+
+		expectedStatusCode int
+	}
+
+	var gatewayTimeout = func(ctx Context) {
+		ctx.StatusText(http.StatusGatewayTimeout)
+	}
+
+	tt := []testCase{
+		{
+			name:               "the shorter timeout of the handler is enforced within the global one",
+			globalTimeout:      300 * time.Millisecond,
+			handlerTimeout:     20 * time.Millisecond,
+			handlerOnTimeout:   gatewayTimeout,
+			expectedStatusCode: http.StatusGatewayTimeout,
+		},
+		{
+			name:               "the shorter timeout of the route is enforced within the global one",
+			globalTimeout:      300 * time.Millisecond,
+			routeTimeout:       20 * time.Millisecond,
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:               "the ignored longer timeout does not replace the handler of the active one",
+			globalTimeout:      20 * time.Millisecond,
+			handlerTimeout:     300 * time.Millisecond,
+			handlerOnTimeout:   gatewayTimeout,
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:               "the ignored longer global timeout does not replace the handler of the route",
+			globalTimeout:      300 * time.Millisecond,
+			globalOnTimeout:    gatewayTimeout,
+			routeTimeout:       20 * time.Millisecond,
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New()
+
+			r.RegisterMiddlewares(NewMiddleware(func(ctx Context) {
+				ctx.SetTimeout(tc.globalTimeout, tc.globalOnTimeout)
+				ctx.Next()
+			}))
+
+			route := r.Get("/api/slow", func(ctx Context) {
+				ctx.SetTimeout(tc.handlerTimeout, tc.handlerOnTimeout)
+
+				// The handler does not respect the deadline of the context.
+				time.Sleep(150 * time.Millisecond)
+
+				ctx.Copy(strings.NewReader("slow"))
+			})
+
+			if tc.routeTimeout > 0 {
+				route.Timeout(tc.routeTimeout)
+			}
+
+			var (
+				rec   = httptest.NewRecorder()
+				start = time.Now()
+			)
+
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/slow", nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Errorf("expected the timeout to be hit early; got: %s\n", elapsed)
+			}
+		})
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	type testCase struct {
+		name    string
+		url     string
+		handler HandlerFunc
+
+		expectedStatusCode int
+		expectedBody       string
+		expectedHeader     string
+	}
+
+	var (
+		finished = make(chan error, 1)
+		panics   = make(chan any, 1)
+	)
+
+	tt := []testCase{
+		{
+			name: "the fast handler writes the response",
+			url:  "/api/fast",
+			handler: func(ctx Context) {
+				ctx.GetResponseHeaders().Set("X-Handler", "fast")
+				ctx.Copy(strings.NewReader("fast"))
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "fast",
+			expectedHeader:     "fast",
+		},
+		{
+			name: "the slow handler is timed out and its writes are discarded",
+			url:  "/api/slow",
+			handler: func(ctx Context) {
+				<-ctx.GetRequestContext().Done()
+
+				// Making sure, that the timeout wins.
+				time.Sleep(20 * time.Millisecond)
+
+				ctx.GetResponseHeaders().Set("X-Handler", "slow")
+				ctx.Copy(strings.NewReader("slow"))
+				ctx.Flush()
+
+				finished <- ctx.GetRequestContext().Err()
+			},
+			expectedStatusCode: http.StatusServiceUnavailable,
+			expectedBody:       http.StatusText(http.StatusServiceUnavailable),
+		},
+	}
+
+	r := New(WithPanicHandler(func(ctx Context, val interface{}) {
+		panics <- val
+		ctx.Status(http.StatusInternalServerError)
+	}))
+
+	for _, tc := range tt {
+		r.Get(tc.url, tc.handler).Timeout(50 * time.Millisecond)
+	}
+
+	r.Get("/api/panic", func(ctx Context) {
+		panic("boom")
+	}).Timeout(50 * time.Millisecond)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Errorf("expected status code: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("X-Handler"); got != tc.expectedHeader {
+				t.Errorf("expected header: %q; got: %q\n", tc.expectedHeader, got)
+			}
+		})
+	}
+
+	select {
+	case err := <-finished:
+		if !errors.Is(err, ctxpkg.DeadlineExceeded) {
+			t.Errorf("expected error: %v; got: %v\n", ctxpkg.DeadlineExceeded, err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("the slow handler has not finished\n")
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+
+	if val := <-panics; val != "boom" {
+		t.Errorf("expected panic: %v; got: %v\n", "boom", val)
+	}
+}
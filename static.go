@@ -0,0 +1,488 @@
+package gorouter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	staticParamKey string = "filepath"
+
+	defaultIndexFile string = "index.html"
+
+	defaultFileContentType string = "application/octet-stream"
+
+	etagHeaderKey               string = "ETag"
+	cacheControlHeaderKey       string = "Cache-Control"
+	contentEncodingHeaderKey    string = "Content-Encoding"
+	contentDispositionHeaderKey string = "Content-Disposition"
+	acceptEncodingHeaderKey     string = "Accept-Encoding"
+	locationHeaderKey           string = "Location"
+	varyHeaderKey               string = "Vary"
+)
+
+type precompressedEncoding struct {
+	name string
+	ext  string
+}
+
+// The order of the slice determines the preference
+// in case of the client accepts multiple encodings.
+var precompressedEncodings = []precompressedEncoding{
+	{name: "br", ext: ".br"},
+	{name: "gzip", ext: ".gz"},
+}
+
+// StaticConfig holds the configuration of serving static files
+// from a fs.FS registered with Router.Static.
+type StaticConfig struct {
+	// IndexFiles are looked up – in the given order – in case of
+	// a directory is requested. By default it is index.html.
+	IndexFiles []string
+
+	// Browse enables the listing of directories,
+	// which does not have any of the index files.
+	Browse bool
+
+	// Precompressed enables serving the .br or .gz sibling
+	// of the requested file, if the client accepts the encoding.
+	Precompressed bool
+
+	// MaxAge is the max-age directive of the Cache-Control header.
+	// Zero value means there is no Cache-Control header written.
+	MaxAge time.Duration
+}
+
+type etagEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+type fileServer struct {
+	fsys fs.FS
+	conf StaticConfig
+
+	// The computed etags of the files, so the content
+	// hashing is only done once per file.
+	etags sync.Map
+}
+
+func newFileServer(fsys fs.FS, conf StaticConfig) *fileServer {
+	if len(conf.IndexFiles) == 0 {
+		conf.IndexFiles = []string{defaultIndexFile}
+	}
+
+	return &fileServer{
+		fsys: fsys,
+		conf: conf,
+	}
+}
+
+// Static registers GET and HEAD routes serving the files of
+// the given fs.FS under the given prefix. Works with embed.FS as well.
+func (r *router) Static(prefix string, fsys fs.FS, conf StaticConfig) {
+	var (
+		fsrv = newFileServer(fsys, conf)
+		url  = fmt.Sprintf("%s/{%s%s}", strings.TrimSuffix(prefix, slash), staticParamKey, catchAllSuffix)
+	)
+
+	r.Get(url, fsrv.serve)
+	r.Head(url, fsrv.serve)
+}
+
+func (fsrv *fileServer) serve(ctx Context) {
+	r := ctx.GetRequest()
+	if r == nil {
+		ctx.StatusText(http.StatusInternalServerError)
+
+		return
+	}
+
+	name, err := cleanStaticPath(ctx.GetParam(staticParamKey))
+	if err != nil {
+		ctx.Status(http.StatusBadRequest)
+
+		return
+	}
+
+	info, err := fs.Stat(fsrv.fsys, name)
+	if err != nil {
+		ctx.Status(statusFromFsError(err))
+
+		return
+	}
+
+	if info.IsDir() {
+		// The relative links of the index file and the listing
+		// only work properly if the URL ends with a slash.
+		if u := ctx.GetCleanedUrl(); !strings.HasSuffix(u, slash) {
+			location := u + slash
+			if q := r.URL.RawQuery; q != "" {
+				location += "?" + q
+			}
+
+			ctx.AppendHttpHeader(locationHeaderKey, location)
+			ctx.Status(http.StatusMovedPermanently)
+
+			return
+		}
+
+		indexName, indexInfo := fsrv.findIndex(name)
+		if indexInfo == nil {
+			if !fsrv.conf.Browse {
+				ctx.Status(http.StatusNotFound)
+
+				return
+			}
+
+			fsrv.listDir(ctx, name)
+
+			return
+		}
+
+		name, info = indexName, indexInfo
+	}
+
+	if fsrv.conf.MaxAge > 0 {
+		ctx.AppendHttpHeader(cacheControlHeaderKey, fmt.Sprintf("public, max-age=%d", int64(fsrv.conf.MaxAge.Seconds())))
+	}
+
+	if fsrv.conf.Precompressed {
+		ctx.AppendHttpHeader(varyHeaderKey, acceptEncodingHeaderKey)
+
+		accept := ctx.GetRequestHeader(acceptEncodingHeaderKey)
+
+		for _, enc := range precompressedEncodings {
+			if !acceptsEncoding(accept, enc.name) {
+				continue
+			}
+
+			encInfo, err := fs.Stat(fsrv.fsys, name+enc.ext)
+			if err != nil || encInfo.IsDir() {
+				continue
+			}
+
+			// The content type must be the one of the original file,
+			// otherwise it would be detected from the compressed content.
+			contentType := mime.TypeByExtension(path.Ext(name))
+			if contentType == "" {
+				contentType = defaultFileContentType
+			}
+
+			ctx.AppendHttpHeader(contentTypeHeaderKey, contentType)
+			ctx.AppendHttpHeader(contentEncodingHeaderKey, enc.name)
+
+			fsrv.serveFile(ctx, name+enc.ext, encInfo)
+
+			return
+		}
+	}
+
+	fsrv.serveFile(ctx, name, info)
+}
+
+func (fsrv *fileServer) serveFile(ctx Context, name string, info fs.FileInfo) {
+	if etag, err := fsrv.getEtag(name, info); err == nil {
+		ctx.AppendHttpHeader(etagHeaderKey, etag)
+	}
+
+	ctx.FileFS(fsrv.fsys, name)
+}
+
+// getEtag returns the cached etag of the file, or computes
+// it if the file has changed since the last computation.
+func (fsrv *fileServer) getEtag(name string, info fs.FileInfo) (string, error) {
+	if v, ok := fsrv.etags.Load(name); ok {
+		entry := v.(*etagEntry)
+		if entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+			return entry.etag, nil
+		}
+	}
+
+	f, err := fsrv.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	etag, err := computeEtag(info, f)
+	if err != nil {
+		return "", err
+	}
+
+	fsrv.etags.Store(name, &etagEntry{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		etag:    etag,
+	})
+
+	return etag, nil
+}
+
+func (fsrv *fileServer) findIndex(dir string) (string, fs.FileInfo) {
+	for _, index := range fsrv.conf.IndexFiles {
+		name := path.Join(dir, index)
+
+		info, err := fs.Stat(fsrv.fsys, name)
+		if err == nil && !info.IsDir() {
+			return name, info
+		}
+	}
+
+	return "", nil
+}
+
+func (fsrv *fileServer) listDir(ctx Context, dir string) {
+	entries, err := fs.ReadDir(fsrv.fsys, dir)
+	if err != nil {
+		ctx.Status(statusFromFsError(err))
+
+		return
+	}
+
+	var b bytes.Buffer
+
+	b.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += slash
+		}
+
+		// The name could contain a colon, which would be
+		// treated as a scheme without the leading ./
+		link := url.URL{Path: "./" + name}
+
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(name))
+	}
+
+	b.WriteString("</pre>\n")
+
+	ctx.Render(http.StatusOK, &HtmlResponse{Data: b.Bytes()})
+}
+
+// File serves the file at the given path of the filesystem.
+// Handles conditional and range requests.
+func (ctx *context) File(filePath string) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		ctx.Status(statusFromFsError(err))
+
+		return
+	}
+	defer f.Close()
+
+	ctx.serveFile(f, filePath)
+}
+
+// FileFS serves the file with the given name from the fs.FS.
+// Handles conditional and range requests.
+func (ctx *context) FileFS(fsys fs.FS, name string) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		ctx.Status(statusFromFsError(err))
+
+		return
+	}
+	defer f.Close()
+
+	ctx.serveFile(f, name)
+}
+
+// Attachment serves the file at the given path of the filesystem,
+// signalling the client to download the file with the given name.
+// If the name is empty, then the base of the path is used.
+func (ctx *context) Attachment(filePath string, name string) {
+	if name == "" {
+		name = filepath.Base(filePath)
+	}
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": name})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+
+	ctx.AppendHttpHeader(contentDispositionHeaderKey, disposition)
+	ctx.File(filePath)
+}
+
+func (ctx *context) serveFile(f fs.File, name string) {
+	info, err := f.Stat()
+	if err != nil {
+		ctx.Status(statusFromFsError(err))
+
+		return
+	}
+
+	if info.IsDir() {
+		ctx.Status(http.StatusNotFound)
+
+		return
+	}
+
+	// Not every fs.File implements io.Seeker,
+	// in that case the content is read to the memory.
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+
+	header := ctx.writer.w.Header()
+
+	if header.Get(etagHeaderKey) == "" {
+		etag, err := computeEtag(info, content)
+		if err != nil {
+			ctx.Status(http.StatusInternalServerError)
+
+			return
+		}
+
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			ctx.Status(http.StatusInternalServerError)
+
+			return
+		}
+
+		header.Set(etagHeaderKey, etag)
+	}
+
+	if header.Get(contentTypeHeaderKey) == "" {
+		if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+			header.Set(contentTypeHeaderKey, contentType)
+		}
+	}
+
+	ctx.ServeContent(name, info.ModTime(), content)
+}
+
+// ServeContent serves the given content handling conditional – including
+// If-Range –, single and multiple range requests. The content is streamed
+// to the connection, instead of copying it to the buffer of the response.
+// If the Content-Type header is not set, it is determined by the extension
+// of the name, or the content itself. If the ETag header is set, it is
+// used for the conditional requests alongside with the modification time.
+func (ctx *context) ServeContent(name string, modTime time.Time, content io.ReadSeeker) {
+	r := ctx.GetRequest()
+	if r == nil {
+		ctx.StatusText(http.StatusInternalServerError)
+
+		return
+	}
+
+	cw := &contentWriter{
+		rw:          ctx.writer,
+		isStreaming: true,
+	}
+
+	http.ServeContent(cw, r, name, modTime, content)
+}
+
+// computeEtag returns a strong etag based upon the modification
+// time and the size of the file. If there is no modification time –
+// like in case of embed.FS –, then the content is hashed.
+func computeEtag(info fs.FileInfo, content io.Reader) (string, error) {
+	if modTime := info.ModTime(); !modTime.IsZero() {
+		return fmt.Sprintf("\"%x-%x\"", modTime.UnixNano(), info.Size()), nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+
+	return "\"" + hex.EncodeToString(h.Sum(nil)[:16]) + "\"", nil
+}
+
+// cleanStaticPath returns the cleaned, fs.FS compatible name
+// of the given – escaped – URL path. Any path trying to
+// step out of the root of the filesystem is rejected.
+func cleanStaticPath(p string) (string, error) {
+	unescaped, err := url.PathUnescape(p)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.ContainsAny(unescaped, "\\\x00") {
+		return "", fs.ErrInvalid
+	}
+
+	for _, segment := range strings.Split(unescaped, slash) {
+		if segment == ".." {
+			return "", fs.ErrInvalid
+		}
+	}
+
+	name := strings.TrimPrefix(path.Clean(slash+unescaped), slash)
+	if name == "" {
+		name = "."
+	}
+
+	if !fs.ValidPath(name) {
+		return "", fs.ErrInvalid
+	}
+
+	return name, nil
+}
+
+func statusFromFsError(err error) int {
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, fs.ErrInvalid):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// acceptsEncoding returns whether the given Accept-Encoding
+// header value allows the given encoding.
+func acceptsEncoding(header string, encoding string) bool {
+	var isWildcardAccepted bool
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+
+		if strings.EqualFold(name, encoding) {
+			return q > 0
+		}
+
+		if name == "*" {
+			isWildcardAccepted = q > 0
+		}
+	}
+
+	return isWildcardAccepted
+}
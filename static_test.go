@@ -0,0 +1,550 @@
+package gorouter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestCleanStaticPath(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected string
+		isValid  bool
+	}
+
+	tt := []testCase{
+		{
+			name:     "empty path is the root",
+			input:    "",
+			expected: ".",
+			isValid:  true,
+		},
+		{
+			name:     "nested path is returned as is",
+			input:    "css/main.css",
+			expected: "css/main.css",
+			isValid:  true,
+		},
+		{
+			name:     "escaped characters are unescaped",
+			input:    "my%20file.txt",
+			expected: "my file.txt",
+			isValid:  true,
+		},
+		{
+			name:     "redundant separators are removed",
+			input:    "css//./main.css",
+			expected: "css/main.css",
+			isValid:  true,
+		},
+		{
+			name:    "parent directory is rejected",
+			input:   "../secret",
+			isValid: false,
+		},
+		{
+			name:    "escaped parent directory is rejected",
+			input:   "css/%2e%2e/%2e%2e/secret",
+			isValid: false,
+		},
+		{
+			name:    "backslash is rejected",
+			input:   "..%5csecret",
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := cleanStaticPath(tc.input)
+
+			if isValid := err == nil; isValid != tc.isValid {
+				t.Fatalf("expected valid: %t; got error: %v\n", tc.isValid, err)
+			}
+
+			if name != tc.expected {
+				t.Errorf("expected name: %s; got name: %s\n", tc.expected, name)
+			}
+		})
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	type testCase struct {
+		header   string
+		encoding string
+		expected bool
+	}
+
+	tt := []testCase{
+		{header: "", encoding: "gzip", expected: false},
+		{header: "gzip", encoding: "gzip", expected: true},
+		{header: "deflate, gzip;q=1.0, *;q=0.5", encoding: "br", expected: true},
+		{header: "gzip;q=0", encoding: "gzip", expected: false},
+		{header: "*;q=0, br", encoding: "gzip", expected: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.header+" "+tc.encoding, func(t *testing.T) {
+			if got := acceptsEncoding(tc.header, tc.encoding); got != tc.expected {
+				t.Errorf("expected: %t; got: %t\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestStatic(t *testing.T) {
+	modTime := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	fsys := fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>home</h1>"), ModTime: modTime},
+		"css/main.css":     {Data: []byte("body{}"), ModTime: modTime},
+		"js/app.js":        {Data: []byte("console.log(1)"), ModTime: modTime},
+		"js/app.js.gz":     {Data: []byte("gzipped"), ModTime: modTime},
+		"data/numbers.txt": {Data: []byte("0123456789"), ModTime: modTime},
+		"embedded.txt":     {Data: []byte("no modtime")},
+	}
+
+	type testCase struct {
+		name    string
+		conf    StaticConfig
+		method  string
+		url     string
+		headers map[string]string
+
+		expectedStatusCode int
+		expectedBody       string
+		expectedHeaders    map[string]string
+	}
+
+	tt := []testCase{
+		{
+			name:               "serves the file with content-type and validators",
+			url:                "/static/css/main.css",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "body{}",
+			expectedHeaders: map[string]string{
+				"Content-Type":  "text/css; charset=utf-8",
+				"Last-Modified": modTime.Format(http.TimeFormat),
+				"Accept-Ranges": "bytes",
+			},
+		},
+		{
+			name:               "head request has no body",
+			method:             http.MethodHead,
+			url:                "/static/css/main.css",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "",
+		},
+		{
+			name:               "responds 404 for missing files",
+			url:                "/static/css/missing.css",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "responds 400 for path traversal",
+			url:                "/static/css/%2e%2e/%2e%2e/secret",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "responds 304 if the modification time matches",
+			url:                "/static/css/main.css",
+			headers:            map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:               "responds 304 if the etag matches",
+			url:                "/static/css/main.css",
+			headers:            map[string]string{"If-None-Match": "\"17a6101701650000-6\""},
+			expectedStatusCode: http.StatusNotModified,
+		},
+		{
+			name:               "hashes the content if there is no modification time",
+			url:                "/static/embedded.txt",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "no modtime",
+			expectedHeaders: map[string]string{
+				"ETag": "\"cc4f23f1f3f23a86a0b81b5021b3ea25\"",
+			},
+		},
+		{
+			name:               "serves the requested range",
+			url:                "/static/data/numbers.txt",
+			headers:            map[string]string{"Range": "bytes=2-4"},
+			expectedStatusCode: http.StatusPartialContent,
+			expectedBody:       "234",
+			expectedHeaders: map[string]string{
+				"Content-Range": "bytes 2-4/10",
+			},
+		},
+		{
+			name:               "responds 416 for unsatisfiable range",
+			url:                "/static/data/numbers.txt",
+			headers:            map[string]string{"Range": "bytes=20-30"},
+			expectedStatusCode: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:               "serves the index file of the root",
+			url:                "/static/",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "<h1>home</h1>",
+		},
+		{
+			name:               "redirects directories without trailing slash",
+			url:                "/static/css?v=1",
+			expectedStatusCode: http.StatusMovedPermanently,
+			expectedHeaders: map[string]string{
+				"Location": "/static/css/?v=1",
+			},
+		},
+		{
+			name:               "responds 404 for directories without index if browsing is disabled",
+			url:                "/static/css/",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "lists directories without index if browsing is enabled",
+			conf:               StaticConfig{Browse: true},
+			url:                "/static/js/",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n<a href=\"./app.js\">app.js</a>\n<a href=\"./app.js.gz\">app.js.gz</a>\n</pre>\n",
+		},
+		{
+			name:               "serves the precompressed sibling if accepted",
+			conf:               StaticConfig{Precompressed: true},
+			url:                "/static/js/app.js",
+			headers:            map[string]string{"Accept-Encoding": "br, gzip"},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "gzipped",
+			expectedHeaders: map[string]string{
+				"Content-Encoding": "gzip",
+				"Content-Type":     "text/javascript; charset=utf-8",
+				"Vary":             "Accept-Encoding",
+			},
+		},
+		{
+			name:               "serves the original file if the encoding is not accepted",
+			conf:               StaticConfig{Precompressed: true},
+			url:                "/static/js/app.js",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "console.log(1)",
+			expectedHeaders: map[string]string{
+				"Content-Encoding": "",
+			},
+		},
+		{
+			name:               "writes cache-control if max-age is set",
+			conf:               StaticConfig{MaxAge: time.Hour},
+			url:                "/static/css/main.css",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "body{}",
+			expectedHeaders: map[string]string{
+				"Cache-Control": "public, max-age=3600",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := New()
+			r.Static("/static", fsys, tc.conf)
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			var (
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest(method, tc.url, nil)
+			)
+
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("expected statusCode: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+
+			if method == http.MethodHead && rec.Body.Len() > 0 {
+				t.Errorf("expected empty body; got: %q\n", rec.Body.String())
+			}
+
+			for k, v := range tc.expectedHeaders {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expected header %s: %q; got: %q\n", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticMultipartRange(t *testing.T) {
+	r := New()
+	r.Static("/", fstest.MapFS{"numbers.txt": {Data: []byte("0123456789")}}, StaticConfig{})
+
+	var (
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/numbers.txt", nil)
+	)
+
+	req.Header.Set("Range", "bytes=0-1,5-6")
+
+	r.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "multipart/byteranges") {
+		t.Fatalf("expected multipart/byteranges content-type; got: %s\n", ct)
+	}
+
+	for _, part := range []string{"Content-Range: bytes 0-1/10", "01", "Content-Range: bytes 5-6/10", "56"} {
+		if !strings.Contains(rec.Body.String(), part) {
+			t.Errorf("expected body to contain: %q\n", part)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+
+	filePath := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(filePath, []byte("a,b\n1,2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name   string
+		handle func(Context)
+
+		expectedStatusCode  int
+		expectedBody        string
+		expectedDisposition string
+	}
+
+	tt := []testCase{
+		{
+			name:               "serves the file",
+			handle:             func(ctx Context) { ctx.File(filePath) },
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "a,b\n1,2\n",
+		},
+		{
+			name:               "responds 404 for missing file",
+			handle:             func(ctx Context) { ctx.File(filepath.Join(dir, "missing.csv")) },
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "responds 404 for directories",
+			handle:             func(ctx Context) { ctx.File(dir) },
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:                "serves the file as attachment with the base name",
+			handle:              func(ctx Context) { ctx.Attachment(filePath, "") },
+			expectedStatusCode:  http.StatusOK,
+			expectedBody:        "a,b\n1,2\n",
+			expectedDisposition: "attachment; filename=report.csv",
+		},
+		{
+			name:                "serves the file as attachment with encoded name",
+			handle:              func(ctx Context) { ctx.Attachment(filePath, "jelentés.csv") },
+			expectedStatusCode:  http.StatusOK,
+			expectedBody:        "a,b\n1,2\n",
+			expectedDisposition: "attachment; filename*=utf-8''jelent%C3%A9s.csv",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				ctx = NewContext(ContextConfig{})
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodGet, "/", nil)
+			)
+
+			ctx.Reset(rec, req)
+
+			tc.handle(ctx)
+
+			ctx.Flush()
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("expected statusCode: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("Content-Disposition"); got != tc.expectedDisposition {
+				t.Errorf("expected disposition: %q; got: %q\n", tc.expectedDisposition, got)
+			}
+		})
+	}
+}
+
+func TestServeContent(t *testing.T) {
+	var (
+		modTime = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		content = "0123456789"
+		etag    = "\"v1\""
+	)
+
+	type testCase struct {
+		name    string
+		headers map[string]string
+
+		expectedStatusCode int
+		expectedBody       string
+	}
+
+	tt := []testCase{
+		{
+			name:               "serves the whole content",
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       content,
+		},
+		{
+			name:               "serves the requested range",
+			headers:            map[string]string{"Range": "bytes=7-"},
+			expectedStatusCode: http.StatusPartialContent,
+			expectedBody:       "789",
+		},
+		{
+			name:               "serves the range if the etag of If-Range matches",
+			headers:            map[string]string{"Range": "bytes=0-2", "If-Range": etag},
+			expectedStatusCode: http.StatusPartialContent,
+			expectedBody:       "012",
+		},
+		{
+			name:               "serves the whole content if the etag of If-Range does not match",
+			headers:            map[string]string{"Range": "bytes=0-2", "If-Range": "\"v0\""},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       content,
+		},
+		{
+			name:               "serves the whole content if the date of If-Range is outdated",
+			headers:            map[string]string{"Range": "bytes=0-2", "If-Range": modTime.Add(-time.Hour).Format(http.TimeFormat)},
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       content,
+		},
+		{
+			name:               "responds 416 for unsatisfiable range",
+			headers:            map[string]string{"Range": "bytes=10-"},
+			expectedStatusCode: http.StatusRequestedRangeNotSatisfiable,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				ctx = NewContext(ContextConfig{})
+				rec = httptest.NewRecorder()
+				req = httptest.NewRequest(http.MethodGet, "/download", nil)
+			)
+
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			ctx.Reset(rec, req)
+
+			ctx.AppendHttpHeader("ETag", etag)
+			ctx.ServeContent("numbers.txt", modTime, strings.NewReader(content))
+
+			// The content must be streamed, instead of being buffered.
+			if ctx.writer.buff.Len() > 0 {
+				t.Errorf("expected empty buffer; got %d bytes\n", ctx.writer.buff.Len())
+			}
+
+			ctx.writer.end()
+
+			if rec.Code != tc.expectedStatusCode {
+				t.Fatalf("expected statusCode: %d; got: %d\n", tc.expectedStatusCode, rec.Code)
+			}
+
+			if tc.expectedBody != "" && rec.Body.String() != tc.expectedBody {
+				t.Errorf("expected body: %q; got: %q\n", tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServeContentWithoutRequest(t *testing.T) {
+	var (
+		ctx = NewContext(ContextConfig{})
+		rec = httptest.NewRecorder()
+	)
+
+	// The context has no request, eg. it has been already collected.
+	ctx.Reset(rec, nil)
+	ctx.ServeContent("a.txt", time.Now(), strings.NewReader("content"))
+	endResponse(ctx)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestStaticWithoutRequest(t *testing.T) {
+	var (
+		ctx  = NewContext(ContextConfig{})
+		rec  = httptest.NewRecorder()
+		fsrv = newFileServer(fstest.MapFS{"dir/index.html": {Data: []byte("index")}}, StaticConfig{})
+	)
+
+	// The context has no request, eg. it has been already collected.
+	ctx.Reset(rec, nil)
+	fsrv.serve(ctx)
+	endResponse(ctx)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d; got: %d\n", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestFlushStreamsResponse(t *testing.T) {
+	var (
+		ctx = NewContext(ContextConfig{})
+		rec = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodGet, "/events", nil)
+	)
+
+	ctx.Reset(rec, req)
+
+	ctx.Status(http.StatusAccepted)
+	ctx.WriteResponse([]byte("first;"))
+	ctx.Flush()
+
+	if !rec.Flushed || rec.Body.String() != "first;" {
+		t.Fatalf("expected flushed body: %q; got: %q\n", "first;", rec.Body.String())
+	}
+
+	ctx.WriteResponse([]byte("second;"))
+	ctx.writer.end()
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("expected statusCode: %d; got: %d\n", http.StatusAccepted, rec.Code)
+	}
+
+	if rec.Body.String() != "first;second;" {
+		t.Errorf("expected body: %q; got: %q\n", "first;second;", rec.Body.String())
+	}
+
+	if got := ctx.GetInfo().WrittenBytes; got != 13 {
+		t.Errorf("expected written bytes: %d; got: %d\n", 13, got)
+	}
+}
@@ -0,0 +1,344 @@
+package gorouter
+
+import (
+	ctxpkg "context"
+	"net/http"
+	"sync"
+	"time"
+	"weak"
+)
+
+// timeoutWriter guards the underlying writer of a context with a timeout.
+// Once the execution is bounded, the handler gets its own copy of the
+// headers, and after the timeout everything it writes is discarded,
+// so the timeout response could be written without data races.
+type timeoutWriter struct {
+	w http.ResponseWriter
+
+	mu sync.Mutex
+	h  http.Header
+
+	isBounded   bool
+	isTimedOut  bool
+	isFinished  bool
+	wroteHeader bool
+
+	// The earlier deadlines set while the execution is already bounded.
+	rearm chan timeoutDeadline
+
+	// Closed, once the timeout response has been written. The status code
+	// and the size of the body of it are reported by GetInfo afterwards.
+	responded    chan struct{}
+	statusCode   int
+	writtenBytes int
+}
+
+// timeoutDeadline is a deadline alongside the handler of its timeout.
+type timeoutDeadline struct {
+	deadline  time.Time
+	onTimeout HandlerFunc
+}
+
+var (
+	_ http.ResponseWriter = (*timeoutWriter)(nil)
+	_ http.Flusher        = (*timeoutWriter)(nil)
+)
+
+func (tw *timeoutWriter) Header() http.Header {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isBounded {
+		return tw.h
+	}
+	return tw.w.Header()
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.writeHeader(statusCode)
+}
+
+func (tw *timeoutWriter) writeHeader(statusCode int) {
+	if tw.isTimedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+
+	if tw.isBounded {
+		header := tw.w.Header()
+		clear(header)
+
+		for k, v := range tw.h {
+			header[k] = v
+		}
+	}
+
+	tw.w.WriteHeader(statusCode)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isTimedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	tw.writeHeader(http.StatusOK)
+
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isTimedOut {
+		return
+	}
+
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// bound gives the handler its own copy of the headers.
+func (tw *timeoutWriter) bound() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.isBounded = true
+	tw.h = tw.w.Header().Clone()
+	tw.rearm = make(chan timeoutDeadline, 1)
+	tw.responded = make(chan struct{})
+}
+
+// finish marks the execution finished, unless it has been timed out already.
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isTimedOut {
+		return false
+	}
+	tw.isFinished = true
+
+	return true
+}
+
+// timeout marks the execution timed out, unless it has been finished already.
+// It returns whether the status code could be still written.
+func (tw *timeoutWriter) timeout() (bool, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isFinished {
+		return false, false
+	}
+	tw.isTimedOut = true
+
+	return true, !tw.wroteHeader
+}
+
+// SetTimeout bounds the execution of the rest of the chain with the given
+// timeout. The deadline is derived on the context of the request, and if
+// it is hit, then the onTimeout handler – by default 503 – writes the
+// response, while everything written by the handler is discarded.
+// If it is called multiple times, then the earliest deadline is applied
+// alongside its onTimeout handler – even if the execution is already bounded.
+func (ctx *context) SetTimeout(d time.Duration, onTimeout HandlerFunc) {
+	if d <= 0 || ctx.writer.w == nil {
+		return
+	}
+
+	deadline := time.Now().Add(d)
+	if !ctx.deadline.IsZero() && ctx.deadline.Before(deadline) {
+		return
+	}
+	ctx.deadline = deadline
+	ctx.onTimeout = onTimeout
+
+	// The timer of the bounded execution must be re-armed. Only the goroutine
+	// of the handler sends, so after the stale deadline is dropped, the
+	// channel has room for the new – always earlier – one.
+	if tw := ctx.timeoutWriter; tw != nil && tw.isBounded {
+		select {
+		case <-tw.rearm:
+		default:
+		}
+
+		tw.rearm <- timeoutDeadline{deadline: deadline, onTimeout: onTimeout}
+	}
+
+	// The writers wrapping it afterwards – eg. compression –
+	// are guarded by the timeout as well.
+	if ctx.timeoutWriter == nil {
+		ctx.timeoutWriter = &timeoutWriter{w: ctx.writer.w}
+		ctx.writer.w = ctx.timeoutWriter
+	}
+
+	if r := ctx.GetRequest(); r != nil {
+		reqCtx, cancel := ctxpkg.WithDeadline(r.Context(), deadline)
+		ctx.Defer(cancel)
+
+		ctx.SetRequestContext(reqCtx)
+	}
+}
+
+// GetRequestContext returns the context of the request,
+// which carries the deadline of the timeout – if there is any.
+func (ctx *context) GetRequestContext() ctxpkg.Context {
+	if r := ctx.GetRequest(); r != nil {
+		return r.Context()
+	}
+	return ctxpkg.Background()
+}
+
+// isTimedOut returns whether the execution of the context has been timed
+// out, in which case the context is owned by the goroutine of the handler.
+func (ctx *context) isTimedOut() bool {
+	return ctx.timedOut.Load()
+}
+
+// executeBounded executes the given function in a separate goroutine,
+// if the context has a deadline, which is not bounded yet. If the deadline
+// is hit, then the timeout response is written and false is returned,
+// meaning the caller must not touch the context anymore. The goroutine
+// calls the deferred functions of the context, once the function returns.
+// If the chain sets an earlier deadline meanwhile, then the timer is re-armed.
+func (ctx *context) executeBounded(fn func()) bool {
+	if ctx.deadline.IsZero() || ctx.timeoutWriter.isBounded {
+		fn()
+
+		return true
+	}
+
+	var (
+		tw        = ctx.timeoutWriter
+		request   = ctx.request
+		onTimeout = ctx.onTimeout
+
+		done  = make(chan any, 1)
+		timer = time.NewTimer(time.Until(ctx.deadline))
+	)
+	defer timer.Stop()
+
+	tw.bound()
+
+	go func() {
+		defer func() {
+			val := recover()
+
+			if tw.finish() {
+				// The stack trace of the handler is available only here.
+				if val != nil {
+					val = &boundedPanic{val: val, stack: captureStack(ctx.stackSize())}
+				}
+
+				done <- val
+
+				return
+			}
+
+			if val != nil {
+				ctx.logPanic(val)
+			}
+
+			// The deferred functions must see the timeout response, eg. the metrics.
+			<-tw.responded
+
+			defer func() {
+				if val := recover(); val != nil {
+					ctx.logPanic(val)
+				}
+			}()
+
+			ctx.runDeferred()
+			ctx.derivedRequest = nil
+		}()
+
+		fn()
+	}()
+
+	var val any
+
+wait:
+	for {
+		select {
+		case val = <-done:
+			break wait
+		case d := <-tw.rearm:
+			// An earlier deadline has been set by the chain meanwhile.
+			timer.Reset(time.Until(d.deadline))
+			onTimeout = d.onTimeout
+		case <-timer.C:
+			isTimedOut, canWriteHeader := tw.timeout()
+			if !isTimedOut {
+				// It has been finished meanwhile.
+				val = <-done
+
+				break wait
+			}
+
+			if canWriteHeader {
+				tw.statusCode, tw.writtenBytes = writeTimeoutResponse(ctx.router, tw.w, request, onTimeout)
+			}
+
+			// It is set after the response, so GetInfo could read it without lock.
+			ctx.timedOut.Store(true)
+			close(tw.responded)
+
+			return false
+		}
+	}
+
+	// The panic is passed to the panic handler of the router.
+	if val != nil {
+		panic(val)
+	}
+
+	return true
+}
+
+// stackSize returns the maximum size of the captured stack traces.
+func (ctx *context) stackSize() int {
+	if ctx.router == nil {
+		return defaultStackSize
+	}
+
+	return ctx.router.recovery.StackSize
+}
+
+// writeTimeoutResponse writes the response of the onTimeout handler with a new
+// context, since the original is owned by the handler. It returns the written
+// status code and the size of the written body.
+func writeTimeoutResponse(r *router, w http.ResponseWriter, request weak.Pointer[http.Request], onTimeout HandlerFunc) (int, int) {
+	tctx := NewContext(ContextConfig{DefaultResponseStatusCode: http.StatusServiceUnavailable})
+
+	if onTimeout == nil {
+		onTimeout = defaultTimeoutHandler
+	}
+
+	tctx.router = r
+	tctx.ctx = ctxpkg.Background()
+	tctx.writer.w = w
+	tctx.request = request
+	tctx.startTime = time.Now()
+
+	onTimeout(tctx)
+
+	tctx.writer.end()
+
+	return tctx.writer.getStatusCode(), tctx.writer.writtenBytes
+}
+
+func (ctx *context) logPanic(val any) {
+	ctx.GetLogger().Error("panic after timeout", "panic", val)
+}
+
+func defaultTimeoutHandler(ctx Context) {
+	ctx.StatusText(http.StatusServiceUnavailable)
+}
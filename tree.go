@@ -14,6 +14,7 @@ var (
 	errUrlAlreadyStored  error = errors.New("the given URL is already stored with the same method")
 	errInvalidMethod     error = errors.New("invalid HTTP method")
 	errUnsupportedMethod error = errors.New("method not supported")
+	errMalformedCatchAll error = errors.New("malformed catch-all param: it must be the last segment")
 
 	paramStart       string = "{"
 	paramEnd         string = "}"
@@ -21,6 +22,10 @@ var (
 	paramPlaceholder string = "/{}"
 	slashRune        rune   = '/'
 	paramStartByte   byte   = '{'
+
+	catchAllSuffix      string = "..."
+	catchAllPlaceholder string = "/*"
+	catchAllByte        byte   = '*'
 )
 
 // Every registered URL replaces the inital params,
@@ -28,6 +33,9 @@ var (
 type param struct {
 	key   string // In case of {foo} the stored key is key.
 	index int    // Stores index of the segment where the key originally was.
+
+	// In case of {foo...} the param matches all the remaining segments.
+	isCatchAll bool
 }
 
 type nodeValue struct {
@@ -61,6 +69,19 @@ var methodMap = map[string]methodValue{
 	http.MethodTrace:   TraceMethodValue,
 }
 
+// The methods in the order as they are listed in the Allow header.
+var allowedMethodsOrder = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
 type node struct {
 	// The stored part of the URL.
 	part string
@@ -123,6 +144,23 @@ func normalizeUrl(url string) (string, []param, error) {
 
 		key := e[1 : len(e)-1]
 
+		// A catch-all param is stored as a single *,
+		// which can only be the last segment of the URL.
+		if strings.HasSuffix(key, catchAllSuffix) {
+			if i != len(spl)-1 {
+				return "", nil, errMalformedCatchAll
+			}
+
+			s.WriteString(catchAllPlaceholder)
+			params = append(params, param{
+				key:        strings.TrimSuffix(key, catchAllSuffix),
+				index:      i,
+				isCatchAll: true,
+			})
+
+			continue
+		}
+
 		s.WriteString(paramPlaceholder)
 		params = append(params, param{key: key, index: i})
 	}
@@ -158,6 +196,11 @@ func getMatchingOffsets(url1 string, url2 string) (int, int, bool) {
 	)
 
 	for offset1 < len1 && offset2 < len2 {
+		// The catch-all param matches the whole remaining part.
+		if url1[offset1] == catchAllByte && offset1 == len1-1 {
+			return len1, len2, true
+		}
+
 		if url1[offset1] == url2[offset2] {
 			offset1++
 			offset2++
@@ -191,6 +234,11 @@ func getMatchingOffsets(url1 string, url2 string) (int, int, bool) {
 		}
 	}
 
+	// The catch-all param matches an empty remaining part too, eg. /static/.
+	if offset2 == len2 && offset1 == len1-1 && url1[offset1] == catchAllByte {
+		return len1, len2, true
+	}
+
 	return offset1, offset2, doesIncludeWildcard
 }
 
@@ -345,14 +393,26 @@ func (n *node) insert(method string, url string, route Route) error {
 
 	parentCandidate.node.children = append(parentCandidate.node.children, newNode)
 
-	setMethodsRec(&edge{
-		parentEdge: parentCandidate,
-		node:       newNode,
-	}, methodValue)
+	// The new node already holds the method, so the
+	// propagation must be started from its parent.
+	setMethodsRec(parentCandidate, methodValue)
 
 	return nil
 }
 
+// getAllowedMethods returns all the methods, which the given url is registered with.
+func (n *node) getAllowedMethods(url string) []string {
+	allowed := make([]string, 0)
+
+	for _, m := range allowedMethodsOrder {
+		if route, _, _ := n.find(m, url); route != nil {
+			allowed = append(allowed, m)
+		}
+	}
+
+	return allowed
+}
+
 func (n *node) find(method string, url string) (Route, pathParams, error) {
 	methodValue, valid := methodMap[method]
 	if !valid {
@@ -380,6 +440,16 @@ func (n *node) find(method string, url string) (Route, pathParams, error) {
 		}
 
 		rem := searchUrl[offset2:]
+
+		// A node created by splitting holds no value, however
+		// its catch-all child could match the empty remaining part.
+		if rem == "" && len(currNode.values) == 0 {
+			searchUrl = rem
+			nodes = append(nodes, currNode.children...)
+
+			continue
+		}
+
 		if rem == "" {
 			foundNode = currNode
 
@@ -415,6 +485,12 @@ func (n *node) find(method string, url string) (Route, pathParams, error) {
 		spl := strings.Split(url, "/")[1:]
 
 		for _, e := range v.params {
+			if e.isCatchAll {
+				params[e.key] = strings.Join(spl[e.index:], slash)
+
+				continue
+			}
+
 			params[e.key] = spl[e.index]
 		}
 	}
@@ -61,6 +61,22 @@ func TestNormalizeUrl(t *testing.T) {
 			},
 			err: nil,
 		},
+		{
+			name:   "returns the changed url with catch-all param",
+			input:  "/static/{filepath...}",
+			output: "/static/*",
+			params: []param{
+				{key: "filepath", index: 1, isCatchAll: true},
+			},
+			err: nil,
+		},
+		{
+			name:   "returns error if the catch-all param is not the last segment",
+			input:  "/static/{filepath...}/foo",
+			output: "",
+			params: nil,
+			err:    errMalformedCatchAll,
+		},
 	}
 
 	for _, tc := range tt {
@@ -150,6 +166,22 @@ func TestGetMatchingOffsets(t *testing.T) {
 			offset2:          4,
 			includesWildcard: true,
 		},
+		{
+			url1: "/static/*",
+			url2: "/static/css/main.css",
+
+			offset1:          9,
+			offset2:          20,
+			includesWildcard: true,
+		},
+		{
+			url1: "/static/*",
+			url2: "/static/",
+
+			offset1:          9,
+			offset2:          8,
+			includesWildcard: true,
+		},
 	}
 
 	for _, tc := range tt {
@@ -365,6 +397,48 @@ func TestFind(t *testing.T) {
 			expectedParams: nil,
 			expectedError:  nil,
 		},
+		{
+			name: "the function returns the node, which is split from a node with different method",
+			getTree: func(t *testing.T) *node {
+				n := newNode()
+
+				if err := n.insert(http.MethodPost, "/login", mockRoute1); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				if err := n.insert(http.MethodGet, "/me", mockRoute2); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				return n
+			},
+			method:         http.MethodGet,
+			url:            "/me",
+			expectedRoute:  mockRoute2,
+			expectedParams: make(pathParams),
+			expectedError:  nil,
+		},
+		{
+			name: "the function returns the catch-all node for the empty remaining part next to a static sibling",
+			getTree: func(t *testing.T) *node {
+				n := newNode()
+
+				if err := n.insert(http.MethodGet, "/api/{path...}", mockRoute1); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				if err := n.insert(http.MethodGet, "/api/foo", mockRoute4); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				return n
+			},
+			method:         http.MethodGet,
+			url:            "/api/",
+			expectedRoute:  mockRoute1,
+			expectedParams: pathParams{"path": ""},
+			expectedError:  nil,
+		},
 		{
 			name: "the function returns the queried node, without wildcard parameters #1",
 			getTree: func(t *testing.T) *node {
@@ -542,6 +616,50 @@ func TestFind(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name: "the function returns the queried node with catch-all parameter",
+			getTree: func(t *testing.T) *node {
+				n := newNode()
+
+				if err := n.insert(http.MethodGet, "/api/foo", mockRoute1); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				if err := n.insert(http.MethodGet, "/static/{filepath...}", mockRoute2); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				return n
+			},
+			method:        http.MethodGet,
+			url:           "/static/css/main.css",
+			expectedRoute: mockRoute2,
+			expectedParams: pathParams{
+				"filepath": "css/main.css",
+			},
+			expectedError: nil,
+		},
+		{
+			name: "the function returns the queried node in favor of exact match over catch-all",
+			getTree: func(t *testing.T) *node {
+				n := newNode()
+
+				if err := n.insert(http.MethodGet, "/{filepath...}", mockRoute1); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				if err := n.insert(http.MethodGet, "/api/foo", mockRoute2); err != nil {
+					t.Fatalf("err while inserting into tree: %v\n", err)
+				}
+
+				return n
+			},
+			method:         http.MethodGet,
+			url:            "/api/foo",
+			expectedRoute:  mockRoute2,
+			expectedParams: make(pathParams),
+			expectedError:  nil,
+		},
 		{
 			name: "the function returns the queried node in favor of exact match #1",
 			getTree: func(t *testing.T) *node {